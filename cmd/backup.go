@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// backupCmdOptions holds values for options accepted by the backup command
+type backupCmdOptions struct {
+	selectorOptions *cmdutil.SelectorOptions
+	outputFile      string
+}
+
+var (
+	backupDocLong = heredoc.Doc(`
+		Export heartbeats with their full configuration as manifests.
+
+		All heartbeats are exported unless selectors or name expressions are given,
+		which work the same way as for 'enable'. Manifests are written as a stream
+		of YAML documents to the file given with '--output-file', or to standard
+		output, and can be used to recreate heartbeats with 'restore'.
+	`)
+	backupDocExamples = heredoc.Doc(`
+		# export all heartbeats to a file
+		heartbeatctl backup -o heartbeats.yaml
+
+		# export heartbeats owned by team 'ops' to standard output
+		heartbeatctl backup --field-selector=ownerTeam/name=ops
+	`)
+)
+
+func NewBackupOptions() *backupCmdOptions {
+	return &backupCmdOptions{
+		selectorOptions: cmdutil.NewSelectorOptions(),
+	}
+}
+
+func NewCmdBackup(f *cmdutil.Factory) *cobra.Command {
+	opts := NewBackupOptions()
+
+	cmd := &cobra.Command{
+		Use:     "backup [NAME..]",
+		Short:   "Export heartbeats as manifests",
+		Long:    backupDocLong,
+		Example: backupDocExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runBackup(f, opts)
+		},
+	}
+
+	opts.selectorOptions.
+		WithCapturingArgsUsingValidator().
+		WithCompletion(cmdutil.NewCompleter(f)).
+		WithSavedSelectors(f.SavedSelectorsPath()).
+		AddFlags(cmd)
+	cmd.Flags().StringVarP(&opts.outputFile, "output-file", "o", "", "File to write manifests to, standard output if not given.")
+
+	return cmd
+}
+
+func runBackup(f *cmdutil.Factory, opts *backupCmdOptions) error {
+	selector, err := opts.selectorOptions.ToConfig()
+	if err != nil {
+		return err
+	}
+
+	heartbeats, err := f.Ctl().Get(selector)
+	if err != nil {
+		return fmt.Errorf("failed to get heartbeats: %w", err)
+	}
+
+	manifests := make([]manifest.Heartbeat, 0, len(heartbeats))
+	for _, h := range heartbeats {
+		manifests = append(manifests, manifest.FromHeartbeat(h))
+	}
+
+	if opts.outputFile == "" || opts.outputFile == "-" {
+		return manifest.Encode(f.Out, manifests)
+	}
+
+	var buf bytes.Buffer
+	if err := manifest.Encode(&buf, manifests); err != nil {
+		return err
+	}
+	if err := os.WriteFile(opts.outputFile, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	fmt.Fprintf(f.Out, "%d heartbeats backed up to %s\n", len(manifests), opts.outputFile)
+	return nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/bridge"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+)
+
+// defaultBridgeSecretEnv is the environment variable the shared secret of the
+// bridge is read from by default.
+const defaultBridgeSecretEnv = "HEARTBEATCTL_BRIDGE_SECRET"
+
+// bridgeCmdOptions holds values for options accepted by the
+// alertmanager-bridge command
+type bridgeCmdOptions struct {
+	listen       string
+	secretEnv    string
+	nameTemplate string
+	alertName    string
+	dedupWindow  time.Duration
+}
+
+var (
+	bridgeDocLong = heredoc.Doc(`
+		Ping heartbeats for alerts received from Alertmanager.
+
+		Alertmanager webhook payloads are accepted at '/' and '/webhook' on
+		'--listen', and the heartbeat of every firing alert named '--alert-name'
+		is pinged, so a single receiver for the always-firing 'Watchdog' alert of
+		every installation keeps their heartbeats from expiring. Resolved alerts
+		and alerts with other names are ignored, or all firing alerts are
+		forwarded if '--alert-name' is empty.
+
+		Heartbeat names are rendered from alerts with the Go template given with
+		'--name-template', which refers to labels as '.Labels.<name>' and to
+		annotations as '.Annotations.<name>'. Alerts without labels the template
+		refers to are logged and skipped.
+
+		If the environment variable named by '--secret-env' is set, webhook
+		requests must carry its value in an 'Authorization: Bearer <secret>'
+		header, otherwise they are accepted without authentication.
+
+		A heartbeat pinged once is not pinged again for '--dedup-window', so
+		notifications sent by every replica of Alertmanager and repeated ones cost
+		a single ping. Requests fail if any ping fails, so Alertmanager retries
+		them.
+
+		Metrics of received alerts and forwarded pings are served in the
+		Prometheus format at '/metrics', and liveness at '/healthz'. Pings are
+		logged at 'info' level unless '--verbosity' is given, and are not recorded
+		in the journal.
+	`)
+	bridgeDocExamples = heredoc.Doc(`
+		# ping heartbeats named after the 'installation' label of Watchdog alerts
+		HEARTBEATCTL_BRIDGE_SECRET=s3cr3t heartbeatctl alertmanager-bridge --listen=:8080 --name-template='{{ .Labels.installation }}-watchdog'
+
+		# matching Alertmanager configuration
+		#   receivers:
+		#     - name: heartbeats
+		#       webhook_configs:
+		#         - url: http://heartbeatctl-bridge:8080/webhook
+		#           http_config:
+		#             authorization:
+		#               credentials: s3cr3t
+		#   route:
+		#     routes:
+		#       - matchers: [alertname="Watchdog"]
+		#         receiver: heartbeats
+		#         repeat_interval: 5m
+	`)
+)
+
+func NewBridgeOptions() *bridgeCmdOptions {
+	return &bridgeCmdOptions{
+		listen:       ":8080",
+		secretEnv:    defaultBridgeSecretEnv,
+		nameTemplate: "{{ .Labels.installation }}-watchdog",
+		alertName:    "Watchdog",
+		dedupWindow:  time.Minute,
+	}
+}
+
+func NewCmdBridge(f *cmdutil.Factory) *cobra.Command {
+	opts := NewBridgeOptions()
+
+	cmd := &cobra.Command{
+		Use:     "alertmanager-bridge",
+		Short:   "Ping heartbeats for alerts received from Alertmanager",
+		Long:    bridgeDocLong,
+		Example: bridgeDocExamples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("verbosity") {
+				f.Logger.SetLevel(logrus.InfoLevel)
+			}
+			return runBridge(cmd.Context(), f, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.listen, "listen", opts.listen, "Address to accept webhooks and serve metrics at.")
+	flags.StringVar(&opts.secretEnv, "secret-env", opts.secretEnv, "Environment variable holding the shared secret webhooks must carry, if set.")
+	flags.StringVar(&opts.nameTemplate, "name-template", opts.nameTemplate, "Go template rendering the name of the heartbeat of an alert.")
+	flags.StringVar(&opts.alertName, "alert-name", opts.alertName, "Name of alerts to forward, all alerts if empty.")
+	flags.DurationVar(&opts.dedupWindow, "dedup-window", opts.dedupWindow, "How long after pinging a heartbeat further alerts for it are not forwarded.")
+
+	return cmd
+}
+
+func runBridge(ctx context.Context, f *cmdutil.Factory, opts *bridgeCmdOptions) error {
+	switch {
+	case opts.listen == "":
+		return cmdutil.UsageErrorf("'--listen' must be given")
+	case opts.dedupWindow < 0:
+		return cmdutil.UsageErrorf("'--dedup-window' must not be negative, got %s", opts.dedupWindow)
+	}
+
+	secret := ""
+	if opts.secretEnv != "" {
+		secret = os.Getenv(opts.secretEnv)
+	}
+
+	c, err := f.Client()
+	if err != nil {
+		return err
+	}
+	b, err := bridge.New(c, bridge.Config{
+		NameTemplate: opts.nameTemplate,
+		AlertName:    opts.alertName,
+		DedupWindow:  opts.dedupWindow,
+		Secret:       secret,
+	}, f.Logger, f.Now)
+	if err != nil {
+		return &cmdutil.UsageError{Err: err}
+	}
+	if secret == "" {
+		f.Logger.Warnf("%s is not set, accepting webhooks without authentication", opts.secretEnv)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	f.Logger.WithField("address", opts.listen).Info("accepting Alertmanager webhooks")
+	if err := cmdutil.Serve(ctx, opts.listen, b.Handler()); err != nil {
+		return fmt.Errorf("failed to serve webhooks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/check"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// checkCmdOptions holds values for options accepted by the check command
+type checkCmdOptions struct {
+	selectorOptions *cmdutil.SelectorOptions
+	thresholds      check.Thresholds
+}
+
+var (
+	checkDocLong = heredoc.Docf(`
+		Check heartbeats as a Nagios or Icinga plugin.
+
+		Selected heartbeats, or all heartbeats if no selectors are given, are
+		checked for expired and disabled ones, and the result is printed as a
+		single status line with performance data of checked, enabled, expired and
+		disabled heartbeats.
+
+		The state is WARNING if more enabled heartbeats than '--warn-expired' are
+		expired, and CRITICAL if more than '--crit-expired' are, or if any
+		heartbeat is disabled and '--crit-disabled' is given. By default any
+		expired heartbeat is CRITICAL, and with only '--warn-expired' given,
+		'--crit-expired' defaults to the same number. Disabled heartbeats are never
+		counted as expired.
+
+		Following plugin conventions, the exit code is the state:
+		  %d  OK
+		  %d  WARNING
+		  %d  CRITICAL
+		  %d  UNKNOWN, e.g. invalid flags, selectors matching nothing or failed
+		     OpsGenie requests, with the reason on the status line
+	`, check.OK, check.Warning, check.Critical, check.Unknown)
+	checkDocExamples = heredoc.Doc(`
+		# fail if any heartbeat of backup jobs is expired or disabled
+		heartbeatctl check --crit-disabled "backup-.*"
+
+		# warn about one expired P1 heartbeat, fail on more
+		heartbeatctl check --field-selector=alertPriority=P1 --warn-expired=0 --crit-expired=1
+
+		# matching Icinga command definition
+		#   object CheckCommand "heartbeats" {
+		#     command = [ "/usr/local/bin/heartbeatctl", "check" ]
+		#     arguments = {
+		#       "--selector" = "$heartbeats_selector$"
+		#       "--crit-expired" = "$heartbeats_crit_expired$"
+		#     }
+		#     env.HEARTBEATCTL_TOKEN = "$heartbeats_token$"
+		#   }
+	`)
+)
+
+func NewCheckOptions() *checkCmdOptions {
+	return &checkCmdOptions{
+		selectorOptions: cmdutil.NewSelectorOptions(),
+	}
+}
+
+func NewCmdCheck(f *cmdutil.Factory) *cobra.Command {
+	opts := NewCheckOptions()
+
+	cmd := &cobra.Command{
+		Use:     "check [NAME..]",
+		Short:   "Check heartbeats as a Nagios or Icinga plugin",
+		Long:    checkDocLong,
+		Example: checkDocExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("crit-expired") {
+				opts.thresholds.CritExpired = max(opts.thresholds.CritExpired, opts.thresholds.WarnExpired)
+			}
+			return runCheck(f, opts)
+		},
+	}
+
+	opts.selectorOptions.
+		WithCapturingArgsUsingValidator().
+		WithCompletion(cmdutil.NewCompleter(f)).
+		WithSavedSelectors(f.SavedSelectorsPath()).
+		AddFlags(cmd)
+
+	flags := cmd.Flags()
+	flags.IntVar(&opts.thresholds.WarnExpired, "warn-expired", opts.thresholds.WarnExpired, "Number of expired heartbeats above which the state is WARNING.")
+	flags.IntVar(&opts.thresholds.CritExpired, "crit-expired", opts.thresholds.CritExpired, "Number of expired heartbeats above which the state is CRITICAL.")
+	flags.BoolVar(&opts.thresholds.CritDisabled, "crit-disabled", false, "Make the state CRITICAL if any heartbeat is disabled.")
+
+	// Invalid flags make the state UNKNOWN like any other failure.
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return checkUnknown(f, err)
+	})
+
+	return cmd
+}
+
+func runCheck(f *cmdutil.Factory, opts *checkCmdOptions) error {
+	if err := opts.thresholds.Validate(); err != nil {
+		return checkUnknown(f, err)
+	}
+	selector, err := opts.selectorOptions.ToConfig()
+	if err != nil {
+		return checkUnknown(f, err)
+	}
+
+	heartbeats, err := f.Ctl().Get(selector)
+	if err != nil {
+		return checkUnknown(f, fmt.Errorf("failed to get heartbeats: %w", err))
+	}
+	if len(heartbeats) == 0 {
+		return checkUnknown(f, ctl.ErrNoMatch)
+	}
+
+	result := check.Evaluate(heartbeats, opts.thresholds)
+	fmt.Fprintln(f.Out, result)
+	if result.State != check.OK {
+		return &cmdutil.StatusError{Code: result.State}
+	}
+	return nil
+}
+
+// checkUnknown prints the status line reporting given error and returns an
+// error making heartbeatctl exit with the UNKNOWN state.
+func checkUnknown(f *cmdutil.Factory, err error) error {
+	fmt.Fprintln(f.Out, check.UnknownStatus(err))
+	return &cmdutil.StatusError{Code: check.Unknown}
+}
@@ -0,0 +1,18 @@
+package cmd_test
+
+import (
+	"flag"
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// update makes golden file assertions overwrite golden files with actual
+// output instead of comparing it, run `go test ./cmd/... -update` to use it.
+var update = flag.Bool("update", false, "update golden files")
+
+func TestCmd(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cmd Suite")
+}
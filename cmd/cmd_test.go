@@ -0,0 +1,694 @@
+package cmd_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+var _ = Describe("heartbeatctl", func() {
+	var repo *fake.Client
+
+	BeforeEach(func() {
+		repo = fake.NewClient(fixtureHeartbeats()...)
+	})
+
+	DescribeTable("produces expected output and exit code",
+		func(golden string, exitCode int, args ...string) {
+			r := execute(repo, args...)
+			Expect(r.exitCode).To(Equal(exitCode))
+			ExpectGolden(r, golden)
+		},
+
+		Entry("list", "list", 0, "list"),
+		Entry("list without headers", "list_no_headers", 0, "list", "--no-headers"),
+		Entry("list filtered by status", "list_status_disabled", 0, "list", "--status=DISABLED"),
+		Entry("list with invalid status", "list_status_invalid", 2, "list", "-s", "NOPE"),
+		Entry("list with unknown flag", "list_unknown_flag", 2, "list", "--nope"),
+
+		Entry("get", "get", 0, "get", "bar-oof2"),
+		Entry("get missing heartbeat", "get_missing", 4, "get", "nope"),
+		Entry("get missing heartbeat with JSON errors", "get_missing_json", 4, "get", "nope", "--error-format=json"),
+		Entry("get without name", "get_no_args", 2, "get"),
+
+		Entry("enable", "enable", 0, "enable", "-l", "!enabled"),
+		Entry("enable without selector", "enable_no_selector", 2, "enable"),
+		Entry("enable with selector matching nothing", "enable_no_match", 4, "enable", "nope"),
+
+		Entry("disable", "disable", 0, "disable", "--field-selector=alertPriority=P3", "foo.*"),
+		Entry("disable without selector", "disable_no_selector", 2, "disable"),
+		Entry("disable with extended field selector", "disable_field_selector", 0, "disable", "--field-selector=alertPriority in (P3, P4),interval>=30"),
+		Entry("disable with invalid field selector", "disable_invalid_field_selector", 2, "disable", "--field-selector=interval>often"),
+		Entry("disable with query", "disable_query", 0, "disable", "--query", "(alertPriority=P4 and interval>=30) or ownerTeam/name=team-rocket"),
+		Entry("disable with invalid query", "disable_invalid_query", 2, "disable", "--query", "(alertPriority=P4 or"),
+		Entry("disable with invalid selector", "disable_invalid_selector", 2, "disable", "-l", "in in"),
+
+		Entry("ping", "ping", 0, "ping", "-l", "managed-by=foobricator"),
+		Entry("ping without selector", "ping_no_selector", 2, "ping"),
+
+		Entry("check", "check", 2, "check"),
+		Entry("check within thresholds", "check_ok", 0, "check", "--warn-expired=2", "--crit-expired=2"),
+		Entry("check warning", "check_warning", 1, "check", "-l", "managed-by=foobricator", "--crit-expired=5"),
+		Entry("check disabled", "check_disabled", 2, "check", "--crit-disabled", "bar.*", "--crit-expired=5"),
+		Entry("check matching nothing", "check_no_match", 3, "check", "nope"),
+		Entry("check with only a warning threshold", "check_warn_only", 0, "check", "--warn-expired=1"),
+		Entry("check with invalid thresholds", "check_invalid_thresholds", 3, "check", "--warn-expired=3", "--crit-expired=1"),
+		Entry("check with invalid flag", "check_invalid_flag", 3, "check", "--warn-expired=many"),
+		Entry("check with invalid selector", "check_invalid_selector", 3, "check", "-l", "in in"),
+
+		Entry("complete heartbeat names", "complete_names", 0, "__complete", "enable", "foo", ""),
+		Entry("complete get heartbeat name", "complete_get", 0, "__complete", "get", "bar-"),
+		Entry("complete label keys", "complete_label_keys", 0, "__complete", "disable", "--selector", "!enabled,man"),
+		Entry("complete label values", "complete_label_values", 0, "__complete", "ping", "-l", "managed-by="),
+		Entry("complete field names", "complete_field_names", 0, "__complete", "enable", "--field-selector", "alert"),
+		Entry("complete field values", "complete_field_values", 0, "__complete", "enable", "--field-selector", "alertPriority!=P"),
+		Entry("complete status", "complete_status", 0, "__complete", "list", "--status", ""),
+		Entry("completion for unsupported shell", "completion_invalid_shell", 2, "completion", "tcsh"),
+
+		Entry("disable dry run", "disable_dry_run", 0, "disable", "foo.*", "--dry-run"),
+		Entry("disable dry run as JSON", "disable_dry_run_json", 0, "disable", "foo.*", "--dry-run", "-o", "json"),
+		Entry("enable dry run", "enable_dry_run", 0, "enable", "bar.*", "--dry-run"),
+		Entry("ping dry run", "ping_dry_run", 0, "ping", "-l", "managed-by=foobricator", "--dry-run"),
+		Entry("dry run matching nothing", "disable_dry_run_no_match", 4, "disable", "nope", "--dry-run"),
+		Entry("disable as JSON", "disable_json", 0, "disable", "bar", "-o", "json"),
+		Entry("ping as JSON", "ping_json", 0, "ping", "foo", "-o", "json"),
+		Entry("enable with invalid output format", "enable_invalid_output", 2, "enable", "foo", "-o", "yaml"),
+
+		Entry("backup", "backup", 0, "backup", "-l", "managed-by=foobricator", "--field-selector=interval<=5"),
+		Entry("backup matching nothing", "backup_no_match", 0, "backup", "nope"),
+		Entry("restore", "restore", 0, "restore", "-f", "testdata/heartbeats.yaml"),
+		Entry("restore with enabled state and team mapping", "restore_enabled_map_team", 0, "restore", "-f", "testdata/heartbeats.yaml", "--restore-enabled", "--map-team=ops=team-rocket"),
+		Entry("restore dry run", "restore_dry_run", 0, "restore", "-f", "testdata/heartbeats.yaml", "--dry-run"),
+		Entry("restore as JSON", "restore_json", 0, "restore", "-f", "testdata/heartbeats.yaml", "-o", "json"),
+		Entry("restore without file", "restore_no_file", 2, "restore"),
+		Entry("restore missing file", "restore_missing_file", 2, "restore", "-f", "testdata/nope.yaml"),
+		Entry("restore invalid manifest", "restore_invalid", 2, "restore", "-f", "testdata/list.golden"),
+
+		Entry("lint", "lint", 0, "lint", "-f", "testdata/heartbeats.yaml"),
+		Entry("lint invalid manifests", "lint_invalid", 1, "lint", "-f", "testdata/heartbeats.yaml", "-f", "testdata/invalid.yaml"),
+		Entry("lint invalid manifests as JSON", "lint_invalid_json", 1, "lint", "-f", "testdata/invalid.yaml", "-o", "json"),
+		Entry("lint malformed YAML", "lint_malformed", 1, "lint", "-f", "testdata/list.golden"),
+		Entry("lint without file", "lint_no_file", 2, "lint"),
+		Entry("lint missing file", "lint_missing_file", 2, "lint", "-f", "testdata/nope.yaml"),
+
+		Entry("reconcile without path", "reconcile_no_path", 2, "reconcile", "--once"),
+		Entry("reconcile missing path", "reconcile_missing_path", 2, "reconcile", "--path=testdata/nope", "--once"),
+		Entry("reconcile file", "reconcile_file", 2, "reconcile", "--path=testdata/heartbeats.yaml", "--once"),
+		Entry("reconcile with invalid interval", "reconcile_invalid_interval", 2, "reconcile", "--path=testdata", "--interval=0s"),
+
+		Entry("schedule explain", "schedule_explain", 0, "schedule", "explain", "0 3 * * 1-5", "--grace=30m"),
+		Entry("schedule explain as JSON", "schedule_explain_json", 0, "schedule", "explain", "@hourly", "-o", "json"),
+		Entry("schedule explain invalid schedule", "schedule_explain_invalid", 2, "schedule", "explain", "0 3 * *"),
+		Entry("set-schedule", "set_schedule", 0, "set-schedule", "0 3 * * 1-5", "--grace=30m", "-l", "tagged", "bar-.*"),
+		Entry("set-schedule dry run", "set_schedule_dry_run", 0, "set-schedule", "*/10 * * * *", "--grace=0s", "--field-selector=intervalUnit=minutes", "--dry-run"),
+		Entry("set-schedule without selector", "set_schedule_no_selector", 2, "set-schedule", "@daily"),
+		Entry("set-schedule without schedule", "set_schedule_no_schedule", 2, "set-schedule"),
+
+		Entry("alertmanager-bridge with malformed template", "bridge_invalid_template", 2, "alertmanager-bridge", "--name-template={{ .Labels.installation"),
+		Entry("alertmanager-bridge with negative dedup window", "bridge_invalid_dedup_window", 2, "alertmanager-bridge", "--dedup-window=-1s"),
+		Entry("alertmanager-bridge without address", "bridge_no_listen", 2, "alertmanager-bridge", "--listen="),
+
+		Entry("relay without selector", "relay_no_selector", 2, "relay"),
+		Entry("relay with invalid selector", "relay_invalid_selector", 2, "relay", "-l", "team in"),
+		Entry("relay with negative coalesce window", "relay_invalid_coalesce_window", 2, "relay", "bar-.*", "--coalesce-window=-1s"),
+
+		Entry("operator with negative resync period", "operator_invalid_resync_period", 2, "operator", "--resync-period=-1m"),
+		Entry("operator with invalid owner", "operator_invalid_owner", 2, "operator", "--owner=not valid"),
+		Entry("operator with unknown controller", "operator_unknown_controller", 2, "operator", "--controllers=heartbeats,jobs"),
+		Entry("operator with negative grace period", "operator_invalid_grace_period", 2, "operator", "--controllers=cronjobs", "--cronjob-grace-period=-1m"),
+		Entry("operator missing kubeconfig", "operator_missing_kubeconfig", 2, "operator", "--kubeconfig=testdata/nope.yaml"),
+
+		Entry("generate", "generate", 0, "generate", "-t", "testdata/heartbeat.yaml.tmpl", "-i", "testdata/installations.csv"),
+		Entry("generate and create", "generate_create", 0, "generate", "-t", "testdata/heartbeat.yaml.tmpl", "-i", "testdata/installations.csv", "--create"),
+		Entry("generate dry run", "generate_dry_run", 0, "generate", "-t", "testdata/heartbeat.yaml.tmpl", "-i", "testdata/installations.yaml", "--create", "--dry-run"),
+		Entry("generate invalid heartbeats", "generate_invalid", 2, "generate", "-t", "testdata/heartbeat.yaml.tmpl", "-i", "testdata/installations_invalid.csv"),
+		Entry("generate without template", "generate_no_template", 2, "generate", "-i", "testdata/installations.csv"),
+		Entry("generate dry run without create", "generate_dry_run_no_create", 2, "generate", "-t", "testdata/heartbeat.yaml.tmpl", "-i", "testdata/installations.csv", "--dry-run"),
+
+		Entry("unknown command", "unknown_command", 2, "frobnicate"),
+		Entry("invalid error format", "invalid_error_format", 2, "list", "--error-format=xml"),
+		Entry("invalid log format", "invalid_log_format", 2, "list", "--log-format=xml"),
+	)
+
+	It("outputs completion code for supported shells", func() {
+		for _, shell := range []string{"bash", "zsh", "fish", "powershell"} {
+			r := execute(repo, "completion", shell)
+			Expect(r.exitCode).To(Equal(0), shell)
+			Expect(r.stdout).To(ContainSubstring("heartbeatctl"), shell)
+			Expect(r.stderr).To(BeEmpty(), shell)
+		}
+	})
+
+	It("completes nothing when listing heartbeats fails", func() {
+		r := executeWithClientError(client.ErrMissingAPIKey, "__complete", "enable", "")
+		Expect(r.exitCode).To(Equal(0))
+		Expect(r.stdout).To(Equal(":1\n"))
+	})
+
+	Describe("saved selectors", func() {
+		BeforeEach(func() {
+			r := execute(repo, "selectors", "add", "foobricated", "-l", "managed-by=foobricator", "--query", "name=~'bar.*|foo-rab1'")
+			Expect(r.exitCode).To(Equal(0))
+			r = execute(repo, "selectors", "add", "urgent", "--field-selector", "alertPriority in (P1, P2)")
+			Expect(r.exitCode).To(Equal(0))
+		})
+
+		DescribeTable("produces expected output and exit code",
+			func(golden string, exitCode int, args ...string) {
+				r := execute(repo, args...)
+				Expect(r.exitCode).To(Equal(exitCode))
+				ExpectGolden(r, golden)
+			},
+			Entry("list", "selectors_list", 0, "selectors", "list"),
+			Entry("show", "selectors_show", 0, "selectors", "show", "foobricated"),
+			Entry("show missing", "selectors_show_missing", 2, "selectors", "show", "nope"),
+			Entry("add existing", "selectors_add_existing", 2, "selectors", "add", "urgent", "-l", "enabled"),
+			Entry("add empty", "selectors_add_empty", 2, "selectors", "add", "empty"),
+			Entry("add invalid", "selectors_add_invalid", 2, "selectors", "add", "invalid", "--query", "name="),
+			Entry("remove", "selectors_remove", 0, "selectors", "remove", "urgent"),
+			Entry("disable by argument", "selectors_disable", 0, "disable", "@foobricated", "-l", "!expired"),
+			Entry("ping by flag", "selectors_ping", 0, "ping", "--saved-selector=foobricated", "--saved-selector=urgent"),
+			Entry("enable unknown", "selectors_enable_unknown", 2, "enable", "@nope"),
+			Entry("complete arguments", "selectors_complete", 0, "__complete", "enable", "@"),
+		)
+
+		It("overwrites saved selectors when requested", func() {
+			Expect(execute(repo, "selectors", "add", "urgent", "-l", "enabled", "--overwrite").exitCode).To(Equal(0))
+			Expect(execute(repo, "selectors", "show", "urgent").stdout).To(Equal("selector: enabled\n"))
+		})
+	})
+
+	Describe("lint", func() {
+		It("works without an OpsGenie client", func() {
+			r := executeWithClientError(errors.New("no client"), "lint", "-f", "testdata/heartbeats.yaml")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stderr).To(BeEmpty())
+		})
+
+		It("finds no problems in backups", func() {
+			backup := execute(repo, "backup", ".*")
+			Expect(backup.exitCode).To(Equal(0))
+
+			r := executeWithFactory(func(f *cmdutil.Factory) {
+				f.ClientFunc = func() (client.Port, error) { return repo, nil }
+				f.In = strings.NewReader(backup.stdout)
+			}, "lint", "-f", "-")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(BeEmpty())
+		})
+
+		It("prints the JSON Schema of manifests", func() {
+			r := execute(repo, "lint", "--print-schema")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(Equal(string(manifest.Schema)))
+		})
+	})
+
+	Describe("generate", func() {
+		It("creates heartbeats with tags selectable as labels", func() {
+			r := execute(repo, "generate", "-t", "testdata/heartbeat.yaml.tmpl", "-i", "testdata/installations.csv", "--create")
+			Expect(r.exitCode).To(Equal(0))
+
+			r = execute(repo, "backup", "-l", "provider=azure")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(ContainSubstring("name: baz"))
+			Expect(r.stdout).NotTo(ContainSubstring("name: foo"))
+		})
+	})
+
+	Describe("prune", func() {
+		BeforeEach(func() {
+			r := execute(repo, "generate", "-t", "testdata/heartbeat.yaml.tmpl", "-i", "testdata/installations.csv", "--owner=installations", "--create")
+			Expect(r.exitCode).To(Equal(0))
+		})
+
+		DescribeTable("produces expected output and exit code",
+			func(golden string, exitCode int, args ...string) {
+				r := execute(repo, args...)
+				Expect(r.exitCode).To(Equal(exitCode))
+				ExpectGolden(r, golden)
+			},
+			Entry("prune", "prune", 0, "prune", "--owner=installations", "--keep-file=testdata/keep.txt"),
+			Entry("prune dry run", "prune_dry_run", 0, "prune", "--owner=installations", "--keep-file=testdata/keep.txt", "--dry-run", "-o", "json"),
+			Entry("prune other set", "prune_other_set", 0, "prune", "--owner=clusters", "--keep-file=testdata/keep.txt"),
+			Entry("prune without owner", "prune_no_owner", 2, "prune", "--keep-file=testdata/keep.txt"),
+			Entry("prune with invalid owner", "prune_invalid_owner", 2, "prune", "--owner=my installations", "--keep-file=testdata/keep.txt"),
+			Entry("prune without keep file", "prune_no_keep_file", 2, "prune", "--owner=installations"),
+			Entry("prune with empty keep file", "prune_empty_keep_file", 2, "prune", "--owner=installations", "--keep-file=testdata/keep_empty.txt"),
+		)
+
+		It("deletes only heartbeats of the set missing in the list", func() {
+			r := executeWithFactory(func(f *cmdutil.Factory) {
+				f.ClientFunc = func() (client.Port, error) { return repo, nil }
+				f.In = strings.NewReader("# nothing to keep\n")
+			}, "prune", "--owner=installations", "--keep-file=-", "--allow-empty")
+			Expect(r.exitCode).To(Equal(0))
+
+			names := []string{}
+			for _, h := range repo.Heartbeats() {
+				names = append(names, h.Name)
+			}
+			Expect(names).To(Equal([]string{"bar", "bar-oof2", "bar-rab2", "foo-oof1", "foo-rab1"}))
+		})
+	})
+
+	Describe("set-schedule", func() {
+		It("updates intervals through the journal so they can be undone", func() {
+			before := repo.Heartbeats()
+
+			r := execute(repo, "set-schedule", "@daily", "--grace=1h", "foo")
+			Expect(r.exitCode).To(Equal(0))
+			h, err := repo.Get(context.TODO(), "foo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(h.Heartbeat.Interval).To(Equal(25))
+			Expect(h.Heartbeat.IntervalUnit).To(Equal("hours"))
+			Expect(h.Heartbeat.Enabled).To(BeTrue())
+
+			Expect(execute(repo, "undo").exitCode).To(Equal(0))
+			Expect(repo.Heartbeats()).To(Equal(before))
+		})
+	})
+
+	Describe("reconcile", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			data, err := os.ReadFile("testdata/heartbeats.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(dir, "heartbeats.yaml"), data, 0644)).To(Succeed())
+		})
+
+		It("reconciles heartbeats once, logging corrections", func() {
+			r := execute(repo, "reconcile", "--path", dir, "--once", "--owner=gitops")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(BeEmpty())
+			Expect(r.stderr).To(ContainSubstring(`msg="corrected drifted heartbeat" action=update heartbeat=bar`))
+			Expect(r.stderr).To(ContainSubstring(`msg="created missing heartbeat" action=create heartbeat=qux`))
+
+			h, err := repo.Get(context.TODO(), "qux")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(h.Heartbeat.AlertTags).To(ContainElement("heartbeatctl.giantswarm.io/owner: gitops"))
+		})
+
+		It("fails on problems in manifests without changing anything", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("kind: ["), 0644)).To(Succeed())
+
+			r := execute(repo, "reconcile", "--path", dir, "--once", "-v", "0")
+			Expect(r.exitCode).To(Equal(1))
+			Expect(r.stderr).To(ContainSubstring("Error: failed to reconcile heartbeats: found 1 problem in manifests:"))
+			Expect(repo.Heartbeats()).To(Equal(fake.NewClient(fixtureHeartbeats()...).Heartbeats()))
+		})
+	})
+
+	Describe("backup and restore", func() {
+		It("recreates deleted heartbeats with their configuration", func() {
+			before := repo.Heartbeats()
+			path := filepath.Join(GinkgoT().TempDir(), "backup.yaml")
+
+			r := execute(repo, "backup", "-o", path)
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(Equal(fmt.Sprintf("6 heartbeats backed up to %s\n", path)))
+
+			for _, h := range before {
+				_, err := repo.Delete(context.Background(), h.Name)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			r = execute(repo, "restore", "-f", path, "--restore-enabled")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(ContainSubstring("heartbeat \"bar-rab2\" created\n"))
+
+			expected := before
+			for i := range expected {
+				expected[i].Expired = false
+				if expected[i].AlertMessage == "" {
+					expected[i].AlertMessage = fake.DefaultAlertMessage(expected[i].Name)
+				}
+			}
+			Expect(repo.Heartbeats()).To(Equal(expected))
+		})
+
+		It("reports fields added since the backup that it can't clear", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "backup.yaml")
+			Expect(execute(repo, "backup", "foo", "-o", path).exitCode).To(Equal(0))
+
+			h, err := repo.Get(context.Background(), "foo")
+			Expect(err).NotTo(HaveOccurred())
+			update := manifest.FromHeartbeat(h.Heartbeat).UpdateRequest()
+			update.Description = "edited in the UI"
+			update.AlertTag = []string{"ui"}
+			_, err = repo.Update(context.Background(), update)
+			Expect(err).NotTo(HaveOccurred())
+
+			r := execute(repo, "restore", "-f", path)
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(Equal("heartbeat \"foo\" unchanged\n"))
+			Expect(r.stderr).To(Equal("heartbeat \"foo\" keeps its description and alertTags, which OpsGenie can't clear\n"))
+		})
+
+		It("reads manifests from standard input", func() {
+			r := executeWithFactory(func(f *cmdutil.Factory) {
+				f.ClientFunc = func() (client.Port, error) { return repo, nil }
+				f.In = strings.NewReader(execute(repo, "backup", "foo").stdout)
+			}, "restore", "-f", "-")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(Equal("heartbeat \"foo\" unchanged\n"))
+		})
+
+		It("records updated heartbeats in the journal", func() {
+			Expect(execute(repo, "restore", "-f", "testdata/heartbeats.yaml", "--restore-enabled").exitCode).To(Equal(0))
+			Expect(execute(repo, "undo").stdout).To(Equal("heartbeat \"bar\" restored\n"))
+			Expect(repo.Heartbeats()[0]).To(Equal(fixtureHeartbeats()[3]))
+		})
+	})
+
+	Describe("sync", func() {
+		var target *fake.Client
+
+		BeforeEach(func() {
+			target = fake.NewClient(
+				heartbeat.Heartbeat{Name: "acme-oof1", Interval: 1, IntervalUnit: "hours", Enabled: true, AlertPriority: "P3", AlertTags: []string{"tagged", "managed-by: foobricator"}},
+				heartbeat.Heartbeat{Name: "acme-rab1", Interval: 1, IntervalUnit: "hours", AlertPriority: "P2", AlertTags: []string{"tagged", "managed-by: foobricator"}},
+				heartbeat.Heartbeat{Name: "acme-stale", Interval: 1, IntervalUnit: "days", AlertTags: []string{"tagged", "managed-by: foobricator"}},
+				heartbeat.Heartbeat{Name: "acme-manual", Interval: 1, IntervalUnit: "days"},
+			)
+		})
+
+		// executeSync runs heartbeatctl with given args, with the default
+		// account's client returned for $HEARTBEATCTL_TOKEN and the target
+		// client for $ACME_TOKEN.
+		executeSync := func(args ...string) result {
+			return executeWithFactory(func(f *cmdutil.Factory) {
+				f.AccountClientFunc = func(tokenEnvVar, apiURL string) (client.Port, error) {
+					switch tokenEnvVar {
+					case client.TokenEnvVar:
+						return repo, nil
+					case "ACME_TOKEN":
+						return target, nil
+					default:
+						return nil, &client.MissingAPIKeyError{EnvVar: tokenEnvVar}
+					}
+				}
+			}, args...)
+		}
+
+		DescribeTable("produces expected output and exit code",
+			func(golden string, exitCode int, args ...string) {
+				r := executeSync(args...)
+				Expect(r.exitCode).To(Equal(exitCode))
+				ExpectGolden(r, golden)
+			},
+			Entry("sync", "sync", 0, "sync", "--from-prefix=foo", "--to-prefix=acme", "--to-token-env=ACME_TOKEN", "--map-team=team-rocket=acme-team"),
+			Entry("sync with pruning", "sync_prune", 0, "sync", "-l", "managed-by=foobricator", "--from-prefix=foo-", "--to-prefix=acme-", "--to-token-env=ACME_TOKEN", "--prune"),
+			Entry("sync dry run as JSON", "sync_dry_run_json", 0, "sync", "--from-prefix=foo-", "--to-prefix=acme-", "--to-token-env=ACME_TOKEN", "--prune", "--dry-run", "-o", "json"),
+			Entry("sync without target", "sync_no_target", 2, "sync", ".*"),
+			Entry("sync without selector", "sync_no_selector", 2, "sync", "--to-token-env=ACME_TOKEN"),
+			Entry("sync pruning without scope", "sync_unscoped_prune", 2, "sync", "--from-prefix=foo-", "--to-token-env=ACME_TOKEN", "--prune"),
+			Entry("sync matching nothing", "sync_no_match", 4, "sync", "nope", "--to-token-env=ACME_TOKEN"),
+			Entry("sync with missing API key", "sync_missing_key", 3, "sync", ".*", "--to-token-env=OTHER_TOKEN"),
+		)
+
+		It("copies heartbeats with renamed names and owner teams", func() {
+			Expect(executeSync("sync", "foo", "--to-token-env=ACME_TOKEN", "--map-team=team-rocket=acme-team").exitCode).To(Equal(0))
+
+			copied := target.Heartbeats()[4]
+			Expect(copied.Name).To(Equal("foo"))
+			Expect(copied.OwnerTeam.Name).To(Equal("acme-team"))
+			Expect(copied.Enabled).To(BeTrue())
+		})
+
+		It("doesn't change the target account in dry run mode", func() {
+			before := target.Heartbeats()
+			Expect(executeSync("sync", ".*", "--to-token-env=ACME_TOKEN", "--prune", "--dry-run").exitCode).To(Equal(0))
+			Expect(target.Heartbeats()).To(Equal(before))
+		})
+	})
+
+	Describe("journal", func() {
+		BeforeEach(func() {
+			Expect(execute(repo, "disable", "foo.*").exitCode).To(Equal(0))
+			Expect(execute(repo, "ping", "bar").exitCode).To(Equal(0))
+			Expect(execute(repo, "enable", "-l", "!enabled,managed-by=foobricator").exitCode).To(Equal(0))
+		})
+
+		DescribeTable("produces expected output and exit code",
+			func(golden string, exitCode int, args ...string) {
+				r := execute(repo, args...)
+				Expect(r.exitCode).To(Equal(exitCode))
+				ExpectGolden(r, golden)
+			},
+			Entry("history", "history", 0, "history"),
+			Entry("history as JSON", "history_json", 0, "history", "-o", "json", "--no-headers"),
+			Entry("undo", "undo", 0, "undo"),
+			Entry("undo by ID", "undo_id", 0, "undo", "1"),
+			Entry("undo dry run", "undo_dry_run", 0, "undo", "1", "--dry-run"),
+			Entry("undo ping", "undo_ping", 2, "undo", "2"),
+			Entry("undo missing", "undo_missing", 2, "undo", "42"),
+			Entry("undo invalid ID", "undo_invalid_id", 2, "undo", "last"),
+		)
+
+		It("restores heartbeats to their state before the change", func() {
+			before := repo.Heartbeats()
+			Expect(execute(repo, "disable", ".*").exitCode).To(Equal(0))
+			Expect(execute(repo, "undo").exitCode).To(Equal(0))
+			Expect(repo.Heartbeats()).To(Equal(before))
+		})
+
+		It("reports fields added since the change that it can't clear", func() {
+			Expect(execute(repo, "disable", "foo").exitCode).To(Equal(0))
+			h, err := repo.Get(context.Background(), "foo")
+			Expect(err).NotTo(HaveOccurred())
+			update := manifest.FromHeartbeat(h.Heartbeat).UpdateRequest()
+			update.Description = "edited in the UI"
+			_, err = repo.Update(context.Background(), update)
+			Expect(err).NotTo(HaveOccurred())
+
+			r := execute(repo, "undo", "--dry-run")
+			Expect(r.stderr).To(Equal("heartbeat \"foo\" keeps its description, which OpsGenie can't clear\n"))
+
+			r = execute(repo, "undo")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(Equal("heartbeat \"foo\" restored\n"))
+			Expect(r.stderr).To(Equal("heartbeat \"foo\" keeps its description, which OpsGenie can't clear\n"))
+			Expect(repo.Heartbeats()[0].Enabled).To(BeTrue())
+		})
+
+		It("undoes changes one by one, newest first", func() {
+			Expect(execute(repo, "undo").stdout).To(Equal(
+				"heartbeat \"bar-rab2\" restored\nheartbeat \"foo-oof1\" restored\nheartbeat \"foo-rab1\" restored\n",
+			))
+			Expect(execute(repo, "undo").stdout).To(Equal(
+				"heartbeat \"foo\" restored\nheartbeat \"foo-oof1\" restored\nheartbeat \"foo-rab1\" restored\n",
+			))
+
+			r := execute(repo, "undo")
+			Expect(r.exitCode).To(Equal(2))
+			Expect(r.stderr).To(Equal("Error: there are no changes to undo\n"))
+
+			r = execute(repo, "undo", "1")
+			Expect(r.exitCode).To(Equal(2))
+			Expect(r.stderr).To(Equal("Error: change 1 was already undone by change 5\n"))
+		})
+
+		It("doesn't record dry runs or failed selections", func() {
+			Expect(execute(repo, "disable", ".*", "--dry-run").exitCode).To(Equal(0))
+			Expect(execute(repo, "disable", "nope").exitCode).To(Equal(4))
+			Expect(strings.Count(execute(repo, "history", "--no-headers").stdout, "\n")).To(Equal(3))
+		})
+	})
+
+	Describe("spool", func() {
+		// unreachable is the error requests fail with without connectivity.
+		unreachable := &url.Error{Op: "Post", URL: "https://api.opsgenie.com/v2/heartbeats", Err: errors.New("connection refused")}
+
+		BeforeEach(func() {
+			repo.Fail("Ping", "foo-rab1", unreachable)
+			repo.Fail("Ping", "foo-oof1", unreachable)
+			r := execute(repo, "ping", "foo.*")
+			Expect(r.exitCode).To(Equal(5))
+			Expect(r.stderr).To(ContainSubstring("ping of heartbeat \"foo-oof1\" spooled"))
+		})
+
+		DescribeTable("produces expected output and exit code",
+			func(golden string, exitCode int, args ...string) {
+				repo.Fail("Ping", "foo-oof1", nil)
+				r := execute(repo, args...)
+				Expect(r.exitCode).To(Equal(exitCode))
+				ExpectGolden(r, golden)
+			},
+			Entry("spool list", "spool_list", 0, "spool", "list"),
+			Entry("spool list as JSON", "spool_list_json", 0, "spool", "list", "-o", "json"),
+			Entry("spool flush", "spool_flush", 1, "spool", "flush"),
+			Entry("spool purge", "spool_purge", 0, "spool", "purge", "foo-rab1", "nope"),
+			Entry("ping replaying spooled pings", "ping_spool_replay", 0, "ping", "bar"),
+		)
+
+		It("drops spooled pings of heartbeats pinged again", func() {
+			repo.Fail("Ping", "foo-oof1", nil)
+			Expect(execute(repo, "ping", "foo-oof1").exitCode).To(Equal(0))
+			Expect(execute(repo, "spool", "list", "--no-headers").stdout).To(HavePrefix("foo-rab1 "))
+		})
+
+		It("discards spooled pings older than the heartbeat interval", func() {
+			repo.Fail("Ping", "foo-rab1", nil)
+			repo.Fail("Ping", "foo-oof1", nil)
+			defer func(at time.Time) { now = at }(now)
+			now = now.Add(30 * time.Minute)
+
+			r := execute(repo, "spool", "flush")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(Equal(
+				"spooled ping of heartbeat \"foo-oof1\": delivered\nspooled ping of heartbeat \"foo-rab1\": expired\n",
+			))
+			Expect(execute(repo, "spool", "list", "--no-headers").stdout).To(Equal("\n"))
+		})
+
+		It("spools pings of heartbeats given by exact names when selecting them fails", func() {
+			Expect(execute(repo, "spool", "purge").exitCode).To(Equal(0))
+			repo.Fail("List", "", unreachable)
+
+			Expect(execute(repo, "ping", "foo.*").stderr).NotTo(ContainSubstring("spooled"))
+			Expect(execute(repo, "ping", "bar", "--no-spool").stderr).NotTo(ContainSubstring("spooled"))
+			r := execute(repo, "ping", "bar", "bar-rab2")
+			Expect(r.exitCode).To(Equal(1))
+			Expect(r.stderr).To(ContainSubstring("ping of heartbeat \"bar-rab2\" spooled"))
+
+			repo.Fail("List", "", nil)
+			r = execute(repo, "ping", "foo")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stderr).To(Equal(
+				"spooled ping of heartbeat \"bar\": delivered\nspooled ping of heartbeat \"bar-rab2\": delivered\n",
+			))
+		})
+
+		It("doesn't spool pings failing for other reasons", func() {
+			Expect(execute(repo, "spool", "purge").exitCode).To(Equal(0))
+			repo.Fail("Ping", "bar", errors.New("API call failed"))
+
+			Expect(execute(repo, "ping", "bar").stderr).NotTo(ContainSubstring("spooled"))
+			Expect(execute(repo, "spool", "list").stdout).To(Equal("HEARTBEAT  PINGED  EXPIRES  ATTEMPTS  NEXT ATTEMPT  ERROR\n"))
+		})
+	})
+
+	Describe("safety guard", func() {
+		BeforeEach(func() {
+			GinkgoT().Setenv(cmdutil.ConfirmThresholdEnv, "2")
+		})
+
+		DescribeTable("produces expected output and exit code",
+			func(golden string, exitCode int, input string, args ...string) {
+				r := executeInTerminal(repo, input, args...)
+				Expect(r.exitCode).To(Equal(exitCode))
+				ExpectGolden(r, golden)
+			},
+			Entry("below threshold", "guard_below_threshold", 0, "", "disable", "bar-.*"),
+			Entry("confirmed", "guard_confirmed", 0, "y\n", "disable", "foo.*"),
+			Entry("declined", "guard_declined", 1, "n\n", "disable", "foo.*"),
+			Entry("declined by end of input", "guard_declined_eof", 1, "", "enable", "foo.*"),
+			Entry("confirmed with flag", "guard_yes", 0, "", "enable", "foo.*", "--yes"),
+			Entry("pinging without confirmation", "guard_ping", 0, "", "ping", "foo.*"),
+			Entry("pinging too many targets", "guard_ping_max_targets", 2, "", "ping", ".*", "--max-targets=5"),
+			Entry("too many targets", "guard_max_targets", 2, "y\n", "disable", ".*", "--max-targets=5", "--yes"),
+		)
+
+		It("refuses to change many heartbeats without a terminal", func() {
+			r := execute(repo, "disable", ".*")
+			Expect(r.exitCode).To(Equal(2))
+			ExpectGolden(r, "guard_no_terminal")
+			Expect(execute(repo, "list", "-s", "DISABLED").stdout).To(ContainSubstring("foo-rab1"))
+			Expect(execute(repo, "list", "-s", "DISABLED").stdout).NotTo(ContainSubstring("bar-oof2"))
+		})
+
+		It("doesn't ask for confirmation in dry run mode", func() {
+			r := execute(repo, "disable", ".*", "--dry-run")
+			Expect(r.exitCode).To(Equal(0))
+			Expect(r.stdout).To(ContainSubstring(`heartbeat "bar-oof2" disabled (dry run)`))
+			Expect(execute(repo, "list", "-s", "DISABLED").stdout).NotTo(ContainSubstring("bar-oof2"))
+		})
+
+		It("rejects an invalid threshold", func() {
+			GinkgoT().Setenv(cmdutil.ConfirmThresholdEnv, "many")
+			r := execute(repo, "disable", ".*")
+			Expect(r.exitCode).To(Equal(2))
+			Expect(r.stderr).To(ContainSubstring("HEARTBEATCTL_CONFIRM_THRESHOLD must be a non-negative number"))
+		})
+	})
+
+	It("changes backend state when enabling heartbeats", func() {
+		Expect(execute(repo, "enable", "foo-rab1").exitCode).To(Equal(0))
+		Expect(execute(repo, "get", "foo-rab1").stdout).To(ContainSubstring("ACTIVE"))
+	})
+
+	It("reports heartbeats processed before a failure", func() {
+		repo.Fail("Disable", "foo-oof1", errors.New("API call failed"))
+
+		r := execute(repo, "disable", "foo.*")
+		Expect(r.exitCode).To(Equal(5))
+		ExpectGolden(r, "disable_partial_failure")
+	})
+
+	It("reports failed heartbeats of a partial failure as JSON", func() {
+		repo.Fail("Ping", "foo-oof1", errors.New("API call failed"))
+		repo.Fail("Ping", "foo-rab1", errors.New("API call failed"))
+
+		r := execute(repo, "ping", "--error-format=json", "foo.*")
+		Expect(r.exitCode).To(Equal(5))
+		ExpectGolden(r, "ping_partial_failure_json")
+	})
+
+	It("reports an API error when all heartbeats failed", func() {
+		repo.Fail("Enable", "", errors.New("API call failed"))
+
+		r := execute(repo, "enable", "foo.*")
+		Expect(r.exitCode).To(Equal(1))
+		ExpectGolden(r, "enable_api_error")
+	})
+
+	It("reports an auth error when OpsGenie rejects the API key", func() {
+		repo.Fail("List", "", &ogclient.ApiError{StatusCode: 401, Message: "Could not authenticate"})
+
+		r := execute(repo, "list")
+		Expect(r.exitCode).To(Equal(3))
+		ExpectGolden(r, "list_unauthorized")
+	})
+
+	It("doesn't need a client for commands not talking to OpsGenie", func() {
+		r := executeWithClientError(client.ErrMissingAPIKey, "--help")
+		Expect(r.exitCode).To(Equal(0))
+		Expect(r.stdout).To(ContainSubstring("Exit codes:"))
+	})
+
+	It("reports usage errors before trying to create a client", func() {
+		r := executeWithClientError(client.ErrMissingAPIKey, "enable")
+		Expect(r.exitCode).To(Equal(2))
+	})
+
+	It("reports an unknown state when the client cannot be created", func() {
+		r := executeWithClientError(client.ErrMissingAPIKey, "check")
+		Expect(r.exitCode).To(Equal(3))
+		Expect(r.stdout).To(Equal("HEARTBEATS UNKNOWN - failed to get heartbeats: " + client.ErrMissingAPIKey.Error() + "\n"))
+		Expect(r.stderr).To(BeEmpty())
+	})
+
+	It("reports an auth error when the client cannot be created", func() {
+		r := executeWithClientError(client.ErrMissingAPIKey, "list")
+		Expect(r.exitCode).To(Equal(3))
+		ExpectGolden(r, "list_no_client")
+	})
+})
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+)
+
+var (
+	completionDocLong = heredoc.Doc(`
+		Output shell completion code for the specified shell (bash, zsh, fish, or
+		powershell).
+
+		Besides commands and flags, the completion suggests names of existing
+		heartbeats, label keys and values for '--selector', and field names and
+		values for '--field-selector'. Heartbeats are fetched from OpsGenie and
+		cached for a few minutes in heartbeatctl's cache directory, separately for
+		every API key, so completing them requires a valid API key to be set.
+	`)
+	completionDocExamples = heredoc.Doc(`
+		# load completion for the current bash session
+		source <(heartbeatctl completion bash)
+
+		# install zsh completion, the directory must be in your $fpath
+		heartbeatctl completion zsh > "${fpath[1]}/_heartbeatctl"
+
+		# install fish completion
+		heartbeatctl completion fish > ~/.config/fish/completions/heartbeatctl.fish
+
+		# load completion for the current PowerShell session
+		heartbeatctl completion powershell | Out-String | Invoke-Expression
+	`)
+)
+
+func NewCmdCompletion(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "completion bash|zsh|fish|powershell",
+		Short:                 "Output shell completion code",
+		Long:                  completionDocLong,
+		Example:               completionDocExamples,
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCompletion(f, cmd, args[0])
+		},
+	}
+
+	return cmd
+}
+
+func runCompletion(f *cmdutil.Factory, cmd *cobra.Command, shell string) error {
+	root := cmd.Root()
+
+	switch shell {
+	case "bash":
+		return root.GenBashCompletionV2(f.Out, true)
+	case "zsh":
+		return root.GenZshCompletion(f.Out)
+	case "fish":
+		return root.GenFishCompletion(f.Out, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(f.Out)
+	default:
+		return cmdutil.UsageErrorf("unsupported shell %q", shell)
+	}
+}
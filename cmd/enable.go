@@ -2,12 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 
 	"github.com/MakeNowJust/heredoc/v2"
 	"github.com/spf13/cobra"
 
-	"github.com/giantswarm/heartbeatctl/pkg/client"
 	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
 	"github.com/giantswarm/heartbeatctl/pkg/ctl"
 )
@@ -15,6 +13,9 @@ import (
 // enableCmdOptions holds values for options accepted by the enable command
 type enableCmdOptions struct {
 	selectorOptions *cmdutil.SelectorOptions
+	guardOptions    *cmdutil.GuardOptions
+	outputOptions   *cmdutil.OutputOptions
+	dryRun          bool
 }
 
 var (
@@ -34,9 +35,17 @@ var (
 		expressions with operators like '=', '==', '!=', 'in', 'notin', 'X', '!X'.
 
 		The second is a kubectl-like field selector specified with '--field-selector'
-		flag. This is similar but simpler, fields are exactly the same as fields in
-		heartbeat object with first letter lowercased, and the only operators allowed
-		are '=', '==', '!='.
+		flag. Fields are exactly the same as fields in heartbeat object with first
+		letter lowercased, and operators allowed are '=', '==', '!=', set-based 'in'
+		and 'notin', e.g. 'alertPriority in (P1, P2)', '=~' and '!~' matching regular
+		expressions against the entire value, e.g. 'description=~.*staging.*', and
+		numeric comparisons '>', '>=', '<', '<=' for the 'interval' field.
+
+		The third is a query expression specified with '--query' flag, which can
+		combine comparisons of any fields and labels using the operators above with
+		'and', 'or', 'not' and parentheses, e.g. '(not enabled and alertPriority=P1)
+		or ownerTeam/name=ops'. A label on its own is true when present, and values
+		containing whitespace, commas or parentheses must be quoted.
 
 		And finally any positional arguments are taken as regular expressions to match
 		against heartbeat names. Multiple arguments can be given and they will be joined
@@ -54,6 +63,13 @@ var (
 		# enable all heartbeats with alert priority equal to 'P3'
 		heartbeatctl enable --field-selector=alertPriority=P3
 
+		# enable all heartbeats with alert priority 'P1' or 'P2' and interval above 10
+		heartbeatctl enable --field-selector="alertPriority in (P1, P2),interval>10"
+
+		# enable all disabled heartbeats with alert priority 'P1' and all heartbeats
+		# owned by team 'ops'
+		heartbeatctl enable --query="(not enabled and alertPriority=P1) or ownerTeam/name=ops"
+
 		# enable all non-expired heartbeats with alert priority equal to 'P2' or 'P4'
 		heartbeatctl enable -l "!expired,alertPriority in (P2, P4)"
 
@@ -76,17 +92,15 @@ var (
 	`)
 )
 
-func init() {
-	rootCmd.AddCommand(NewCmdEnable())
-}
-
 func NewEnableOptions() *enableCmdOptions {
 	return &enableCmdOptions{
 		selectorOptions: cmdutil.NewSelectorOptions(),
+		guardOptions:    cmdutil.NewGuardOptions(),
+		outputOptions:   cmdutil.NewOutputOptions(),
 	}
 }
 
-func NewCmdEnable() *cobra.Command {
+func NewCmdEnable(f *cmdutil.Factory) *cobra.Command {
 	opts := NewEnableOptions()
 
 	cmd := &cobra.Command{
@@ -94,28 +108,56 @@ func NewCmdEnable() *cobra.Command {
 		Short:   "Enable heartbeats",
 		Long:    enableDocLong,
 		Example: enableDocExamples,
-		Run: func(cmd *cobra.Command, args []string) {
-			runEnable(opts)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runEnable(f, opts)
 		},
 	}
 
-	opts.selectorOptions.WithCapturingArgsUsingValidator().AddFlags(cmd)
+	opts.selectorOptions.
+		WithCapturingArgsUsingValidator().
+		WithCompletion(cmdutil.NewCompleter(f)).
+		WithSavedSelectors(f.SavedSelectorsPath()).
+		AddFlags(cmd)
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only print heartbeats that would be enabled, without changing them.")
 
 	return cmd
 }
 
-func runEnable(opts *enableCmdOptions) {
-	repo, err := client.New(nil)
+func runEnable(f *cmdutil.Factory, opts *enableCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	selector, err := opts.selectorOptions.ToConfig()
 	if err != nil {
-		log.Fatalf("Failed to init OpsGenie client: %v\n", err)
+		return err
+	}
+
+	if opts.dryRun {
+		plan, err := f.Ctl().Plan(ctl.ActionEnable, selector)
+		if err != nil {
+			return fmt.Errorf("failed to enable heartbeats: %w", err)
+		}
+		return printPlan(f, opts.outputOptions, plan)
 	}
-	c := ctl.NewCtl(repo)
 
-	heartbeats, err := c.Enable(opts.selectorOptions.ToConfig())
-	for _, hbi := range heartbeats {
-		fmt.Printf("heartbeat \"%s\" enabled\n", hbi.Name)
+	c := f.Ctl(ctl.WithGuard(opts.guardOptions.Guard(f)), ctl.WithRecorder(f.Recorder()))
+
+	heartbeats, err := c.Enable(selector)
+	switch {
+	case !opts.outputOptions.JSON():
+		for _, hbi := range heartbeats {
+			fmt.Fprintf(f.Out, "heartbeat \"%s\" enabled\n", hbi.Name)
+		}
+	case len(heartbeats) > 0 || err == nil:
+		// Results are printed on failure only if some heartbeats succeeded.
+		if printErr := printHeartbeatInfosJSON(f, heartbeats); printErr != nil {
+			return printErr
+		}
 	}
 	if err != nil {
-		log.Fatalf("Failed to enable other heartbeats: %v\n", err)
+		return fmt.Errorf("failed to enable heartbeats: %w", err)
 	}
+	return nil
 }
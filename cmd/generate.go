@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/generate"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// generateCmdOptions holds values for options accepted by the generate command
+type generateCmdOptions struct {
+	guardOptions  *cmdutil.GuardOptions
+	outputOptions *cmdutil.OutputOptions
+	templateFile  string
+	inventoryFile string
+	owner         string
+	create        bool
+	dryRun        bool
+}
+
+var (
+	generateDocLong = heredoc.Doc(`
+		Generate heartbeat manifests from a template for every item of an inventory.
+
+		The template given with '--template' is a Go template of one or more
+		heartbeat manifests, rendered for every item of the inventory given with
+		'--inventory', with variables of the item available as fields, e.g.
+		'{{ .name }}'. The inventory is a CSV file with a header line naming
+		variables, or a YAML list of mappings of variables to their values.
+		Variables missing in some items of a YAML inventory are empty in them, and
+		referring to variables no item has is an error.
+
+		Besides standard template functions, these helpers are available:
+		  tag KEY VALUE       quoted 'KEY: VALUE' alert tag, which selectors see
+		                      as label KEY with value VALUE
+		  quote VALUE         VALUE as a quoted string
+		  default DEF VALUE   VALUE, or DEF if VALUE is empty
+		  lower VALUE         VALUE in lower case
+		  upper VALUE         VALUE in upper case
+		  replace OLD NEW S   S with all occurrences of OLD replaced with NEW
+
+		With '--owner', generated heartbeats are tagged as belonging to the set
+		with given ID, so those no longer generated can be deleted with 'prune'.
+
+		Rendered manifests are checked the same way as with 'lint', and are printed
+		to standard output, or with '--create' are applied like with 'restore',
+		creating heartbeats that don't exist and updating those that differ.
+	`)
+	generateDocExamples = heredoc.Doc(`
+		# print heartbeats for installations listed in a CSV file
+		heartbeatctl generate -t heartbeat.yaml.tmpl -i installations.csv
+
+		# show which heartbeats would be created or updated
+		heartbeatctl generate -t heartbeat.yaml.tmpl -i installations.yaml --create --dry-run
+
+		# a template creating a heartbeat per installation, selectable later with
+		# e.g. '--selector=installation=gorilla'
+		apiVersion: heartbeatctl.giantswarm.io/v1alpha1
+		kind: Heartbeat
+		metadata:
+		  name: {{ .name }}-prometheus
+		spec:
+		  interval: {{ default 10 .interval }}
+		  intervalUnit: minutes
+		  ownerTeam: team-rocket
+		  alertTags:
+		    - {{ tag "installation" .name }}
+		    - {{ tag "provider" .provider }}
+	`)
+)
+
+func NewGenerateOptions() *generateCmdOptions {
+	return &generateCmdOptions{
+		guardOptions:  cmdutil.NewGuardOptions(),
+		outputOptions: cmdutil.NewOutputOptions(),
+	}
+}
+
+func NewCmdGenerate(f *cmdutil.Factory) *cobra.Command {
+	opts := NewGenerateOptions()
+
+	cmd := &cobra.Command{
+		Use:     "generate -t TEMPLATE -i INVENTORY",
+		Short:   "Generate heartbeat manifests from a template and an inventory",
+		Long:    generateDocLong,
+		Example: generateDocExamples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGenerate(f, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.templateFile, "template", "t", "", "File with a Go template of heartbeat manifests.")
+	flags.StringVarP(&opts.inventoryFile, "inventory", "i", "", "CSV or YAML file listing items to render the template for, '-' for YAML from standard input.")
+	flags.StringVar(&opts.owner, "owner", "", "ID of the set to tag generated heartbeats as belonging to.")
+	flags.BoolVar(&opts.create, "create", false, "Create or update generated heartbeats instead of printing them.")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "With '--create', only print heartbeats that would be created or updated, without changing them.")
+	_ = cmd.MarkFlagFilename("inventory", "csv", "yaml", "yml")
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+
+	return cmd
+}
+
+func runGenerate(f *cmdutil.Factory, opts *generateCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	switch {
+	case opts.templateFile == "":
+		return cmdutil.UsageErrorf("'--template' must be given")
+	case opts.inventoryFile == "":
+		return cmdutil.UsageErrorf("'--inventory' must be given")
+	case !opts.create && opts.dryRun:
+		return cmdutil.UsageErrorf("'--dry-run' can only be used with '--create'")
+	case !opts.create && opts.outputOptions.JSON():
+		return cmdutil.UsageErrorf("generated manifests are printed as YAML, JSON output can only be used with '--create'")
+	}
+	if opts.owner != "" {
+		if err := validateOwner(opts.owner); err != nil {
+			return err
+		}
+	}
+
+	tmpl, err := generate.ReadTemplate(opts.templateFile)
+	if err != nil {
+		return &cmdutil.UsageError{Err: err}
+	}
+	items, err := generate.ReadInventory(opts.inventoryFile, f.In)
+	if err != nil {
+		return &cmdutil.UsageError{Err: err}
+	}
+	manifests, err := tmpl.Render(items)
+	if err != nil {
+		return &cmdutil.UsageError{Err: err}
+	}
+	if opts.owner != "" {
+		for i := range manifests {
+			manifests[i].SetOwner(opts.owner)
+		}
+	}
+
+	if !opts.create {
+		return manifest.Encode(f.Out, manifests)
+	}
+
+	if opts.dryRun {
+		plan, err := f.Ctl().PlanApply(manifests)
+		if err != nil {
+			return fmt.Errorf("failed to create heartbeats: %w", err)
+		}
+		return printPlan(f, opts.outputOptions, plan)
+	}
+
+	c := f.Ctl(ctl.WithGuard(opts.guardOptions.Guard(f)), ctl.WithRecorder(f.Recorder()))
+
+	applied, err := c.Apply(manifests)
+	if printErr := printApplied(f, opts.outputOptions, applied, err); printErr != nil {
+		return printErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create heartbeats: %w", err)
+	}
+	return nil
+}
@@ -3,38 +3,53 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log"
 
 	"github.com/ryanuber/columnize"
 	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
 )
 
-var (
-	getCmd = &cobra.Command{
+func NewCmdGet(f *cmdutil.Factory) *cobra.Command {
+	completer := cmdutil.NewCompleter(f)
+
+	cmd := &cobra.Command{
 		Use:   "get",
 		Short: "Get heartbeat",
-		Run:   getRun,
 		Args:  cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completer.HeartbeatNames(cmd, args, toComplete)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runGet(f, cmd, args[0])
+		},
 	}
-)
 
-func init() {
-	rootCmd.AddCommand(getCmd)
+	return cmd
 }
 
-func getRun(cmd *cobra.Command, args []string) {
-	heartbeat, err := heartbeatClient.Get(context.Background(), args[0])
+func runGet(f *cmdutil.Factory, cmd *cobra.Command, name string) error {
+	repo, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	heartbeat, err := repo.Get(context.Background(), name)
 	if err != nil {
-		log.Fatalf("%v\n", err)
+		return err
 	}
 
 	output := []string{}
 
-	if !noHeaders {
+	if noHeaders, _ := cmd.Flags().GetBool("no-headers"); !noHeaders {
 		output = append(output, "NAME | STATUS")
 	}
 
 	output = append(output, fmt.Sprintf("%v | %v", heartbeat.Name, getStatus(heartbeat.Heartbeat)))
 
-	fmt.Println(columnize.SimpleFormat(output))
+	fmt.Fprintln(f.Out, columnize.SimpleFormat(output))
+	return nil
 }
@@ -0,0 +1,171 @@
+package cmd_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+
+	"github.com/giantswarm/heartbeatctl/cmd"
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+)
+
+// fixtureHeartbeats returns heartbeats the fake backend is populated with by
+// default.
+func fixtureHeartbeats() []heartbeat.Heartbeat {
+	return []heartbeat.Heartbeat{
+		{
+			Name:          "foo",
+			Interval:      10,
+			IntervalUnit:  "minutes",
+			Enabled:       true,
+			AlertPriority: "P2",
+			OwnerTeam:     og.OwnerTeam{Name: "team-rocket"},
+		},
+		{
+			Name:          "foo-oof1",
+			Interval:      1,
+			IntervalUnit:  "hours",
+			Enabled:       true,
+			AlertPriority: "P3",
+			AlertTags:     []string{"tagged", "managed-by: foobricator"},
+		},
+		{
+			Name:          "foo-rab1",
+			Interval:      5,
+			IntervalUnit:  "minutes",
+			AlertPriority: "P2",
+			AlertTags:     []string{"tagged", "managed-by: foobricator"},
+		},
+		{
+			Name:          "bar",
+			Interval:      10,
+			IntervalUnit:  "minutes",
+			Enabled:       true,
+			AlertPriority: "P2",
+		},
+		{
+			Name:          "bar-oof2",
+			Interval:      1,
+			IntervalUnit:  "days",
+			Enabled:       true,
+			Expired:       true,
+			AlertPriority: "P3",
+			AlertTags:     []string{"tagged", "managed-by: foobricator"},
+		},
+		{
+			Name:          "bar-rab2",
+			Interval:      30,
+			IntervalUnit:  "minutes",
+			AlertPriority: "P4",
+			AlertTags:     []string{"tagged", "managed-by: foobricator"},
+		},
+	}
+}
+
+// configDir and stateDir are the configuration and state directories
+// heartbeatctl is run with, new empty directories for each spec.
+var configDir, stateDir string
+
+// now is the time heartbeatctl is run at.
+var now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+
+var _ = BeforeEach(func() {
+	configDir = GinkgoT().TempDir()
+	stateDir = GinkgoT().TempDir()
+})
+
+// result holds the outcome of running heartbeatctl in-process.
+type result struct {
+	args     []string
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// String renders the result in the format stored in golden files.
+func (r result) String() string {
+	return fmt.Sprintf(
+		"$ heartbeatctl %s\n--- exit code: %d\n--- stdout:\n%s--- stderr:\n%s",
+		strings.Join(r.args, " "), r.exitCode, r.stdout, r.stderr,
+	)
+}
+
+// execute runs heartbeatctl with given args against given client.
+func execute(repo client.Port, args ...string) result {
+	return executeWith(func() (client.Port, error) { return repo, nil }, args...)
+}
+
+// executeWithClientError runs heartbeatctl with given args, failing any
+// attempt to obtain a client with given error.
+func executeWithClientError(err error, args ...string) result {
+	return executeWith(func() (client.Port, error) { return nil, err }, args...)
+}
+
+func executeWith(clientFunc func() (client.Port, error), args ...string) result {
+	return executeWithFactory(func(f *cmdutil.Factory) {
+		f.ClientFunc = clientFunc
+	}, args...)
+}
+
+// executeInTerminal runs heartbeatctl with given args against given client,
+// as if run in a terminal where the user types given input.
+func executeInTerminal(repo client.Port, input string, args ...string) result {
+	return executeWithFactory(func(f *cmdutil.Factory) {
+		f.ClientFunc = func() (client.Port, error) { return repo, nil }
+		f.IsTerminal = func() bool { return true }
+		f.In = bytes.NewBufferString(input)
+	}, args...)
+}
+
+// executeWithFactory runs heartbeatctl with given args, with a factory
+// configured for tests and then customized by given function.
+func executeWithFactory(customize func(*cmdutil.Factory), args ...string) result {
+	var stdout, stderr bytes.Buffer
+	f := cmdutil.NewFactory()
+	f.IOStreams = cmdutil.IOStreams{
+		In:     new(bytes.Buffer),
+		Out:    &stdout,
+		ErrOut: &stderr,
+	}
+	f.Logger.SetOutput(&stderr)
+	f.IsTerminal = func() bool { return false }
+	f.CacheDir = ""
+	f.ConfigDir = configDir
+	f.StateDir = stateDir
+	f.User = "tester"
+	f.Now = func() time.Time { return now }
+	customize(f)
+
+	code := cmd.Run(f, args)
+
+	return result{
+		args:     args,
+		stdout:   stdout.String(),
+		stderr:   stderr.String(),
+		exitCode: code,
+	}
+}
+
+// ExpectGolden asserts the result matches the contents of the named golden
+// file in testdata, or overwrites the file when running with `-update`.
+func ExpectGolden(r result, name string) {
+	GinkgoHelper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		Expect(os.WriteFile(path, []byte(r.String()), 0644)).To(Succeed())
+	}
+
+	expected, err := os.ReadFile(path)
+	Expect(err).NotTo(HaveOccurred(), "golden file missing, run tests with -update to create it")
+	Expect(r.String()).To(Equal(string(expected)))
+}
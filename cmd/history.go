@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/ryanuber/columnize"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/journal"
+)
+
+// historyCmdOptions holds values for options accepted by the history command
+type historyCmdOptions struct {
+	outputOptions *cmdutil.OutputOptions
+}
+
+var (
+	historyDocLong = heredoc.Doc(`
+		List changes made to heartbeats.
+
+		Every run of 'enable', 'disable', 'ping', 'restore', 'generate --create',
+		'prune', 'set-schedule' and 'undo' is recorded in a journal in heartbeatctl's
+		state directory, e.g.
+		'~/.local/state/heartbeatctl/journal.jsonl', along with the state of every
+		heartbeat it touched from before the change, the command line, the user and
+		time it was run at. Changes can be reverted using 'undo'.
+	`)
+)
+
+func NewHistoryOptions() *historyCmdOptions {
+	return &historyCmdOptions{
+		outputOptions: cmdutil.NewOutputOptions(),
+	}
+}
+
+func NewCmdHistory(f *cmdutil.Factory) *cobra.Command {
+	opts := NewHistoryOptions()
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List changes made to heartbeats",
+		Long:  historyDocLong,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runHistory(f, cmd, opts)
+		},
+	}
+
+	opts.outputOptions.AddFlags(cmd)
+
+	return cmd
+}
+
+func runHistory(f *cmdutil.Factory, cmd *cobra.Command, opts *historyCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	j, err := openJournal(f)
+	if err != nil {
+		return err
+	}
+
+	entries, err := j.Entries()
+	if err != nil {
+		return err
+	}
+
+	if opts.outputOptions.JSON() {
+		if entries == nil {
+			return cmdutil.PrintJSON(f.Out, []struct{}{})
+		}
+		return cmdutil.PrintJSON(f.Out, entries)
+	}
+
+	// Command lines may contain the default '|' delimiter, so columns are
+	// delimited by a control character instead.
+	config := columnize.DefaultConfig()
+	config.Delim = "\x1f"
+	config.Empty = "<none>"
+
+	output := []string{}
+
+	if noHeaders, _ := cmd.Flags().GetBool("no-headers"); !noHeaders {
+		output = append(output, "ID\x1fTIME\x1fUSER\x1fACTION\x1fHEARTBEATS\x1fCOMMAND")
+	}
+
+	undoneBy := journal.UndoneBy(entries)
+	for _, e := range entries {
+		action := e.Action
+		if e.Undoes != 0 {
+			action = fmt.Sprintf("%s (undo of %d)", action, e.Undoes)
+		}
+		if id, ok := undoneBy[e.ID]; ok {
+			action = fmt.Sprintf("%s (undone by %d)", action, id)
+		}
+		output = append(output, strings.Join([]string{
+			strconv.Itoa(e.ID),
+			e.Time.Format(time.RFC3339),
+			e.User,
+			action,
+			strconv.Itoa(len(e.Heartbeats)),
+			e.Command,
+		}, "\x1f"))
+	}
+
+	fmt.Fprintln(f.Out, columnize.Format(output, config))
+	return nil
+}
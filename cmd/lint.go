@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// lintCmdOptions holds values for options accepted by the lint command
+type lintCmdOptions struct {
+	outputOptions *cmdutil.OutputOptions
+	files         []string
+	printSchema   bool
+}
+
+var (
+	lintDocLong = heredoc.Doc(`
+		Check heartbeat manifests for mistakes.
+
+		Manifests in files given with '--filename' are checked without talking to
+		OpsGenie, and every problem found is reported with the file, line and
+		column it was found at. Besides checking manifests are well-formed and
+		contain only known fields, heartbeats must have supported interval units
+		and alert priorities, intervals must not be below the OpsGenie minimum and
+		names must be unique across all files. Alert tags must be usable as labels
+		in selectors, e.g. 'key: value' tags must have a non-empty key and value
+		and must not set the same label as heartbeat fields or other tags.
+
+		The command fails if any problems are found. The JSON Schema of manifests,
+		e.g. for editors validating them, is printed with '--print-schema'.
+	`)
+	lintDocExamples = heredoc.Doc(`
+		# check manifests in two files
+		heartbeatctl lint -f gorilla.yaml -f gaia.yaml
+
+		# check manifests written by another tool
+		generate-heartbeats | heartbeatctl lint -f -
+
+		# save the JSON Schema of manifests
+		heartbeatctl lint --print-schema > heartbeat.schema.json
+	`)
+)
+
+func NewLintOptions() *lintCmdOptions {
+	return &lintCmdOptions{
+		outputOptions: cmdutil.NewOutputOptions(),
+	}
+}
+
+func NewCmdLint(f *cmdutil.Factory) *cobra.Command {
+	opts := NewLintOptions()
+
+	cmd := &cobra.Command{
+		Use:     "lint -f FILE..",
+		Short:   "Check heartbeat manifests for mistakes",
+		Long:    lintDocLong,
+		Example: lintDocExamples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLint(f, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringArrayVarP(&opts.files, "filename", "f", nil, "File to read manifests from, '-' for standard input, can be given multiple times.")
+	flags.BoolVar(&opts.printSchema, "print-schema", false, "Print the JSON Schema of manifests instead of checking them.")
+	_ = cmd.MarkFlagFilename("filename", "yaml", "yml")
+	opts.outputOptions.AddFlags(cmd)
+
+	return cmd
+}
+
+func runLint(f *cmdutil.Factory, opts *lintCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	if opts.printSchema {
+		_, err := f.Out.Write(manifest.Schema)
+		return err
+	}
+	if len(opts.files) == 0 {
+		return cmdutil.UsageErrorf("'--filename' must be given")
+	}
+
+	linter := manifest.NewLinter()
+	for _, path := range opts.files {
+		if path == "-" {
+			linter.Lint("<stdin>", f.In)
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return &cmdutil.UsageError{Err: err}
+		}
+		linter.Lint(path, file)
+		file.Close()
+	}
+
+	problems := linter.Problems()
+	if opts.outputOptions.JSON() {
+		if problems == nil {
+			problems = []manifest.Problem{}
+		}
+		if err := cmdutil.PrintJSON(f.Out, problems); err != nil {
+			return err
+		}
+	} else {
+		for _, p := range problems {
+			fmt.Fprintln(f.Out, p)
+		}
+	}
+
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New("found 1 problem in manifests")
+	default:
+		return fmt.Errorf("found %d problems in manifests", len(problems))
+	}
+}
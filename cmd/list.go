@@ -1,15 +1,14 @@
 package cmd
 
 import (
-	"context"
 	"fmt"
-	"log"
-	"sort"
-	"sync"
 
 	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
 	"github.com/ryanuber/columnize"
 	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
 )
 
 const (
@@ -18,20 +17,9 @@ const (
 	StatusExpired  = "EXPIRED"
 )
 
-var (
-	listCmd = &cobra.Command{
-		Use:   "list",
-		Short: "List heartbeats",
-		Run:   listRun,
-	}
-
+// listCmdOptions holds values for options accepted by the list command
+type listCmdOptions struct {
 	status string
-)
-
-func init() {
-	rootCmd.AddCommand(listCmd)
-
-	listCmd.Flags().StringVarP(&status, "status", "s", "", fmt.Sprintf("status of heartbeats to filter for, one of '%s', '%s', or '%s'", StatusActive, StatusDisabled, StatusExpired))
 }
 
 func getStatus(hb heartbeat.Heartbeat) string {
@@ -45,66 +33,57 @@ func getStatus(hb heartbeat.Heartbeat) string {
 	return StatusActive
 }
 
-func listRun(cmd *cobra.Command, args []string) {
-	// Validate status flag.
-	if status != "" && status != StatusActive && status != StatusDisabled && status != StatusExpired {
-		log.Fatalf("status must be one of '%s', '%s', or '%s'\n", StatusActive, StatusDisabled, StatusExpired)
-	}
+func NewListOptions() *listCmdOptions {
+	return &listCmdOptions{}
+}
 
-	// List all heartbeats.
-	result, err := heartbeatClient.List(context.Background())
-	if err != nil {
-		log.Fatalf("%v\n", err)
-	}
-	listHeartbeats := result.Heartbeats
+func NewCmdList(f *cmdutil.Factory) *cobra.Command {
+	opts := NewListOptions()
 
-	// As expiry field is incorrect due to OpsGenie API bug,
-	// request each heartbeat individually (in parallel),
-	// which returns the correct expiry data.
-	var wg sync.WaitGroup
-	ch := make(chan heartbeat.Heartbeat)
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List heartbeats",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runList(f, cmd, opts)
+		},
+	}
 
-	for _, hb := range listHeartbeats {
-		wg.Add(1)
+	cmd.Flags().StringVarP(&opts.status, "status", "s", "", fmt.Sprintf("status of heartbeats to filter for, one of '%s', '%s', or '%s'", StatusActive, StatusDisabled, StatusExpired))
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions(
+		[]string{StatusActive, StatusDisabled, StatusExpired}, cobra.ShellCompDirectiveNoFileComp,
+	))
 
-		go func(hb heartbeat.Heartbeat, ch chan heartbeat.Heartbeat) {
-			newHb, err := heartbeatClient.Get(context.Background(), hb.Name)
-			if err != nil {
-				log.Fatalf("%v\n", err)
-			}
+	return cmd
+}
 
-			ch <- newHb.Heartbeat
-		}(hb, ch)
+func runList(f *cmdutil.Factory, cmd *cobra.Command, opts *listCmdOptions) error {
+	// Validate status flag.
+	if opts.status != "" && opts.status != StatusActive && opts.status != StatusDisabled && opts.status != StatusExpired {
+		return cmdutil.UsageErrorf("status must be one of '%s', '%s', or '%s'", StatusActive, StatusDisabled, StatusExpired)
 	}
 
-	heartbeats := []heartbeat.Heartbeat{}
-	go func(wg *sync.WaitGroup) {
-		for hb := range ch {
-			heartbeats = append(heartbeats, hb)
-			wg.Done()
-		}
-	}(&wg)
+	c := f.Ctl()
 
-	wg.Wait()
-	close(ch)
+	// List all heartbeats, sorted by name.
+	heartbeats, err := c.Get(&ctl.SelectorConfig{})
+	if err != nil {
+		return err
+	}
 
 	// Filter by status.
-	if status != "" {
+	if opts.status != "" {
 		filteredHeartbeats := []heartbeat.Heartbeat{}
 		for _, hb := range heartbeats {
-			if status == getStatus(hb) {
+			if opts.status == getStatus(hb) {
 				filteredHeartbeats = append(filteredHeartbeats, hb)
 			}
 		}
 		heartbeats = filteredHeartbeats
 	}
 
-	// And sort, as we will have lost original ordering while requesting individual heartbeats.
-	sort.Slice(heartbeats, func(i, j int) bool { return heartbeats[i].Name < heartbeats[j].Name })
-
 	output := []string{}
 
-	if !noHeaders {
+	if noHeaders, _ := cmd.Flags().GetBool("no-headers"); !noHeaders {
 		output = append(output, "NAME | STATUS")
 	}
 
@@ -112,5 +91,6 @@ func listRun(cmd *cobra.Command, args []string) {
 		output = append(output, fmt.Sprintf("%v | %v", heartbeat.Name, getStatus(heartbeat)))
 	}
 
-	fmt.Println(columnize.SimpleFormat(output))
+	fmt.Fprintln(f.Out, columnize.SimpleFormat(output))
+	return nil
 }
@@ -0,0 +1,278 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/giantswarm/heartbeatctl/pkg/apis/v1alpha1"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/operator"
+)
+
+// operatorCmdOptions holds values for options accepted by the operator
+// command
+type operatorCmdOptions struct {
+	kubeconfig         string
+	namespace          string
+	owner              string
+	resyncPeriod       time.Duration
+	metricsAddress     string
+	healthProbeAddress string
+	leaderElect        bool
+	controllers        []string
+	cronJobOwner       string
+	cronJobGracePeriod time.Duration
+}
+
+// Controllers the operator can run.
+const (
+	controllerHeartbeats = "heartbeats"
+	controllerCronJobs   = "cronjobs"
+)
+
+var (
+	operatorDocLong = heredoc.Doc(`
+		Keep heartbeats in sync with Heartbeat resources in a Kubernetes cluster.
+
+		Every Heartbeat resource describes a heartbeat with the same fields as
+		manifests written by 'backup' and read by 'reconcile', named after the
+		resource unless 'spec.name' is set. Heartbeats are created or updated when resources
+		change, and again every '--resync-period' to correct changes made by hand.
+		A finalizer deletes the heartbeat before its resource is deleted, and
+		renaming a heartbeat deletes the one with the old name.
+
+		The outcome is reported in the status of each resource: the name of the
+		heartbeat, whether it's enabled and expired, when a sync last changed it, and a
+		'Ready' condition with reason 'Synced', 'InvalidSpec' for specs 'lint'
+		would report problems with, which are not retried until fixed, or
+		'SyncFailed' for failed requests to OpsGenie, which are retried.
+
+		With '--owner', heartbeats are tagged as belonging to the set with given
+		ID, and only heartbeats of the set are ever deleted.
+
+		With '--controllers=cronjobs', every CronJob annotated with
+		'heartbeatctl.giantswarm.io/enabled: "true"' gets a heartbeat named
+		'<namespace>.<name>' and tagged with 'namespace: <namespace>' and
+		'cronjob: <name>'. Its interval is the longest gap between runs of the
+		CronJob plus '--cronjob-grace-period', and it's disabled while the CronJob
+		is suspended. The heartbeat is pinged whenever a Job of the CronJob
+		succeeds, and deleted when the CronJob is deleted or no longer annotated.
+		These annotations override the defaults:
+
+		  heartbeatctl.giantswarm.io/name            name of the heartbeat
+		  heartbeatctl.giantswarm.io/grace-period    grace period, e.g. '1h'
+		  heartbeatctl.giantswarm.io/owner-team      team owning the heartbeat
+		  heartbeatctl.giantswarm.io/alert-priority  priority of its alerts
+
+		Heartbeats of CronJobs belong to the set '--cronjob-owner'. Heartbeats of
+		the set whose CronJobs were deleted while the operator wasn't running are
+		deleted when it starts, only those tagged with '--namespace' if it's set.
+
+		The custom resource definition and the role the operator needs are in the
+		'config' directory of the repository. The cluster is reached through
+		'--kubeconfig', the KUBECONFIG environment variable, the in-cluster
+		configuration or '~/.kube/config', in this order.
+	`)
+	operatorDocExamples = heredoc.Doc(`
+		# run the operator in a cluster, with leader election for more replicas
+		heartbeatctl operator --leader-elect --owner=operator
+
+		# run the operator locally against heartbeats in one namespace
+		heartbeatctl operator --kubeconfig ~/.kube/config --namespace=monitoring
+
+		# only manage heartbeats of annotated CronJobs
+		heartbeatctl operator --controllers=cronjobs --cronjob-grace-period=30m
+	`)
+)
+
+func NewOperatorOptions() *operatorCmdOptions {
+	return &operatorCmdOptions{
+		resyncPeriod:       10 * time.Minute,
+		metricsAddress:     ":8080",
+		healthProbeAddress: ":8081",
+		controllers:        []string{controllerHeartbeats},
+		cronJobOwner:       "cronjobs",
+		cronJobGracePeriod: 10 * time.Minute,
+	}
+}
+
+func NewCmdOperator(f *cmdutil.Factory) *cobra.Command {
+	opts := NewOperatorOptions()
+
+	cmd := &cobra.Command{
+		Use:     "operator",
+		Short:   "Keep heartbeats in sync with Heartbeat resources in a Kubernetes cluster",
+		Long:    operatorDocLong,
+		Example: operatorDocExamples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("verbosity") {
+				f.Logger.SetLevel(logrus.InfoLevel)
+			}
+			return runOperator(cmd.Context(), f, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.kubeconfig, "kubeconfig", opts.kubeconfig, "Path to the kubeconfig file to reach the cluster with.")
+	flags.StringVar(&opts.namespace, "namespace", opts.namespace, "Namespace to watch Heartbeat resources and CronJobs in, all namespaces if empty.")
+	flags.StringVar(&opts.owner, "owner", opts.owner, "ID of the set to tag heartbeats as belonging to, only deleting heartbeats of the set.")
+	flags.DurationVar(&opts.resyncPeriod, "resync-period", opts.resyncPeriod, "How often to sync heartbeats again, 0 to only sync on changes.")
+	flags.StringVar(&opts.metricsAddress, "metrics-address", opts.metricsAddress, "Address to serve metrics at, '0' to disable.")
+	flags.StringVar(&opts.healthProbeAddress, "health-probe-address", opts.healthProbeAddress, "Address to serve '/healthz' and '/readyz' at, '0' to disable.")
+	flags.BoolVar(&opts.leaderElect, "leader-elect", opts.leaderElect, "Elect a leader so only one replica syncs heartbeats.")
+	flags.StringSliceVar(&opts.controllers, "controllers", opts.controllers, "Controllers to run, 'heartbeats' for Heartbeat resources and 'cronjobs' for annotated CronJobs.")
+	flags.StringVar(&opts.cronJobOwner, "cronjob-owner", opts.cronJobOwner, "ID of the set heartbeats of CronJobs belong to.")
+	flags.DurationVar(&opts.cronJobGracePeriod, "cronjob-grace-period", opts.cronJobGracePeriod, "Time added to the longest gap between runs of CronJobs.")
+	_ = cmd.MarkFlagFilename("kubeconfig")
+	_ = cmd.RegisterFlagCompletionFunc("controllers", cobra.FixedCompletions(
+		[]string{controllerHeartbeats, controllerCronJobs}, cobra.ShellCompDirectiveNoFileComp,
+	))
+
+	return cmd
+}
+
+func runOperator(ctx context.Context, f *cmdutil.Factory, opts *operatorCmdOptions) error {
+	if opts.resyncPeriod < 0 {
+		return cmdutil.UsageErrorf("'--resync-period' must not be negative, got %s", opts.resyncPeriod)
+	}
+	if opts.owner != "" {
+		if err := validateOwner(opts.owner); err != nil {
+			return err
+		}
+	}
+	controllers := map[string]bool{}
+	for _, c := range opts.controllers {
+		if c != controllerHeartbeats && c != controllerCronJobs {
+			return cmdutil.UsageErrorf("unknown controller %q, must be one of %s, %s", c, controllerHeartbeats, controllerCronJobs)
+		}
+		controllers[c] = true
+	}
+	if len(controllers) == 0 {
+		return cmdutil.UsageErrorf("'--controllers' must name at least one controller")
+	}
+	if controllers[controllerCronJobs] {
+		if err := validateOwner(opts.cronJobOwner); err != nil {
+			return err
+		}
+		if opts.cronJobGracePeriod < 0 {
+			return cmdutil.UsageErrorf("'--cronjob-grace-period' must not be negative, got %s", opts.cronJobGracePeriod)
+		}
+	}
+
+	config, err := kubeconfig(opts.kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	ctrl.SetLogger(operator.NewLogger(f.Logger))
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return err
+	}
+	mgrOpts := ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: opts.metricsAddress},
+		HealthProbeBindAddress: opts.healthProbeAddress,
+		LeaderElection:         opts.leaderElect,
+		LeaderElectionID:       "heartbeatctl-operator.giantswarm.io",
+	}
+	if opts.namespace != "" {
+		mgrOpts.Cache = cache.Options{DefaultNamespaces: map[string]cache.Config{opts.namespace: {}}}
+		mgrOpts.LeaderElectionNamespace = opts.namespace
+	}
+	mgr, err := ctrl.NewManager(config, mgrOpts)
+	if err != nil {
+		return fmt.Errorf("failed to create manager: %w", err)
+	}
+
+	c, err := f.Client()
+	if err != nil {
+		return err
+	}
+	if controllers[controllerHeartbeats] {
+		reconciler := &operator.HeartbeatReconciler{
+			Client:       mgr.GetClient(),
+			Ctl:          f.Ctl(),
+			OpsGenie:     c,
+			Owner:        opts.owner,
+			ResyncPeriod: opts.resyncPeriod,
+			Logger:       f.Logger,
+			Now:          f.Now,
+		}
+		if err := reconciler.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to set up controller: %w", err)
+		}
+	}
+	if controllers[controllerCronJobs] {
+		reconciler := &operator.CronJobReconciler{
+			Client:       mgr.GetClient(),
+			Ctl:          f.Ctl(),
+			OpsGenie:     c,
+			Owner:        opts.cronJobOwner,
+			Namespace:    opts.namespace,
+			GracePeriod:  opts.cronJobGracePeriod,
+			ResyncPeriod: opts.resyncPeriod,
+			Logger:       f.Logger,
+			Now:          f.Now,
+		}
+		if err := reconciler.SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("failed to set up CronJob controller: %w", err)
+		}
+	}
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := mgr.Start(ctx); err != nil {
+		return fmt.Errorf("failed to run operator: %w", err)
+	}
+	return nil
+}
+
+// kubeconfig returns the configuration to reach the cluster with, read from
+// given file if a path is given.
+func kubeconfig(path string) (*rest.Config, error) {
+	if path == "" {
+		config, err := ctrl.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		return config, nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, &cmdutil.UsageError{Err: err}
+	}
+	config, err := clientcmd.BuildConfigFromFlags("", path)
+	if err != nil {
+		return nil, &cmdutil.UsageError{Err: fmt.Errorf("failed to load kubeconfig: %w", err)}
+	}
+	return config, nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// actionResults maps actions to words describing heartbeats they were
+// applied to.
+var actionResults = map[string]string{
+	ctl.ActionEnable:  "enabled",
+	ctl.ActionDisable: "disabled",
+	ctl.ActionPing:    "pinged",
+	ctl.ActionDelete:  "deleted",
+	ctl.ActionRestore: "restored",
+	ctl.ActionCreate:  "created",
+	ctl.ActionUpdate:  "updated",
+}
+
+// heartbeatResult is the JSON representation of a heartbeat changed by a
+// command.
+type heartbeatResult struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Expired bool   `json:"expired"`
+}
+
+// printHeartbeatInfosJSON prints given heartbeats changed by a command as
+// JSON.
+func printHeartbeatInfosJSON(f *cmdutil.Factory, infos []heartbeat.HeartbeatInfo) error {
+	results := make([]heartbeatResult, 0, len(infos))
+	for _, hbi := range infos {
+		results = append(results, heartbeatResult{Name: hbi.Name, Enabled: hbi.Enabled, Expired: hbi.Expired})
+	}
+	return cmdutil.PrintJSON(f.Out, results)
+}
+
+// printPlan prints actions planned in dry run mode in given output format.
+func printPlan(f *cmdutil.Factory, output *cmdutil.OutputOptions, plan []ctl.PlannedAction) error {
+	if output.JSON() {
+		return cmdutil.PrintJSON(f.Out, plan)
+	}
+
+	for _, a := range plan {
+		result := actionResults[a.Action]
+		if a.Unchanged {
+			result = "unchanged"
+		}
+		fmt.Fprintf(f.Out, "heartbeat \"%s\" %s (dry run)\n", a.Heartbeat, result)
+		warnUncleared(f, a.Heartbeat, a.Uncleared)
+	}
+	return nil
+}
+
+// warnUncleared warns that given fields of a heartbeat were left in place as
+// OpsGenie can't clear them, if there are any.
+func warnUncleared(f *cmdutil.Factory, name string, fields []string) {
+	if len(fields) > 0 {
+		fmt.Fprintf(f.ErrOut, "heartbeat \"%s\" keeps its %s, which OpsGenie can't clear\n", name, strings.Join(fields, " and "))
+	}
+}
+
+// printApplied prints actions taken to make heartbeats match manifests in
+// given output format. With JSON output, results of a failed operation are
+// only printed if some heartbeats succeeded.
+func printApplied(f *cmdutil.Factory, output *cmdutil.OutputOptions, applied []ctl.PlannedAction, err error) error {
+	if output.JSON() {
+		if len(applied) == 0 && err != nil {
+			return nil
+		}
+		return cmdutil.PrintJSON(f.Out, applied)
+	}
+
+	for _, a := range applied {
+		result := actionResults[a.Action]
+		if a.Unchanged {
+			result = "unchanged"
+		}
+		fmt.Fprintf(f.Out, "heartbeat \"%s\" %s\n", a.Heartbeat, result)
+		warnUncleared(f, a.Heartbeat, a.Uncleared)
+	}
+	return nil
+}
@@ -1,20 +1,28 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"regexp"
+	"sort"
 
 	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
 	"github.com/spf13/cobra"
 
-	"github.com/giantswarm/heartbeatctl/pkg/client"
 	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
 	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/spool"
 )
 
 // pingCmdOptions holds values for options accepted by the ping command
 type pingCmdOptions struct {
 	selectorOptions *cmdutil.SelectorOptions
+	guardOptions    *cmdutil.GuardOptions
+	outputOptions   *cmdutil.OutputOptions
+	dryRun          bool
+	noSpool         bool
 }
 
 var (
@@ -37,14 +45,32 @@ var (
 		expressions with operators like '=', '==', '!=', 'in', 'notin', 'X', '!X'.
 
 		The second is a kubectl-like field selector specified with '--field-selector'
-		flag. This is similar but simpler, fields are exactly the same as fields in
-		heartbeat object with first letter lowercased, and the only operators allowed
-		are '=', '==', '!='.
+		flag. Fields are exactly the same as fields in heartbeat object with first
+		letter lowercased, and operators allowed are '=', '==', '!=', set-based 'in'
+		and 'notin', e.g. 'alertPriority in (P1, P2)', '=~' and '!~' matching regular
+		expressions against the entire value, e.g. 'description=~.*staging.*', and
+		numeric comparisons '>', '>=', '<', '<=' for the 'interval' field.
+
+		The third is a query expression specified with '--query' flag, which can
+		combine comparisons of any fields and labels using the operators above with
+		'and', 'or', 'not' and parentheses, e.g. '(not enabled and alertPriority=P1)
+		or ownerTeam/name=ops'. A label on its own is true when present, and values
+		containing whitespace, commas or parentheses must be quoted.
+
 		And finally any positional arguments are taken as regular expressions to match
 		against heartbeat names. Multiple arguments can be given and they will be joined
 		into an or-expression and wrapped in beginning and end-of-string bounds so the
 		expressions have to match the entire name. E.g. parameters 'foo' 'bar-.*' will
 		result in a regex '^(foo|bar-.*)$'.
+
+		Pings that fail because OpsGenie can't be reached are kept in a spool in
+		heartbeatctl's state directory, e.g. '~/.local/state/heartbeatctl/spool.jsonl',
+		and replayed with backoff by the next 'ping' that reaches OpsGenie, so a
+		brief network outage doesn't let heartbeats expire. If OpsGenie can't be
+		reached to select heartbeats, pings are only spooled when heartbeats are
+		given by exact names. Spooled pings older than the heartbeat interval are
+		discarded. Use 'spool' to inspect, flush or purge spooled pings, and
+		'--no-spool' to not spool failed pings.
 	`)
 	pingDocExamples = heredoc.Doc(`
 		# ping all heartbeats with specified label 'managed-by' equal to 'foobricator'
@@ -53,6 +79,11 @@ var (
 		heartbeatctl ping --selector="!enabled,managed-by=foobricator"
 		# ping all heartbeats with alert priority equal to 'P3'
 		heartbeatctl ping --field-selector=alertPriority=P3
+		# ping all heartbeats with alert priority 'P1' or 'P2' and interval above 10
+		heartbeatctl ping --field-selector="alertPriority in (P1, P2),interval>10"
+		# ping all disabled heartbeats with alert priority 'P1' and all heartbeats
+		# owned by team 'ops'
+		heartbeatctl ping --query="(not enabled and alertPriority=P1) or ownerTeam/name=ops"
 		# ping all non-expired heartbeats with alert priority equal to 'P2' or 'P4'
 		heartbeatctl ping -l "!expired,alertPriority in (P2, P4)"
 		# ping expired heartbeats with alert priority equal to 'P3'
@@ -72,17 +103,15 @@ var (
 	`)
 )
 
-func init() {
-	rootCmd.AddCommand(NewCmdPing())
-}
-
 func NewPingOptions() *pingCmdOptions {
 	return &pingCmdOptions{
 		selectorOptions: cmdutil.NewSelectorOptions(),
+		guardOptions:    cmdutil.NewGuardOptions().WithoutConfirmation(),
+		outputOptions:   cmdutil.NewOutputOptions(),
 	}
 }
 
-func NewCmdPing() *cobra.Command {
+func NewCmdPing(f *cmdutil.Factory) *cobra.Command {
 	opts := NewPingOptions()
 
 	cmd := &cobra.Command{
@@ -90,27 +119,166 @@ func NewCmdPing() *cobra.Command {
 		Short:   "Ping heartbeats",
 		Long:    pingDocLong,
 		Example: pingDocExamples,
-		Run: func(cmd *cobra.Command, args []string) {
-			runPing(opts)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPing(f, opts)
 		},
 	}
 
-	opts.selectorOptions.WithCapturingArgsUsingValidator().AddFlags(cmd)
+	opts.selectorOptions.
+		WithCapturingArgsUsingValidator().
+		WithCompletion(cmdutil.NewCompleter(f)).
+		WithSavedSelectors(f.SavedSelectorsPath()).
+		AddFlags(cmd)
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only print heartbeats that would be pinged, without changing them.")
+	cmd.Flags().BoolVar(&opts.noSpool, "no-spool", false, "Don't spool pings that fail because OpsGenie can't be reached, nor replay spooled ones.")
 
 	return cmd
 }
 
-func runPing(opts *pingCmdOptions) {
-	repo, err := client.New(nil)
+func runPing(f *cmdutil.Factory, opts *pingCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	selector, err := opts.selectorOptions.ToConfig()
 	if err != nil {
-		log.Fatalf("Failed to init OpsGenie client: %v\n", err)
+		return err
+	}
+
+	if opts.dryRun {
+		plan, err := f.Ctl().Plan(ctl.ActionPing, selector)
+		if err != nil {
+			return fmt.Errorf("failed to ping heartbeats: %w", err)
+		}
+		return printPlan(f, opts.outputOptions, plan)
+	}
+
+	c := f.Ctl(ctl.WithGuard(opts.guardOptions.Guard(f)), ctl.WithRecorder(f.Recorder()))
+	pings, err := c.Ping(selector)
+	if !opts.noSpool {
+		spoolPings(f, selector, pings, err)
+	}
+
+	switch {
+	case !opts.outputOptions.JSON():
+		printPings(f, pings)
+	case len(pings) > 0 || err == nil:
+		// Results are printed on failure only if some heartbeats succeeded.
+		if printErr := printPingsJSON(f, pings); printErr != nil {
+			return printErr
+		}
 	}
-	c := ctl.NewCtl(repo)
-	pings, err := c.Ping(opts.selectorOptions.ToConfig())
 	if err != nil {
-		log.Fatalf("Failed to ping heartbeats: %v\n", err)
+		return fmt.Errorf("failed to ping heartbeats: %w", err)
+	}
+	return nil
+}
+
+// spoolPings keeps pings that failed because OpsGenie couldn't be reached in
+// the spool, and drops spooled pings of heartbeats that were pinged. If no
+// ping had to be spooled, spooled pings that are due are replayed. Problems
+// with the spool are only reported, as they don't change the result of the
+// ping.
+func spoolPings(f *cmdutil.Factory, selector *ctl.SelectorConfig, pings map[string]heartbeat.PingResult, err error) {
+	s := f.Spool()
+	if s == nil {
+		return
+	}
+
+	spooled := map[string]error{}
+	var hbErr *ctl.HeartbeatsError
+	switch {
+	case errors.As(err, &hbErr):
+		for _, name := range hbErr.Failed {
+			if spool.Retryable(hbErr.Errors[name]) {
+				spooled[name] = hbErr.Errors[name]
+			}
+		}
+	case err != nil && spool.Retryable(err):
+		// Selecting heartbeats failed, so only heartbeats given by exact
+		// names are known.
+		for _, name := range exactNames(selector) {
+			spooled[name] = err
+		}
+	}
+
+	if len(spooled) > 0 {
+		if spoolErr := s.Add(spooled, f.Now()); spoolErr != nil {
+			fmt.Fprintf(f.ErrOut, "failed to spool pings: %v\n", spoolErr)
+			return
+		}
+		names := make([]string, 0, len(spooled))
+		for name := range spooled {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(f.ErrOut, "ping of heartbeat \"%s\" spooled, to be replayed by the next ping or 'spool flush'\n", name)
+		}
+		return
 	}
-	for name, ping := range pings {
-		fmt.Printf("heartbeat \"%s\": %s\n", name, ping.Message)
+	if len(pings) == 0 {
+		return
+	}
+
+	if _, spoolErr := s.Remove(sortedPingNames(pings)...); spoolErr != nil {
+		fmt.Fprintf(f.ErrOut, "failed to update spooled pings: %v\n", spoolErr)
+		return
+	}
+	c, clientErr := f.Client()
+	if clientErr != nil {
+		return
+	}
+	results, spoolErr := s.Replay(context.Background(), c, f.Now(), false)
+	printSpoolResults(f.ErrOut, results)
+	if spoolErr != nil {
+		fmt.Fprintf(f.ErrOut, "failed to replay spooled pings: %v\n", spoolErr)
+	}
+}
+
+// exactNames returns names given in given selector if it selects heartbeats
+// by exact names only, or nil otherwise.
+func exactNames(selector *ctl.SelectorConfig) []string {
+	if selector.LabelSelector != "" || selector.FieldSelector != "" || selector.Query != "" {
+		return nil
+	}
+	for _, expr := range selector.NameExpressions {
+		if regexp.QuoteMeta(expr) != expr {
+			return nil
+		}
+	}
+	return selector.NameExpressions
+}
+
+// pingResult is the JSON representation of a heartbeat ping.
+type pingResult struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// printPings prints results of pinging heartbeats, sorted by name.
+func printPings(f *cmdutil.Factory, pings map[string]heartbeat.PingResult) {
+	for _, name := range sortedPingNames(pings) {
+		fmt.Fprintf(f.Out, "heartbeat \"%s\": %s\n", name, pings[name].Message)
+	}
+}
+
+// printPingsJSON prints results of pinging heartbeats as JSON, sorted by
+// name.
+func printPingsJSON(f *cmdutil.Factory, pings map[string]heartbeat.PingResult) error {
+	results := make([]pingResult, 0, len(pings))
+	for _, name := range sortedPingNames(pings) {
+		results = append(results, pingResult{Name: name, Message: pings[name].Message})
+	}
+	return cmdutil.PrintJSON(f.Out, results)
+}
+
+func sortedPingNames(pings map[string]heartbeat.PingResult) []string {
+	names := make([]string, 0, len(pings))
+	for name := range pings {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
 }
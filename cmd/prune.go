@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// pruneCmdOptions holds values for options accepted by the prune command
+type pruneCmdOptions struct {
+	guardOptions  *cmdutil.GuardOptions
+	outputOptions *cmdutil.OutputOptions
+	owner         string
+	keepFile      string
+	allowEmpty    bool
+	dryRun        bool
+}
+
+var (
+	pruneDocLong = heredoc.Docf(`
+		Delete heartbeats of a set that are no longer wanted.
+
+		Heartbeats belong to the set given with '--owner' if they are tagged with
+		'%s' and '%s: OWNER', e.g. by 'generate --owner'. Those of
+		them whose names are not listed in the file given with '--keep-file', one
+		name per line with empty lines and lines starting with '#' ignored, are
+		deleted. Heartbeats without these tags, e.g. created by hand or by other
+		tools, are never deleted.
+
+		An empty list is more likely a mistake than a request to delete every
+		heartbeat of the set, so it's refused unless '--allow-empty' is given.
+	`, manifest.ManagedByTag, manifest.OwnerLabel)
+	pruneDocExamples = heredoc.Doc(`
+		# show which heartbeats of set 'installations' are not listed in a file
+		heartbeatctl prune --owner=installations --keep-file=names.txt --dry-run
+
+		# delete heartbeats of set 'installations' with names not listed on standard
+		# input
+		printf 'gorilla-prometheus\ngaia-prometheus\n' | heartbeatctl prune --owner=installations --keep-file=-
+	`)
+)
+
+func NewPruneOptions() *pruneCmdOptions {
+	return &pruneCmdOptions{
+		guardOptions:  cmdutil.NewGuardOptions(),
+		outputOptions: cmdutil.NewOutputOptions(),
+	}
+}
+
+func NewCmdPrune(f *cmdutil.Factory) *cobra.Command {
+	opts := NewPruneOptions()
+
+	cmd := &cobra.Command{
+		Use:     "prune --owner OWNER --keep-file FILE",
+		Short:   "Delete heartbeats of a set that are no longer wanted",
+		Long:    pruneDocLong,
+		Example: pruneDocExamples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPrune(f, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.owner, "owner", "", "ID of the set of heartbeats to prune.")
+	flags.StringVar(&opts.keepFile, "keep-file", "", "File listing names of heartbeats to keep, '-' for standard input.")
+	flags.BoolVar(&opts.allowEmpty, "allow-empty", false, "Delete all heartbeats of the set if the file lists no names.")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Only print heartbeats that would be deleted, without deleting them.")
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+
+	return cmd
+}
+
+func runPrune(f *cmdutil.Factory, opts *pruneCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	if err := validateOwner(opts.owner); err != nil {
+		return err
+	}
+	if opts.keepFile == "" {
+		return cmdutil.UsageErrorf("'--keep-file' must be given")
+	}
+
+	keep, err := readNames(opts.keepFile, f.In)
+	if err != nil {
+		return &cmdutil.UsageError{Err: fmt.Errorf("failed to read names to keep: %w", err)}
+	}
+	if len(keep) == 0 && !opts.allowEmpty {
+		return cmdutil.UsageErrorf("no names to keep listed in %q, pass '--allow-empty' to delete all heartbeats of the set", opts.keepFile)
+	}
+
+	if opts.dryRun {
+		plan, err := ctl.Prune(f.Ctl(), opts.owner, keep, true)
+		if err != nil {
+			return fmt.Errorf("failed to prune heartbeats: %w", err)
+		}
+		return printPlan(f, opts.outputOptions, plan)
+	}
+
+	c := f.Ctl(ctl.WithGuard(opts.guardOptions.Guard(f)), ctl.WithRecorder(f.Recorder()))
+
+	deleted, err := ctl.Prune(c, opts.owner, keep, false)
+	if printErr := printApplied(f, opts.outputOptions, deleted, err); printErr != nil {
+		return printErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to prune heartbeats: %w", err)
+	}
+	return nil
+}
+
+// validateOwner returns a UsageError unless given ID of a set of heartbeats
+// can be used as the value of the ownership tag.
+func validateOwner(owner string) error {
+	if owner == "" {
+		return cmdutil.UsageErrorf("'--owner' must be given")
+	}
+	if len(validation.IsValidLabelValue(owner)) > 0 {
+		return cmdutil.UsageErrorf(
+			"invalid owner %q, must be at most %d alphanumeric characters, '-', '_' or '.', starting and ending with an alphanumeric character",
+			owner, validation.LabelValueMaxLength,
+		)
+	}
+	return nil
+}
+
+// readNames reads names listed one per line in given file, or in standard
+// input if the path is "-", ignoring empty lines and lines starting with '#'.
+func readNames(path string, stdin io.Reader) ([]string, error) {
+	r := stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	return names, scanner.Err()
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/reconcile"
+)
+
+// readyMaxIntervals is how many reconciliation intervals can pass since the
+// last successful one before the reconciler is reported as not ready.
+const readyMaxIntervals = 3
+
+// reconcileCmdOptions holds values for options accepted by the reconcile
+// command
+type reconcileCmdOptions struct {
+	path          string
+	owner         string
+	interval      time.Duration
+	listenAddress string
+	once          bool
+}
+
+var (
+	reconcileDocLong = heredoc.Docf(`
+		Keep heartbeats in sync with manifests in a directory.
+
+		Manifests are read from YAML files in the directory given with '--path' and
+		its subdirectories, ignoring those with names starting with '.', e.g. the
+		'..data' directory of a mounted ConfigMap. Right away and then every
+		'--interval', manifests are read again and heartbeats are created or
+		updated to match them, correcting any changes made by hand, with every
+		correction logged. If manifests have any problems 'lint' would report,
+		nothing is changed until they are fixed.
+
+		With '--owner', heartbeats are tagged as belonging to the set with given
+		ID, and heartbeats of the set without a manifest are deleted like with
+		'prune'. Other heartbeats are never deleted.
+
+		Metrics are served in the Prometheus format at '/metrics' on
+		'--listen-address', and '/readyz' reports the last successful
+		reconciliation as JSON, failing unless one happened within the last %d
+		intervals. Changes are logged at 'info' level unless '--verbosity' is given,
+		and are not recorded in the journal.
+
+		The command runs until interrupted, or with '--once' reconciles heartbeats
+		a single time and fails if that fails.
+	`, readyMaxIntervals)
+	reconcileDocExamples = heredoc.Doc(`
+		# keep heartbeats in sync with manifests mounted from a ConfigMap
+		heartbeatctl reconcile --path=/etc/heartbeats --interval=5m --owner=gitops
+
+		# reconcile heartbeats once, e.g. in a CI job
+		heartbeatctl reconcile --path=heartbeats/ --once
+	`)
+)
+
+func NewReconcileOptions() *reconcileCmdOptions {
+	return &reconcileCmdOptions{
+		interval:      5 * time.Minute,
+		listenAddress: ":8080",
+	}
+}
+
+func NewCmdReconcile(f *cmdutil.Factory) *cobra.Command {
+	opts := NewReconcileOptions()
+
+	cmd := &cobra.Command{
+		Use:     "reconcile --path DIR",
+		Short:   "Keep heartbeats in sync with manifests in a directory",
+		Long:    reconcileDocLong,
+		Example: reconcileDocExamples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("verbosity") {
+				f.Logger.SetLevel(logrus.InfoLevel)
+			}
+			return runReconcile(cmd.Context(), f, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.path, "path", opts.path, "Directory to read manifests from.")
+	flags.StringVar(&opts.owner, "owner", opts.owner, "ID of the set to tag heartbeats as belonging to, deleting those of the set without a manifest.")
+	flags.DurationVar(&opts.interval, "interval", opts.interval, "How often to reconcile heartbeats.")
+	flags.StringVar(&opts.listenAddress, "listen-address", opts.listenAddress, "Address to serve metrics and readiness at, empty to disable.")
+	flags.BoolVar(&opts.once, "once", opts.once, "Reconcile heartbeats once and exit.")
+	_ = cmd.MarkFlagDirname("path")
+
+	return cmd
+}
+
+func runReconcile(ctx context.Context, f *cmdutil.Factory, opts *reconcileCmdOptions) error {
+	switch {
+	case opts.path == "":
+		return cmdutil.UsageErrorf("'--path' must be given")
+	case opts.interval <= 0:
+		return cmdutil.UsageErrorf("'--interval' must be positive, got %s", opts.interval)
+	}
+	if opts.owner != "" {
+		if err := validateOwner(opts.owner); err != nil {
+			return err
+		}
+	}
+	if info, err := os.Stat(opts.path); err != nil {
+		return &cmdutil.UsageError{Err: err}
+	} else if !info.IsDir() {
+		return cmdutil.UsageErrorf("'--path' must be a directory, %s is not", opts.path)
+	}
+
+	r := reconcile.New(f.Ctl(), reconcile.Config{Path: opts.path, Owner: opts.owner}, f.Logger, f.Now)
+
+	if opts.once {
+		if _, err := r.Reconcile(); err != nil {
+			return fmt.Errorf("failed to reconcile heartbeats: %w", err)
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if opts.listenAddress == "" {
+		r.Run(ctx, opts.interval)
+		return nil
+	}
+
+	// Reconciling stops if serving fails, and serving stops once reconciling
+	// was interrupted.
+	serverErr := make(chan error, 1)
+	go func() {
+		err := cmdutil.Serve(ctx, opts.listenAddress, r.Handler(readyMaxIntervals*opts.interval))
+		if err != nil {
+			stop()
+		}
+		serverErr <- err
+	}()
+	f.Logger.WithField("address", opts.listenAddress).Info("serving metrics and readiness")
+
+	r.Run(ctx, opts.interval)
+
+	if err := <-serverErr; err != nil {
+		return fmt.Errorf("failed to serve metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/relay"
+)
+
+// defaultRelaySecretEnv is the environment variable the shared secret of the
+// relay is read from by default.
+const defaultRelaySecretEnv = "HEARTBEATCTL_RELAY_SECRET"
+
+// relayCmdOptions holds values for options accepted by the relay command
+type relayCmdOptions struct {
+	selectorOptions *cmdutil.SelectorOptions
+	listen          string
+	secretEnv       string
+	coalesceWindow  time.Duration
+	refreshInterval time.Duration
+}
+
+var (
+	relayDocLong = heredoc.Doc(`
+		Forward pings from workloads that can't reach OpsGenie.
+
+		Pings are accepted as 'POST /ping/NAME' requests on '--listen' and
+		forwarded to OpsGenie with heartbeatctl's own API key, so workloads in
+		networks without internet egress don't need the key nor access to
+		OpsGenie. Only heartbeats selected by the selector flags or names given as
+		arguments can be pinged, and one must be given. The allow-list is loaded
+		on start and refreshed every '--refresh-interval', so new heartbeats can
+		be pinged after the next refresh.
+
+		If the environment variable named by '--secret-env' is set, requests must
+		carry its value in an 'Authorization: Bearer <secret>' header, otherwise
+		pings are accepted without authentication.
+
+		A heartbeat pinged once is not pinged again for '--coalesce-window', so
+		workloads pinging often or from many replicas cost a single ping. Pings
+		that fail, after the retries of the OpsGenie client, are answered with
+		'502 Bad Gateway' so the workload can retry them.
+
+		Responses are JSON objects with the heartbeat name and the result, one of
+		'forwarded', 'coalesced', 'denied', 'unauthorized' or 'failed'. Metrics of
+		relayed pings are served in the Prometheus format at '/metrics', liveness
+		at '/healthz' and readiness, once the allow-list was loaded, at '/readyz'.
+		Pings are logged at 'info' level unless '--verbosity' is given, and are
+		not recorded in the journal.
+	`)
+	relayDocExamples = heredoc.Doc(`
+		# relay pings of heartbeats of backup jobs
+		HEARTBEATCTL_RELAY_SECRET=s3cr3t heartbeatctl relay --listen=:8080 "backup-.*"
+
+		# relay pings of heartbeats owned by a team, without authentication
+		heartbeatctl relay --field-selector=ownerTeam/name=team-rocket
+
+		# ping a heartbeat through the relay
+		curl -X POST -H "Authorization: Bearer s3cr3t" http://heartbeatctl-relay:8080/ping/backup-etcd
+	`)
+)
+
+func NewRelayOptions() *relayCmdOptions {
+	return &relayCmdOptions{
+		selectorOptions: cmdutil.NewSelectorOptions(),
+		listen:          ":8080",
+		secretEnv:       defaultRelaySecretEnv,
+		coalesceWindow:  30 * time.Second,
+		refreshInterval: 5 * time.Minute,
+	}
+}
+
+func NewCmdRelay(f *cmdutil.Factory) *cobra.Command {
+	opts := NewRelayOptions()
+
+	cmd := &cobra.Command{
+		Use:     "relay [NAME..]",
+		Short:   "Forward pings from workloads that can't reach OpsGenie",
+		Long:    relayDocLong,
+		Example: relayDocExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("verbosity") {
+				f.Logger.SetLevel(logrus.InfoLevel)
+			}
+			return runRelay(cmd.Context(), f, opts)
+		},
+	}
+
+	opts.selectorOptions.
+		WithCapturingArgsUsingValidator().
+		WithCompletion(cmdutil.NewCompleter(f)).
+		WithSavedSelectors(f.SavedSelectorsPath()).
+		AddFlags(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.listen, "listen", opts.listen, "Address to accept pings and serve metrics at.")
+	flags.StringVar(&opts.secretEnv, "secret-env", opts.secretEnv, "Environment variable holding the shared secret pings must carry, if set.")
+	flags.DurationVar(&opts.coalesceWindow, "coalesce-window", opts.coalesceWindow, "How long after forwarding a ping further pings of the heartbeat are not forwarded.")
+	flags.DurationVar(&opts.refreshInterval, "refresh-interval", opts.refreshInterval, "How often to refresh the allow-list.")
+
+	return cmd
+}
+
+func runRelay(ctx context.Context, f *cmdutil.Factory, opts *relayCmdOptions) error {
+	switch {
+	case opts.listen == "":
+		return cmdutil.UsageErrorf("'--listen' must be given")
+	case opts.coalesceWindow < 0:
+		return cmdutil.UsageErrorf("'--coalesce-window' must not be negative, got %s", opts.coalesceWindow)
+	case opts.refreshInterval <= 0:
+		return cmdutil.UsageErrorf("'--refresh-interval' must be positive, got %s", opts.refreshInterval)
+	}
+	selector, err := opts.selectorOptions.ToConfig()
+	if err != nil {
+		return err
+	}
+
+	secret := ""
+	if opts.secretEnv != "" {
+		secret = os.Getenv(opts.secretEnv)
+	}
+
+	c, err := f.Client()
+	if err != nil {
+		return err
+	}
+	r, err := relay.New(c, f.Ctl(), relay.Config{
+		Allowed:        selector,
+		Secret:         secret,
+		CoalesceWindow: opts.coalesceWindow,
+	}, f.Logger, f.Now)
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		f.Logger.Warnf("%s is not set, accepting pings without authentication", opts.secretEnv)
+	}
+	if err := r.Refresh(); err != nil {
+		return fmt.Errorf("failed to load allow-list: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go r.Run(ctx, opts.refreshInterval)
+
+	f.Logger.WithField("address", opts.listen).Info("relaying pings")
+	if err := cmdutil.Serve(ctx, opts.listen, r.Handler()); err != nil {
+		return fmt.Errorf("failed to serve pings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// restoreCmdOptions holds values for options accepted by the restore command
+type restoreCmdOptions struct {
+	guardOptions   *cmdutil.GuardOptions
+	outputOptions  *cmdutil.OutputOptions
+	file           string
+	restoreEnabled bool
+	teamMapping    map[string]string
+	dryRun         bool
+}
+
+var (
+	restoreDocLong = heredoc.Doc(`
+		Create or update heartbeats from manifests.
+
+		Heartbeats described by manifests in the file given with '--filename', e.g.
+		one written by 'backup', are created if they don't exist and updated if
+		their configuration differs from the manifest. Other heartbeats are left
+		untouched. OpsGenie can't clear descriptions and alert tags, so those set
+		on existing heartbeats but missing in manifests are left in place, with a
+		warning.
+
+		Whether heartbeats are enabled is only restored with '--restore-enabled',
+		otherwise existing heartbeats keep their state and new ones are created
+		enabled. Owner teams can be renamed with '--map-team', e.g. when restoring
+		heartbeats in a different OpsGenie account.
+	`)
+	restoreDocExamples = heredoc.Doc(`
+		# recreate heartbeats from a backup, including whether they are enabled
+		heartbeatctl restore -f heartbeats.yaml --restore-enabled
+
+		# show what restoring heartbeats in another account with team 'ops' named
+		# 'operations' would do
+		heartbeatctl restore -f heartbeats.yaml --map-team=ops=operations --dry-run
+	`)
+)
+
+func NewRestoreOptions() *restoreCmdOptions {
+	return &restoreCmdOptions{
+		guardOptions:  cmdutil.NewGuardOptions(),
+		outputOptions: cmdutil.NewOutputOptions(),
+	}
+}
+
+func NewCmdRestore(f *cmdutil.Factory) *cobra.Command {
+	opts := NewRestoreOptions()
+
+	cmd := &cobra.Command{
+		Use:     "restore -f FILE",
+		Short:   "Create or update heartbeats from manifests",
+		Long:    restoreDocLong,
+		Example: restoreDocExamples,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRestore(f, opts)
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.file, "filename", "f", "", "File to read manifests from, '-' for standard input.")
+	flags.BoolVar(&opts.restoreEnabled, "restore-enabled", false, "Enable or disable heartbeats as recorded in manifests.")
+	flags.StringToStringVar(&opts.teamMapping, "map-team", nil, "Replace owner team names, given as OLD=NEW pairs.")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Only print heartbeats that would be created or updated, without changing them.")
+	_ = cmd.MarkFlagFilename("filename", "yaml", "yml")
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+
+	return cmd
+}
+
+func runRestore(f *cmdutil.Factory, opts *restoreCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	if opts.file == "" {
+		return cmdutil.UsageErrorf("'--filename' must be given")
+	}
+
+	manifests, err := manifest.ReadFile(opts.file, f.In)
+	if err != nil {
+		return &cmdutil.UsageError{Err: err}
+	}
+	for i := range manifests {
+		spec := &manifests[i].Spec
+		if !opts.restoreEnabled {
+			spec.Enabled = nil
+		}
+		if team, ok := opts.teamMapping[spec.OwnerTeam]; ok {
+			spec.OwnerTeam = team
+		}
+	}
+
+	if opts.dryRun {
+		plan, err := f.Ctl().PlanApply(manifests)
+		if err != nil {
+			return fmt.Errorf("failed to restore heartbeats: %w", err)
+		}
+		return printPlan(f, opts.outputOptions, plan)
+	}
+
+	c := f.Ctl(ctl.WithGuard(opts.guardOptions.Guard(f)), ctl.WithRecorder(f.Recorder()))
+
+	applied, err := c.Apply(manifests)
+	if printErr := printApplied(f, opts.outputOptions, applied, err); printErr != nil {
+		return printErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore heartbeats: %w", err)
+	}
+	return nil
+}
@@ -1,55 +1,125 @@
 package cmd
 
 import (
-	"log"
+	"fmt"
 	"os"
 
-	"github.com/opsgenie/opsgenie-go-sdk-v2/client"
-	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
-	"github.com/sirupsen/logrus"
+	"github.com/MakeNowJust/heredoc/v2"
 	"github.com/spf13/cobra"
-)
 
-const (
-	tokenEnvVar = "HEARTBEATCTL_TOKEN"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
 )
 
 var (
-	rootCmd = &cobra.Command{
+	rootDocLong = heredoc.Docf(`
+		heartbeatctl is a CLI tool to manage OpsGenie heartbeats.
+
+		Exit codes:
+		  %d  success
+		  %d  OpsGenie API request failed, or another unclassified error occurred
+		  %d  usage error, e.g. unknown flags, malformed or missing selectors
+		  %d  authentication error, API key is missing or was rejected
+		  %d  not found, the heartbeat doesn't exist or selectors matched nothing
+		  %d  partial failure, the operation failed for some of the heartbeats
+
+		With '--error-format=json' errors are written to stderr as a JSON object
+		with 'exitCode', 'reason', 'message' and, where applicable,
+		'failedHeartbeats' and 'succeededHeartbeats' attributes.
+	`,
+		cmdutil.ExitOK, cmdutil.ExitAPIError, cmdutil.ExitUsageError,
+		cmdutil.ExitAuthError, cmdutil.ExitNotFound, cmdutil.ExitPartialFailure,
+	)
+)
+
+// NewCmdRoot returns the root heartbeatctl command with all subcommands
+// configured to obtain their dependencies from given factory.
+func NewCmdRoot(f *cmdutil.Factory) *cobra.Command {
+	loggingOptions := cmdutil.NewLoggingOptions()
+
+	cmd := &cobra.Command{
 		Use:   "heartbeatctl",
 		Short: "heartbeatctl is a CLI tool to manage OpsGenie heartbeats",
+		Long:  rootDocLong,
+
+		SilenceErrors: true,
+		SilenceUsage:  true,
+
+		// Replaced by NewCmdCompletion, which writes to factory's output.
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			format, _ := cmd.Flags().GetString("error-format")
+			if format != cmdutil.ErrorFormatText && format != cmdutil.ErrorFormatJSON {
+				return cmdutil.UsageErrorf("error format must be one of '%s' or '%s'", cmdutil.ErrorFormatText, cmdutil.ErrorFormatJSON)
+			}
+			return loggingOptions.Configure(f.Logger)
+		},
 	}
 
-	noHeaders bool
+	cmd.SetIn(f.In)
+	cmd.SetOut(f.Out)
+	cmd.SetErr(f.ErrOut)
 
-	heartbeatClient *heartbeat.Client
-)
+	cmd.PersistentFlags().Bool("no-headers", false, "whether to disable headers")
+	cmd.PersistentFlags().String("error-format", cmdutil.ErrorFormatText, fmt.Sprintf("format to report errors in, one of '%s' or '%s'", cmdutil.ErrorFormatText, cmdutil.ErrorFormatJSON))
+	loggingOptions.AddFlags(cmd)
 
-func init() {
-	rootCmd.PersistentFlags().BoolVar(&noHeaders, "no-headers", false, "whether to disable headers")
-}
+	cmd.AddCommand(NewCmdList(f))
+	cmd.AddCommand(NewCmdGet(f))
+	cmd.AddCommand(NewCmdEnable(f))
+	cmd.AddCommand(NewCmdDisable(f))
+	cmd.AddCommand(NewCmdPing(f))
+	cmd.AddCommand(NewCmdCheck(f))
+	cmd.AddCommand(NewCmdBackup(f))
+	cmd.AddCommand(NewCmdRestore(f))
+	cmd.AddCommand(NewCmdLint(f))
+	cmd.AddCommand(NewCmdGenerate(f))
+	cmd.AddCommand(NewCmdPrune(f))
+	cmd.AddCommand(NewCmdSetSchedule(f))
+	cmd.AddCommand(NewCmdSchedule(f))
+	cmd.AddCommand(NewCmdReconcile(f))
+	cmd.AddCommand(NewCmdOperator(f))
+	cmd.AddCommand(NewCmdBridge(f))
+	cmd.AddCommand(NewCmdRelay(f))
+	cmd.AddCommand(NewCmdSync(f))
+	cmd.AddCommand(NewCmdHistory(f))
+	cmd.AddCommand(NewCmdUndo(f))
+	cmd.AddCommand(NewCmdSpool(f))
+	cmd.AddCommand(NewCmdSelectors(f))
+	cmd.AddCommand(NewCmdCompletion(f))
 
-func Execute() {
-	token := os.Getenv(tokenEnvVar)
-	if token == "" {
-		log.Fatalf("%s cannot be empty\n", tokenEnvVar)
-	}
+	return cmd
+}
 
-	logger := logrus.New()
-	logger.SetLevel(logrus.PanicLevel)
+// Run executes heartbeatctl with given arguments and returns the code the
+// process should exit with. Errors are reported to the factory's ErrOut in
+// the format requested with the `--error-format` flag.
+func Run(f *cmdutil.Factory, args []string) int {
+	f.CommandLine = append([]string{"heartbeatctl"}, args...)
+	cmd := NewCmdRoot(f)
+	cmd.SetArgs(args)
 
-	config := &client.Config{
-		ApiKey: os.Getenv("HEARTBEATCTL_TOKEN"),
-		Logger: logger,
+	// Errors returned before the persistent pre-run hook is reached come from
+	// parsing the command line, e.g. unknown commands or bad arguments.
+	parsed := false
+	preRun := cmd.PersistentPreRunE
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		parsed = true
+		return preRun(cmd, args)
 	}
 
-	c, err := heartbeat.NewClient(config)
-	if err != nil {
-		log.Fatalf("%v\n", err)
+	executed, err := cmd.ExecuteC()
+	if err == nil {
+		return cmdutil.ExitOK
 	}
-	heartbeatClient = c
-
-	if err := rootCmd.Execute(); err != nil {
-		log.Fatalf("%v\n", err)
+	if !parsed {
+		err = &cmdutil.UsageError{Err: err}
 	}
+
+	format, _ := executed.Flags().GetString("error-format")
+	return cmdutil.ReportError(f.ErrOut, format, err)
+}
+
+func Execute() {
+	os.Exit(Run(cmdutil.NewFactory(), os.Args[1:]))
 }
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/schedule"
+)
+
+// defaultGrace is the time added to the longest gap between runs of a
+// schedule unless '--grace' is given.
+const defaultGrace = 10 * time.Minute
+
+// setScheduleCmdOptions holds values for options accepted by the
+// set-schedule command
+type setScheduleCmdOptions struct {
+	selectorOptions *cmdutil.SelectorOptions
+	guardOptions    *cmdutil.GuardOptions
+	outputOptions   *cmdutil.OutputOptions
+	grace           time.Duration
+	dryRun          bool
+}
+
+// scheduleExplainCmdOptions holds values for options accepted by the
+// schedule explain command
+type scheduleExplainCmdOptions struct {
+	outputOptions *cmdutil.OutputOptions
+	grace         time.Duration
+}
+
+var (
+	scheduleDocLong = heredoc.Doc(`
+		Derive heartbeat intervals from cron schedules.
+
+		The interval of a heartbeat pinged by a job must cover the longest gap
+		between consecutive runs of the job, which for schedules like '0 3 * * 1-5'
+		is the 72 hours over the weekend rather than the 24 hours between other
+		runs. Runs within a year are looked at, so gaps over weekends, months and
+		years are found, and '--grace' is added to allow jobs to take a while. The
+		result is rounded up to whole minutes and expressed in the largest unit
+		that divides it evenly.
+
+		Schedules are in the standard five field format used by cron and Kubernetes
+		CronJobs, or descriptors like '@daily', and run in UTC unless prefixed with
+		'CRON_TZ=<zone> ', e.g. 'CRON_TZ=Europe/Berlin 0 3 * * *'.
+	`)
+	scheduleExplainDocExamples = heredoc.Doc(`
+		# show the interval of a heartbeat of a job running on workdays
+		heartbeatctl schedule explain "0 3 * * 1-5" --grace=30m
+	`)
+	setScheduleDocLong = heredoc.Doc(`
+		Set intervals of heartbeats to match a cron schedule.
+
+		The interval is derived from the schedule like with 'schedule explain', and
+		selected heartbeats are updated to expect pings at it, with the rest of
+		their configuration left as is. Heartbeats are selected with label and
+		field selectors, queries and name expressions given after the schedule,
+		like in 'enable', and at least one must be given.
+	`)
+	setScheduleDocExamples = heredoc.Doc(`
+		# set intervals of heartbeats of backup jobs running on workdays
+		heartbeatctl set-schedule "0 3 * * 1-5" --grace=30m -l job=backup
+
+		# show which heartbeats would be changed
+		heartbeatctl set-schedule "@hourly" "reports-.*" --dry-run
+	`)
+)
+
+func NewScheduleExplainOptions() *scheduleExplainCmdOptions {
+	return &scheduleExplainCmdOptions{
+		outputOptions: cmdutil.NewOutputOptions(),
+		grace:         defaultGrace,
+	}
+}
+
+func NewSetScheduleOptions() *setScheduleCmdOptions {
+	return &setScheduleCmdOptions{
+		selectorOptions: cmdutil.NewSelectorOptions(),
+		guardOptions:    cmdutil.NewGuardOptions(),
+		outputOptions:   cmdutil.NewOutputOptions(),
+		grace:           defaultGrace,
+	}
+}
+
+func NewCmdSchedule(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Derive heartbeat intervals from cron schedules",
+		Long:  scheduleDocLong,
+		Args:  cobra.NoArgs,
+	}
+
+	cmd.AddCommand(NewCmdScheduleExplain(f))
+
+	return cmd
+}
+
+func NewCmdScheduleExplain(f *cmdutil.Factory) *cobra.Command {
+	opts := NewScheduleExplainOptions()
+
+	cmd := &cobra.Command{
+		Use:     "explain SCHEDULE",
+		Short:   "Show the heartbeat interval derived from a cron schedule",
+		Long:    scheduleDocLong,
+		Example: scheduleExplainDocExamples,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runScheduleExplain(f, opts, args[0])
+		},
+	}
+
+	opts.outputOptions.AddFlags(cmd)
+	cmd.Flags().DurationVar(&opts.grace, "grace", opts.grace, "Time added to the longest gap between runs.")
+
+	return cmd
+}
+
+// scheduleResult is the JSON representation of an interval derived from a
+// schedule.
+type scheduleResult struct {
+	Schedule     string `json:"schedule"`
+	MaxGap       string `json:"maxGap"`
+	Grace        string `json:"grace"`
+	Interval     int    `json:"interval"`
+	IntervalUnit string `json:"intervalUnit"`
+}
+
+func runScheduleExplain(f *cmdutil.Factory, opts *scheduleExplainCmdOptions, expr string) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	interval, err := deriveInterval(f, expr, opts.grace)
+	if err != nil {
+		return err
+	}
+
+	if opts.outputOptions.JSON() {
+		return cmdutil.PrintJSON(f.Out, scheduleResult{
+			Schedule:     interval.Schedule,
+			MaxGap:       interval.MaxGap.String(),
+			Grace:        interval.Grace.String(),
+			Interval:     interval.Interval,
+			IntervalUnit: interval.IntervalUnit,
+		})
+	}
+
+	fmt.Fprintf(f.Out, "schedule:     %s\n", interval.Schedule)
+	fmt.Fprintf(f.Out, "longest gap:  %s\n", interval.MaxGap)
+	fmt.Fprintf(f.Out, "grace:        %s\n", interval.Grace)
+	fmt.Fprintf(f.Out, "interval:     %d %s\n", interval.Interval, interval.IntervalUnit)
+	return nil
+}
+
+func NewCmdSetSchedule(f *cmdutil.Factory) *cobra.Command {
+	opts := NewSetScheduleOptions()
+
+	cmd := &cobra.Command{
+		Use:     "set-schedule SCHEDULE [NAME..]",
+		Short:   "Set intervals of heartbeats to match a cron schedule",
+		Long:    setScheduleDocLong,
+		Example: setScheduleDocExamples,
+		Args:    cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.selectorOptions.NameExpressions(args[1:]...)
+			return runSetSchedule(f, opts, args[0])
+		},
+	}
+
+	completer := cmdutil.NewCompleter(f)
+	opts.selectorOptions.
+		WithCompletion(completer).
+		WithSavedSelectors(f.SavedSelectorsPath()).
+		AddFlags(cmd)
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+	cmd.Flags().DurationVar(&opts.grace, "grace", opts.grace, "Time added to the longest gap between runs.")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only print heartbeats that would be updated, without changing them.")
+	cmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completer.HeartbeatNames(cmd, args, toComplete)
+	}
+
+	return cmd
+}
+
+func runSetSchedule(f *cmdutil.Factory, opts *setScheduleCmdOptions, expr string) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	interval, err := deriveInterval(f, expr, opts.grace)
+	if err != nil {
+		return err
+	}
+	selector, err := opts.selectorOptions.ToConfig()
+	if err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		plan, err := ctl.SetInterval(f.Ctl(), selector, interval.Interval, interval.IntervalUnit, true)
+		if err != nil {
+			return fmt.Errorf("failed to set schedule: %w", err)
+		}
+		return printPlan(f, opts.outputOptions, plan)
+	}
+
+	c := f.Ctl(ctl.WithGuard(opts.guardOptions.Guard(f)), ctl.WithRecorder(f.Recorder()))
+
+	updated, err := ctl.SetInterval(c, selector, interval.Interval, interval.IntervalUnit, false)
+	if printErr := printApplied(f, opts.outputOptions, updated, err); printErr != nil {
+		return printErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set schedule: %w", err)
+	}
+	return nil
+}
+
+// deriveInterval returns the heartbeat interval of given schedule, or a
+// UsageError if the schedule or grace time is invalid.
+func deriveInterval(f *cmdutil.Factory, expr string, grace time.Duration) (*schedule.Interval, error) {
+	interval, err := schedule.Derive(expr, grace, f.Now())
+	if err != nil {
+		return nil, &cmdutil.UsageError{Err: err}
+	}
+	return interval, nil
+}
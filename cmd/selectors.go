@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/ryanuber/columnize"
+	"github.com/spf13/cobra"
+	"go.yaml.in/yaml/v3"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// selectorsAddCmdOptions holds values for options accepted by the selectors add
+// command
+type selectorsAddCmdOptions struct {
+	selector  cmdutil.SavedSelector
+	overwrite bool
+}
+
+var (
+	selectorsDocLong = heredoc.Doc(`
+		Manage saved selectors.
+
+		Saved selectors are named combinations of a label selector, field selector
+		and query, stored in 'selectors.yaml' in heartbeatctl's configuration
+		directory, e.g. '~/.config/heartbeatctl/selectors.yaml'. Commands selecting
+		heartbeats refer to them with '--saved-selector NAME' or with '@NAME' given
+		as a positional argument, and heartbeats must match both the saved selector
+		and any other selectors given.
+	`)
+	selectorsDocExamples = heredoc.Doc(`
+		# save a selector named 'gorilla'
+		heartbeatctl selectors add gorilla -l "managed-by=cluster-operator,installation=gorilla" --field-selector=ownerTeam/name=team-rocket
+
+		# disable heartbeats selected by the saved selector
+		heartbeatctl disable @gorilla
+
+		# ping enabled heartbeats selected by the saved selector
+		heartbeatctl ping --saved-selector=gorilla -l enabled
+	`)
+)
+
+func NewSelectorsAddOptions() *selectorsAddCmdOptions {
+	return &selectorsAddCmdOptions{}
+}
+
+func NewCmdSelectors(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "selectors",
+		Short:   "Manage saved selectors",
+		Long:    selectorsDocLong,
+		Example: selectorsDocExamples,
+		Args:    cobra.NoArgs,
+	}
+
+	cmd.AddCommand(NewCmdSelectorsList(f))
+	cmd.AddCommand(NewCmdSelectorsShow(f))
+	cmd.AddCommand(NewCmdSelectorsAdd(f))
+	cmd.AddCommand(NewCmdSelectorsRemove(f))
+
+	return cmd
+}
+
+func NewCmdSelectorsList(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List saved selectors",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSelectorsList(f, cmd)
+		},
+	}
+
+	return cmd
+}
+
+func runSelectorsList(f *cmdutil.Factory, cmd *cobra.Command) error {
+	saved, err := cmdutil.LoadSavedSelectors(f.SavedSelectorsPath())
+	if err != nil {
+		return err
+	}
+
+	// Selectors may contain the default '|' delimiter, e.g. in regular
+	// expressions, so columns are delimited by a control character instead.
+	config := columnize.DefaultConfig()
+	config.Delim = "\x1f"
+	config.Empty = "<none>"
+
+	output := []string{}
+
+	if noHeaders, _ := cmd.Flags().GetBool("no-headers"); !noHeaders {
+		output = append(output, "NAME\x1fSELECTOR\x1fFIELD SELECTOR\x1fQUERY")
+	}
+
+	for _, name := range saved.Names() {
+		s := saved.Selectors[name]
+		output = append(output, strings.Join([]string{name, s.LabelSelector, s.FieldSelector, s.Query}, "\x1f"))
+	}
+
+	fmt.Fprintln(f.Out, columnize.Format(output, config))
+	return nil
+}
+
+func NewCmdSelectorsShow(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "show NAME",
+		Short:             "Show a saved selector",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSavedSelectorName(f),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSelectorsShow(f, args[0])
+		},
+	}
+
+	return cmd
+}
+
+func runSelectorsShow(f *cmdutil.Factory, name string) error {
+	saved, err := cmdutil.LoadSavedSelectors(f.SavedSelectorsPath())
+	if err != nil {
+		return err
+	}
+
+	selector, err := saved.Get(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(selector)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Out.Write(data)
+	return err
+}
+
+func NewCmdSelectorsAdd(f *cmdutil.Factory) *cobra.Command {
+	opts := NewSelectorsAddOptions()
+
+	cmd := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Save a selector",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSelectorsAdd(f, opts, args[0])
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opts.selector.LabelSelector, "selector", "l", "", "Selector (label query) to save.")
+	flags.StringVar(&opts.selector.FieldSelector, "field-selector", "", "Selector (field query) to save.")
+	flags.StringVar(&opts.selector.Query, "query", "", "Query expression to save.")
+	flags.BoolVar(&opts.overwrite, "overwrite", false, "Replace the saved selector if it already exists.")
+
+	completer := cmdutil.NewCompleter(f)
+	_ = cmd.RegisterFlagCompletionFunc("selector", completer.LabelSelector)
+	_ = cmd.RegisterFlagCompletionFunc("field-selector", completer.FieldSelector)
+
+	return cmd
+}
+
+func runSelectorsAdd(f *cmdutil.Factory, opts *selectorsAddCmdOptions, name string) error {
+	if opts.selector == (cmdutil.SavedSelector{}) {
+		return cmdutil.UsageErrorf("at least one of '--selector', '--field-selector' or '--query' must be given")
+	}
+	if err := ctl.ValidateSelector(opts.selector.ToConfig()); err != nil {
+		return err
+	}
+
+	path := f.SavedSelectorsPath()
+	saved, err := cmdutil.LoadSavedSelectors(path)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := saved.Selectors[name]; exists && !opts.overwrite {
+		return cmdutil.UsageErrorf("saved selector %q already exists, pass '--overwrite' to replace it", name)
+	}
+
+	saved.Selectors[name] = opts.selector
+	if err := saved.Save(path); err != nil {
+		return fmt.Errorf("failed to save selector: %w", err)
+	}
+
+	fmt.Fprintf(f.Out, "saved selector \"%s\" added\n", name)
+	return nil
+}
+
+func NewCmdSelectorsRemove(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "remove NAME",
+		Short:             "Remove a saved selector",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeSavedSelectorName(f),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSelectorsRemove(f, args[0])
+		},
+	}
+
+	return cmd
+}
+
+func runSelectorsRemove(f *cmdutil.Factory, name string) error {
+	path := f.SavedSelectorsPath()
+	saved, err := cmdutil.LoadSavedSelectors(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := saved.Get(name); err != nil {
+		return err
+	}
+
+	delete(saved.Selectors, name)
+	if err := saved.Save(path); err != nil {
+		return fmt.Errorf("failed to remove selector: %w", err)
+	}
+
+	fmt.Fprintf(f.Out, "saved selector \"%s\" removed\n", name)
+	return nil
+}
+
+// completeSavedSelectorName returns a function completing the name of a saved
+// selector as the first argument.
+func completeSavedSelectorName(f *cmdutil.Factory) cobra.CompletionFunc {
+	return func(_ *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		saved, err := cmdutil.LoadSavedSelectors(f.SavedSelectorsPath())
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return saved.Names(), cobra.ShellCompDirectiveNoFileComp
+	}
+}
@@ -0,0 +1,269 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/ryanuber/columnize"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/spool"
+)
+
+// spoolListCmdOptions holds values for options accepted by the spool list
+// command
+type spoolListCmdOptions struct {
+	outputOptions *cmdutil.OutputOptions
+}
+
+var (
+	spoolDocLong = heredoc.Doc(`
+		Manage pings spooled because OpsGenie couldn't be reached.
+
+		Pings that 'ping' fails to deliver because OpsGenie can't be reached, or
+		is temporarily unavailable, are kept in a spool in heartbeatctl's state
+		directory, e.g. '~/.local/state/heartbeatctl/spool.jsonl', only the
+		newest one for every heartbeat. The next 'ping' that reaches OpsGenie
+		replays them, backing off after every failed attempt, up to 10 minutes.
+		Spooled pings older than the interval of their heartbeat are discarded,
+		as are pings of heartbeats that don't exist anymore.
+	`)
+	spoolDocExamples = heredoc.Doc(`
+		# list spooled pings
+		heartbeatctl spool list
+
+		# replay all spooled pings now
+		heartbeatctl spool flush
+
+		# drop the spooled ping of a heartbeat
+		heartbeatctl spool purge backup-etcd
+	`)
+)
+
+func NewSpoolListOptions() *spoolListCmdOptions {
+	return &spoolListCmdOptions{
+		outputOptions: cmdutil.NewOutputOptions(),
+	}
+}
+
+func NewCmdSpool(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "spool",
+		Short:   "Manage pings spooled because OpsGenie couldn't be reached",
+		Long:    spoolDocLong,
+		Example: spoolDocExamples,
+		Args:    cobra.NoArgs,
+	}
+
+	cmd.AddCommand(NewCmdSpoolList(f))
+	cmd.AddCommand(NewCmdSpoolFlush(f))
+	cmd.AddCommand(NewCmdSpoolPurge(f))
+
+	return cmd
+}
+
+func NewCmdSpoolList(f *cmdutil.Factory) *cobra.Command {
+	opts := NewSpoolListOptions()
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List spooled pings",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSpoolList(f, cmd, opts)
+		},
+	}
+
+	opts.outputOptions.AddFlags(cmd)
+
+	return cmd
+}
+
+func runSpoolList(f *cmdutil.Factory, cmd *cobra.Command, opts *spoolListCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	s, err := openSpool(f)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.Entries()
+	if err != nil {
+		return err
+	}
+
+	if opts.outputOptions.JSON() {
+		if entries == nil {
+			return cmdutil.PrintJSON(f.Out, []struct{}{})
+		}
+		return cmdutil.PrintJSON(f.Out, entries)
+	}
+
+	// Errors may contain the default '|' delimiter, so columns are delimited
+	// by a control character instead.
+	config := columnize.DefaultConfig()
+	config.Delim = "\x1f"
+	config.Empty = "<none>"
+
+	output := []string{}
+
+	if noHeaders, _ := cmd.Flags().GetBool("no-headers"); !noHeaders {
+		output = append(output, "HEARTBEAT\x1fPINGED\x1fEXPIRES\x1fATTEMPTS\x1fNEXT ATTEMPT\x1fERROR")
+	}
+
+	for _, e := range entries {
+		output = append(output, strings.Join([]string{
+			e.Heartbeat,
+			formatSpoolTime(e.Time),
+			formatSpoolTime(e.Expires),
+			strconv.Itoa(e.Attempts),
+			formatSpoolTime(e.NextAttempt),
+			e.Error,
+		}, "\x1f"))
+	}
+
+	fmt.Fprintln(f.Out, columnize.Format(output, config))
+	return nil
+}
+
+func NewCmdSpoolFlush(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "flush [NAME..]",
+		Short:             "Replay spooled pings now",
+		Long:              "Replay spooled pings of given heartbeats, or all of them, regardless of their backoff.",
+		ValidArgsFunction: completeSpooledHeartbeats(f),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSpoolFlush(f, args)
+		},
+	}
+
+	return cmd
+}
+
+func runSpoolFlush(f *cmdutil.Factory, names []string) error {
+	s, err := openSpool(f)
+	if err != nil {
+		return err
+	}
+	c, err := f.Client()
+	if err != nil {
+		return err
+	}
+
+	results, err := s.Replay(context.Background(), c, f.Now(), true, names...)
+	if len(results) == 0 && err == nil {
+		fmt.Fprintln(f.Out, "no spooled pings")
+		return nil
+	}
+	printSpoolResults(f.Out, results)
+	if err != nil {
+		return fmt.Errorf("failed to update spool: %w", err)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Outcome == spool.OutcomeFailed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to deliver %d spooled ping(s)", failed)
+	}
+	return nil
+}
+
+func NewCmdSpoolPurge(f *cmdutil.Factory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "purge [NAME..]",
+		Short:             "Drop spooled pings",
+		Long:              "Drop spooled pings of given heartbeats, or all of them, without replaying them.",
+		ValidArgsFunction: completeSpooledHeartbeats(f),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSpoolPurge(f, args)
+		},
+	}
+
+	return cmd
+}
+
+func runSpoolPurge(f *cmdutil.Factory, names []string) error {
+	s, err := openSpool(f)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.Remove(names...)
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Fprintln(f.Out, "no spooled pings")
+		return nil
+	}
+	for _, e := range removed {
+		fmt.Fprintf(f.Out, "spooled ping of heartbeat \"%s\" purged\n", e.Heartbeat)
+	}
+	return nil
+}
+
+// printSpoolResults prints results of replaying spooled pings, except for
+// pings that were not due to be replayed.
+func printSpoolResults(w io.Writer, results []spool.Result) {
+	for _, r := range results {
+		switch {
+		case r.Outcome == spool.OutcomePending:
+		case r.Err != nil:
+			fmt.Fprintf(w, "spooled ping of heartbeat \"%s\": %s: %v\n", r.Heartbeat, r.Outcome, r.Err)
+		default:
+			fmt.Fprintf(w, "spooled ping of heartbeat \"%s\": %s\n", r.Heartbeat, r.Outcome)
+		}
+	}
+}
+
+// formatSpoolTime formats given time of a spooled ping, or returns an empty
+// string if it's zero.
+func formatSpoolTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// completeSpooledHeartbeats returns a function completing names of
+// heartbeats with spooled pings.
+func completeSpooledHeartbeats(f *cmdutil.Factory) cobra.CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		s := f.Spool()
+		if s == nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		entries, err := s.Entries()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		var names []string
+		for _, e := range entries {
+			if strings.HasPrefix(e.Heartbeat, toComplete) {
+				names = append(names, e.Heartbeat)
+			}
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+func openSpool(f *cmdutil.Factory) (*spool.Spool, error) {
+	s := f.Spool()
+	if s == nil {
+		return nil, errors.New("ping spool is not available, failed to determine the state directory")
+	}
+	return s, nil
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// syncCmdOptions holds values for options accepted by the sync command
+type syncCmdOptions struct {
+	selectorOptions *cmdutil.SelectorOptions
+	guardOptions    *cmdutil.GuardOptions
+	outputOptions   *cmdutil.OutputOptions
+	fromTokenEnv    string
+	toTokenEnv      string
+	fromAPIURL      string
+	toAPIURL        string
+	fromPrefix      string
+	toPrefix        string
+	teamMapping     map[string]string
+	prune           bool
+	dryRun          bool
+}
+
+// syncReport is the JSON representation of changes made by sync.
+type syncReport struct {
+	Created   int                 `json:"created"`
+	Updated   int                 `json:"updated"`
+	Deleted   int                 `json:"deleted"`
+	Unchanged int                 `json:"unchanged"`
+	DryRun    bool                `json:"dryRun"`
+	Actions   []ctl.PlannedAction `json:"actions"`
+}
+
+var (
+	syncDocLong = heredoc.Doc(`
+		Copy heartbeats from one OpsGenie account to another.
+
+		Heartbeats selected in the source account are created in the target account
+		if they don't exist there, and updated if their configuration differs,
+		including whether they are enabled. Selectors and name expressions work the
+		same way as for 'enable', and at least one of them or '--from-prefix' must
+		be given.
+
+		API keys of both accounts are read from environment variables named with
+		'--from-token-env' and '--to-token-env', and API URLs can be given for
+		accounts in other regions, e.g. 'api.eu.opsgenie.com'.
+
+		With '--from-prefix' only heartbeats with names starting with it are copied,
+		and the prefix is replaced with '--to-prefix' in the target account. Owner
+		teams can be renamed with '--map-team'.
+
+		With '--prune' heartbeats in the target account that have no counterpart in
+		the source account are deleted, but only those in scope of the sync: with
+		names starting with '--to-prefix' and matching the selectors, with name
+		expressions matched against names the heartbeats would have in the source
+		account. Pruning requires '--to-prefix' or a selector, so it never puts every
+		heartbeat of the target account in scope.
+
+		Changes are made in another account, so they are not recorded in the journal
+		and can't be undone with 'undo'.
+	`)
+	syncDocExamples = heredoc.Doc(`
+		# show what copying template heartbeats from the reference account to a
+		# customer account, renaming and pruning them, would do
+		heartbeatctl sync --from-prefix=template- --to-prefix=acme- --to-token-env=ACME_TOKEN --prune --dry-run
+
+		# copy heartbeats labelled 'tier=gold' to an account in the EU region
+		heartbeatctl sync -l tier=gold --to-token-env=EU_TOKEN --to-api-url=api.eu.opsgenie.com
+	`)
+)
+
+func NewSyncOptions() *syncCmdOptions {
+	return &syncCmdOptions{
+		selectorOptions: cmdutil.NewSelectorOptions(),
+		guardOptions:    cmdutil.NewGuardOptions(),
+		outputOptions:   cmdutil.NewOutputOptions(),
+		fromTokenEnv:    client.TokenEnvVar,
+	}
+}
+
+func NewCmdSync(f *cmdutil.Factory) *cobra.Command {
+	opts := NewSyncOptions()
+
+	cmd := &cobra.Command{
+		Use:     "sync [NAME..]",
+		Short:   "Copy heartbeats between OpsGenie accounts",
+		Long:    syncDocLong,
+		Example: syncDocExamples,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runSync(f, opts)
+		},
+	}
+
+	opts.selectorOptions.
+		WithCapturingArgsUsingValidator().
+		WithCompletion(cmdutil.NewCompleter(f)).
+		WithSavedSelectors(f.SavedSelectorsPath()).
+		AddFlags(cmd)
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.fromTokenEnv, "from-token-env", opts.fromTokenEnv, "Environment variable holding the API key of the source account.")
+	flags.StringVar(&opts.toTokenEnv, "to-token-env", "", "Environment variable holding the API key of the target account.")
+	flags.StringVar(&opts.fromAPIURL, "from-api-url", "", "OpsGenie API URL of the source account, the default one if not given.")
+	flags.StringVar(&opts.toAPIURL, "to-api-url", "", "OpsGenie API URL of the target account, the default one if not given.")
+	flags.StringVar(&opts.fromPrefix, "from-prefix", "", "Only copy heartbeats with names starting with this prefix, replacing it with '--to-prefix'.")
+	flags.StringVar(&opts.toPrefix, "to-prefix", "", "Prefix of names of heartbeats in the target account.")
+	flags.StringToStringVar(&opts.teamMapping, "map-team", nil, "Replace owner team names, given as OLD=NEW pairs.")
+	flags.BoolVar(&opts.prune, "prune", false, "Delete heartbeats in scope of the sync that have no counterpart in the source account.")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "Only print changes that would be made, without making them.")
+
+	return cmd
+}
+
+func runSync(f *cmdutil.Factory, opts *syncCmdOptions) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	if opts.toTokenEnv == "" {
+		return cmdutil.UsageErrorf("'--to-token-env' must be given")
+	}
+	selector, err := opts.selectorOptions.ToConfig()
+	if err != nil {
+		return err
+	}
+	if err := ctl.ValidateSelector(selector); err != nil {
+		return err
+	}
+
+	sourceRepo, err := f.AccountClientFunc(opts.fromTokenEnv, opts.fromAPIURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to source account: %w", err)
+	}
+	targetRepo, err := f.AccountClientFunc(opts.toTokenEnv, opts.toAPIURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to target account: %w", err)
+	}
+
+	cfg := ctl.SyncConfig{
+		Selector:   selector,
+		FromPrefix: opts.fromPrefix,
+		ToPrefix:   opts.toPrefix,
+		Teams:      opts.teamMapping,
+		Prune:      opts.prune,
+	}
+	source := ctl.NewCtl(sourceRepo)
+	target := ctl.NewCtl(targetRepo, ctl.WithGuard(opts.guardOptions.Guard(f)))
+
+	actions, err := ctl.Sync(source, target, cfg, opts.dryRun)
+	if len(actions) > 0 || err == nil {
+		if printErr := printSyncReport(f, opts, actions); printErr != nil {
+			return printErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sync heartbeats: %w", err)
+	}
+	return nil
+}
+
+// printSyncReport prints given actions applied by sync, followed by a
+// summary.
+func printSyncReport(f *cmdutil.Factory, opts *syncCmdOptions, actions []ctl.PlannedAction) error {
+	report := syncReport{DryRun: opts.dryRun, Actions: actions}
+	if report.Actions == nil {
+		report.Actions = []ctl.PlannedAction{}
+	}
+	for _, a := range actions {
+		switch {
+		case a.Unchanged:
+			report.Unchanged++
+		case a.Action == ctl.ActionCreate:
+			report.Created++
+		case a.Action == ctl.ActionUpdate:
+			report.Updated++
+		case a.Action == ctl.ActionDelete:
+			report.Deleted++
+		}
+	}
+
+	if opts.outputOptions.JSON() {
+		return cmdutil.PrintJSON(f.Out, report)
+	}
+
+	suffix := ""
+	if opts.dryRun {
+		suffix = " (dry run)"
+	}
+	for _, a := range actions {
+		result := actionResults[a.Action]
+		if a.Unchanged {
+			result = "unchanged"
+		}
+		fmt.Fprintf(f.Out, "heartbeat \"%s\" %s%s\n", a.Heartbeat, result, suffix)
+	}
+	fmt.Fprintf(f.Out, "%d created, %d updated, %d deleted, %d unchanged%s\n",
+		report.Created, report.Updated, report.Deleted, report.Unchanged, suffix)
+	return nil
+}
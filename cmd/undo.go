@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/MakeNowJust/heredoc/v2"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/journal"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// undoCmdOptions holds values for options accepted by the undo command
+type undoCmdOptions struct {
+	guardOptions  *cmdutil.GuardOptions
+	outputOptions *cmdutil.OutputOptions
+	dryRun        bool
+}
+
+var (
+	undoDocLong = heredoc.Doc(`
+		Undo a change made to heartbeats.
+
+		Restores heartbeats touched by the change with given ID, as listed by
+		'history', to the state they were in before it, including whether they were
+		enabled and all their other fields. Descriptions and alert tags added since
+		are left in place as OpsGenie can't clear them, and reported as such.
+		Without an ID the most recent change
+		that wasn't undone yet is undone, so running 'undo' repeatedly reverts
+		changes one by one, from the newest.
+
+		Undoing is itself recorded in the journal and can be undone in turn. Pings
+		can't be undone, and heartbeats created by 'restore' are not deleted.
+	`)
+	undoDocExamples = heredoc.Doc(`
+		# undo the most recent change
+		heartbeatctl undo
+
+		# show what undoing the change with ID 3 would do
+		heartbeatctl undo 3 --dry-run
+	`)
+)
+
+func NewUndoOptions() *undoCmdOptions {
+	return &undoCmdOptions{
+		guardOptions:  cmdutil.NewGuardOptions(),
+		outputOptions: cmdutil.NewOutputOptions(),
+	}
+}
+
+func NewCmdUndo(f *cmdutil.Factory) *cobra.Command {
+	opts := NewUndoOptions()
+
+	cmd := &cobra.Command{
+		Use:     "undo [ID]",
+		Short:   "Undo a change made to heartbeats",
+		Long:    undoDocLong,
+		Example: undoDocExamples,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runUndo(f, opts, args)
+		},
+	}
+
+	opts.guardOptions.AddFlags(cmd)
+	opts.outputOptions.AddFlags(cmd)
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only print heartbeats that would be restored, without changing them.")
+
+	return cmd
+}
+
+func runUndo(f *cmdutil.Factory, opts *undoCmdOptions, args []string) error {
+	if err := opts.outputOptions.Validate(); err != nil {
+		return err
+	}
+	j, err := openJournal(f)
+	if err != nil {
+		return err
+	}
+
+	entry, err := entryToUndo(j, args)
+	if err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		plan, err := f.Ctl().PlanRestore(entry.Heartbeats)
+		if err != nil {
+			return fmt.Errorf("failed to undo change %d: %w", entry.ID, err)
+		}
+		return printPlan(f, opts.outputOptions, plan)
+	}
+
+	// Fields that can't be restored are found from the current state of
+	// heartbeats passed to the recorder.
+	previous := make(map[string]heartbeat.Heartbeat, len(entry.Heartbeats))
+	for _, h := range entry.Heartbeats {
+		previous[h.Name] = h
+	}
+	uncleared := map[string][]string{}
+	record := f.RecorderFor(entry.ID)
+	recorder := func(action string, current []heartbeat.Heartbeat) error {
+		for _, h := range current {
+			uncleared[h.Name] = manifest.FromHeartbeat(previous[h.Name]).Uncleared(h)
+		}
+		if record == nil {
+			return nil
+		}
+		return record(action, current)
+	}
+
+	c := f.Ctl(ctl.WithGuard(opts.guardOptions.Guard(f)), ctl.WithRecorder(recorder))
+
+	heartbeats, err := c.Restore(entry.Heartbeats)
+	switch {
+	case !opts.outputOptions.JSON():
+		for _, hbi := range heartbeats {
+			fmt.Fprintf(f.Out, "heartbeat \"%s\" restored\n", hbi.Name)
+		}
+	case len(heartbeats) > 0 || err == nil:
+		// Results are printed on failure only if some heartbeats succeeded.
+		if printErr := printHeartbeatInfosJSON(f, heartbeats); printErr != nil {
+			return printErr
+		}
+	}
+	for _, hbi := range heartbeats {
+		warnUncleared(f, hbi.Name, uncleared[hbi.Name])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to undo change %d: %w", entry.ID, err)
+	}
+	return nil
+}
+
+// entryToUndo returns the journal entry with the ID given in args, or the
+// most recent entry that can be undone if no ID was given.
+func entryToUndo(j *journal.Journal, args []string) (journal.Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return journal.Entry{}, err
+	}
+	undoneBy := journal.UndoneBy(entries)
+
+	if len(args) == 0 {
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			if _, undone := undoneBy[e.ID]; !undone && e.Undoes == 0 && e.Action != ctl.ActionPing {
+				return e, nil
+			}
+		}
+		return journal.Entry{}, cmdutil.UsageErrorf("there are no changes to undo")
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id < 1 {
+		return journal.Entry{}, cmdutil.UsageErrorf("invalid change ID %q, must be a positive number", args[0])
+	}
+	entry, err := j.Get(id)
+	if errors.Is(err, journal.ErrEntryNotFound) {
+		return entry, cmdutil.UsageErrorf("change %d not found in history", id)
+	} else if err != nil {
+		return entry, err
+	}
+
+	if entry.Action == ctl.ActionPing {
+		return entry, cmdutil.UsageErrorf("change %d pinged heartbeats, which can't be undone", id)
+	}
+	if by, undone := undoneBy[id]; undone {
+		return entry, cmdutil.UsageErrorf("change %d was already undone by change %d", id, by)
+	}
+	return entry, nil
+}
+
+// openJournal returns the journal changes are recorded in, or an error if
+// journaling is disabled.
+func openJournal(f *cmdutil.Factory) (*journal.Journal, error) {
+	j := f.Journal()
+	if j == nil {
+		return nil, errors.New("change journal is not available, failed to determine the state directory")
+	}
+	return j, nil
+}
@@ -0,0 +1,4 @@
+// v1alpha1 package defines version v1alpha1 of the heartbeatctl.giantswarm.io
+// API group, with the Heartbeat custom resource describing an OpsGenie
+// heartbeat managed by the operator.
+package v1alpha1
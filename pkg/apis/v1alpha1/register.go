@@ -0,0 +1,21 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of resources in this package.
+	GroupVersion = schema.GroupVersion{Group: "heartbeatctl.giantswarm.io", Version: "v1alpha1"}
+
+	// SchemeBuilder registers types in this package with a scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds types in this package to a scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func init() {
+	SchemeBuilder.Register(&Heartbeat{}, &HeartbeatList{})
+}
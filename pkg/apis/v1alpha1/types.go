@@ -0,0 +1,86 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// ConditionReady is the type of the condition reporting whether the
+// heartbeat in OpsGenie matches the resource.
+const ConditionReady = "Ready"
+
+// Reasons of the Ready condition.
+const (
+	// ReasonSynced means the heartbeat matches the resource.
+	ReasonSynced = "Synced"
+	// ReasonInvalidSpec means the resource describes a heartbeat OpsGenie
+	// wouldn't accept, and it's not synced until the spec is fixed.
+	ReasonInvalidSpec = "InvalidSpec"
+	// ReasonSyncFailed means a request to OpsGenie failed, and syncing is
+	// retried.
+	ReasonSyncFailed = "SyncFailed"
+)
+
+// Heartbeat describes the desired configuration of an OpsGenie heartbeat,
+// kept in sync by the operator and deleted along with the resource.
+type Heartbeat struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HeartbeatSpec   `json:"spec"`
+	Status HeartbeatStatus `json:"status,omitempty"`
+}
+
+// HeartbeatSpec holds the configuration of a heartbeat, with the same fields
+// as heartbeat manifests.
+type HeartbeatSpec struct {
+	// Name is the name of the heartbeat in OpsGenie, the name of the resource
+	// if not set.
+	Name string `json:"name,omitempty"`
+
+	manifest.Spec `json:",inline"`
+}
+
+// HeartbeatStatus reports the state of the heartbeat in OpsGenie.
+type HeartbeatStatus struct {
+	// Name is the name of the heartbeat in OpsGenie last synced.
+	Name string `json:"name,omitempty"`
+	// Enabled and Expired are the state of the heartbeat when it was last
+	// synced.
+	Enabled bool `json:"enabled"`
+	Expired bool `json:"expired"`
+	// LastSyncTime is when the heartbeat was last synced successfully, updated
+	// at most once per resync period unless the status changes.
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// ObservedGeneration is the generation of the resource last synced.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions include the Ready condition.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// HeartbeatName returns the name of the heartbeat in OpsGenie.
+func (h *Heartbeat) HeartbeatName() string {
+	if h.Spec.Name != "" {
+		return h.Spec.Name
+	}
+	return h.Name
+}
+
+// Manifest returns a manifest describing the heartbeat.
+func (h *Heartbeat) Manifest() manifest.Heartbeat {
+	return manifest.Heartbeat{
+		APIVersion: manifest.APIVersion,
+		Kind:       manifest.KindHeartbeat,
+		Metadata:   manifest.Metadata{Name: h.HeartbeatName()},
+		Spec:       h.Spec.Spec,
+	}
+}
+
+// HeartbeatList is a list of Heartbeat resources.
+type HeartbeatList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Heartbeat `json:"items"`
+}
@@ -0,0 +1,175 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+)
+
+// Results of handling received alerts, counted in metrics.
+const (
+	// ResultForwarded means the heartbeat of the alert was pinged.
+	ResultForwarded = "forwarded"
+	// ResultDeduplicated means the heartbeat was pinged recently enough for
+	// another alert or an earlier notification of the same one.
+	ResultDeduplicated = "deduplicated"
+	// ResultIgnored means the alert is resolved or has another name than
+	// the bridge forwards.
+	ResultIgnored = "ignored"
+	// ResultFailed means the heartbeat name couldn't be rendered or the ping
+	// failed.
+	ResultFailed = "failed"
+)
+
+// results lists results in the order they are reported in metrics.
+var results = []string{ResultForwarded, ResultDeduplicated, ResultIgnored, ResultFailed}
+
+// Config configures which alerts a Bridge forwards and how.
+type Config struct {
+	// NameTemplate is a Go template executed with an Alert to render the name
+	// of the heartbeat to ping, e.g. '{{ .Labels.installation }}-watchdog'.
+	// Referring to labels the alert doesn't have is an error.
+	NameTemplate string
+
+	// AlertName is the value of the 'alertname' label of alerts to forward,
+	// all alerts are forwarded if empty.
+	AlertName string
+
+	// DedupWindow is how long after pinging a heartbeat further alerts for it
+	// are not forwarded, e.g. notifications of the same alert sent by every
+	// replica of Alertmanager.
+	DedupWindow time.Duration
+
+	// Secret is the shared secret webhook requests must carry as a bearer
+	// token, not checked if empty.
+	Secret string
+}
+
+// Bridge pings heartbeats for firing alerts.
+type Bridge struct {
+	client client.Port
+	config Config
+	name   *template.Template
+	logger logrus.FieldLogger
+	now    func() time.Time
+
+	mu           sync.Mutex
+	pinged       map[string]time.Time
+	received     int
+	results      map[string]int
+	unauthorized int
+}
+
+// New returns a Bridge pinging heartbeats through given client, or an error
+// if the name template is malformed.
+func New(c client.Port, config Config, logger logrus.FieldLogger, now func() time.Time) (*Bridge, error) {
+	name, err := template.New("name").Option("missingkey=error").Parse(config.NameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid heartbeat name template: %w", err)
+	}
+	return &Bridge{
+		client:  c,
+		config:  config,
+		name:    name,
+		logger:  logger,
+		now:     now,
+		pinged:  map[string]time.Time{},
+		results: map[string]int{},
+	}, nil
+}
+
+// Forward pings heartbeats of firing alerts in given message, each at most
+// once, and returns how many pings failed. Alerts whose heartbeat names can't
+// be rendered are logged and counted as failed, but not returned, as trying
+// again wouldn't help.
+func (b *Bridge) Forward(ctx context.Context, msg *Message) int {
+	b.count(len(msg.Alerts), "")
+
+	failed := 0
+	seen := map[string]bool{}
+	for _, alert := range msg.Alerts {
+		if alert.Status != StatusFiring || (b.config.AlertName != "" && alert.Labels["alertname"] != b.config.AlertName) {
+			b.count(0, ResultIgnored)
+			continue
+		}
+
+		name, err := b.render(alert)
+		if err != nil {
+			b.logger.WithError(err).WithField("alert", alert.Labels).Error("failed to render heartbeat name")
+			b.count(0, ResultFailed)
+			continue
+		}
+		logger := b.logger.WithField("heartbeat", name)
+
+		if seen[name] || !b.reserve(name) {
+			logger.Debug("skipped pinging heartbeat pinged recently")
+			b.count(0, ResultDeduplicated)
+			continue
+		}
+		seen[name] = true
+
+		if _, err := b.client.Ping(ctx, name); err != nil {
+			b.mu.Lock()
+			delete(b.pinged, name)
+			b.mu.Unlock()
+			logger.WithError(err).Error("failed to ping heartbeat")
+			b.count(0, ResultFailed)
+			failed++
+			continue
+		}
+		logger.Info("pinged heartbeat")
+		b.count(0, ResultForwarded)
+	}
+	return failed
+}
+
+// render returns the name of the heartbeat of given alert.
+func (b *Bridge) render(alert Alert) (string, error) {
+	var buf bytes.Buffer
+	if err := b.name.Execute(&buf, alert); err != nil {
+		return "", err
+	}
+	name := strings.TrimSpace(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("heartbeat name rendered empty")
+	}
+	return name, nil
+}
+
+// reserve returns true and marks the heartbeat with given name as pinged,
+// so concurrent alerts for it are deduplicated while it's pinged, unless it
+// was pinged within the deduplication window. Heartbeats pinged before it are
+// forgotten.
+func (b *Bridge) reserve(name string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	for n, t := range b.pinged {
+		if now.Sub(t) >= b.config.DedupWindow {
+			delete(b.pinged, n)
+		}
+	}
+	if _, recent := b.pinged[name]; recent {
+		return false
+	}
+	b.pinged[name] = now
+	return true
+}
+
+func (b *Bridge) count(received int, result string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.received += received
+	if result != "" {
+		b.results[result]++
+	}
+}
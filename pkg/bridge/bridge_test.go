@@ -0,0 +1,250 @@
+package bridge_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/giantswarm/heartbeatctl/pkg/bridge"
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+)
+
+// slowClient counts pings and delays them, so concurrent requests overlap.
+type slowClient struct {
+	*fake.Client
+	pings atomic.Int32
+}
+
+func (c *slowClient) Ping(ctx context.Context, name string) (*heartbeat.PingResult, error) {
+	c.pings.Add(1)
+	time.Sleep(10 * time.Millisecond)
+	return c.Client.Ping(ctx, name)
+}
+
+// watchdog returns a firing Watchdog alert of given installation.
+func watchdog(installation string) bridge.Alert {
+	return bridge.Alert{
+		Status: bridge.StatusFiring,
+		Labels: map[string]string{"alertname": "Watchdog", "installation": installation},
+	}
+}
+
+var _ = Describe("Bridge", func() {
+	var (
+		repo    *fake.Client
+		logs    *logtest.Hook
+		now     time.Time
+		handler http.Handler
+	)
+
+	post := func(alerts ...bridge.Alert) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(bridge.Message{Version: "4", Status: bridge.StatusFiring, Alerts: alerts})
+		Expect(err).NotTo(HaveOccurred())
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(payload))))
+		return rec
+	}
+
+	// pinged returns names of heartbeats pinged since they were expired.
+	pinged := func() []string {
+		names := []string{}
+		for _, h := range repo.Heartbeats() {
+			if !h.Expired {
+				names = append(names, h.Name)
+			}
+		}
+		return names
+	}
+
+	metrics := func() string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		body, err := io.ReadAll(rec.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "gorilla-watchdog", Expired: true},
+			heartbeat.Heartbeat{Name: "gaia-watchdog", Expired: true},
+		)
+
+		logger, hook := logtest.NewNullLogger()
+		logs = hook
+		b, err := bridge.New(repo, bridge.Config{
+			NameTemplate: "{{ .Labels.installation }}-watchdog",
+			AlertName:    "Watchdog",
+			DedupWindow:  time.Minute,
+		}, logger, func() time.Time { return now })
+		Expect(err).NotTo(HaveOccurred())
+		handler = b.Handler()
+	})
+
+	It("pings heartbeats named after labels of firing alerts", func() {
+		Expect(post(watchdog("gorilla"), watchdog("gaia")).Code).To(Equal(http.StatusOK))
+		Expect(pinged()).To(ConsistOf("gorilla-watchdog", "gaia-watchdog"))
+		Expect(logs.LastEntry().Message).To(Equal("pinged heartbeat"))
+		Expect(logs.LastEntry().Data).To(HaveKeyWithValue("heartbeat", "gaia-watchdog"))
+	})
+
+	It("ignores resolved alerts and alerts with other names", func() {
+		resolved := watchdog("gorilla")
+		resolved.Status = bridge.StatusResolved
+		other := watchdog("gaia")
+		other.Labels["alertname"] = "TargetDown"
+
+		Expect(post(resolved, other).Code).To(Equal(http.StatusOK))
+		Expect(pinged()).To(BeEmpty())
+		Expect(metrics()).To(ContainSubstring(`heartbeatctl_bridge_alerts_total{result="ignored"} 2`))
+	})
+
+	It("pings heartbeats once within the deduplication window", func() {
+		Expect(post(watchdog("gorilla"), watchdog("gorilla")).Code).To(Equal(http.StatusOK))
+		now = now.Add(30 * time.Second)
+		Expect(post(watchdog("gorilla")).Code).To(Equal(http.StatusOK))
+		Expect(metrics()).To(ContainSubstring("heartbeatctl_bridge_pings_forwarded_total 1\n"))
+
+		now = now.Add(30 * time.Second)
+		Expect(post(watchdog("gorilla")).Code).To(Equal(http.StatusOK))
+
+		Expect(metrics()).To(Equal(strings.Join([]string{
+			"# HELP heartbeatctl_bridge_alerts_received_total Alerts received in webhook payloads.",
+			"# TYPE heartbeatctl_bridge_alerts_received_total counter",
+			"heartbeatctl_bridge_alerts_received_total 4",
+			"# HELP heartbeatctl_bridge_alerts_total Received alerts by the result of forwarding them as pings.",
+			"# TYPE heartbeatctl_bridge_alerts_total counter",
+			`heartbeatctl_bridge_alerts_total{result="forwarded"} 2`,
+			`heartbeatctl_bridge_alerts_total{result="deduplicated"} 2`,
+			`heartbeatctl_bridge_alerts_total{result="ignored"} 0`,
+			`heartbeatctl_bridge_alerts_total{result="failed"} 0`,
+			"# HELP heartbeatctl_bridge_pings_forwarded_total Heartbeats pinged for received alerts.",
+			"# TYPE heartbeatctl_bridge_pings_forwarded_total counter",
+			"heartbeatctl_bridge_pings_forwarded_total 2",
+			"# HELP heartbeatctl_bridge_webhooks_unauthorized_total Webhook requests rejected for not carrying the shared secret.",
+			"# TYPE heartbeatctl_bridge_webhooks_unauthorized_total counter",
+			"heartbeatctl_bridge_webhooks_unauthorized_total 0",
+			"",
+		}, "\n")))
+	})
+
+	It("pings heartbeats once for concurrent requests", func() {
+		logger, _ := logtest.NewNullLogger()
+		slow := &slowClient{Client: repo}
+		b, err := bridge.New(slow, bridge.Config{
+			NameTemplate: "{{ .Labels.installation }}-watchdog",
+			DedupWindow:  time.Minute,
+		}, logger, func() time.Time { return now })
+		Expect(err).NotTo(HaveOccurred())
+		handler = b.Handler()
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(post(watchdog("gorilla")).Code).To(Equal(http.StatusOK))
+			}()
+		}
+		wg.Wait()
+
+		Expect(slow.pings.Load()).To(BeEquivalentTo(1))
+		Expect(metrics()).To(ContainSubstring(`heartbeatctl_bridge_alerts_total{result="deduplicated"} 9`))
+	})
+
+	It("fails so Alertmanager retries pings that failed", func() {
+		repo.Fail("Ping", "gaia-watchdog", errors.New("boom"))
+
+		rec := post(watchdog("gorilla"), watchdog("gaia"))
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).To(Equal("failed to ping 1 heartbeat(s)\n"))
+		Expect(pinged()).To(Equal([]string{"gorilla-watchdog"}))
+
+		repo.Fail("Ping", "gaia-watchdog", nil)
+		Expect(post(watchdog("gorilla"), watchdog("gaia")).Code).To(Equal(http.StatusOK))
+		Expect(pinged()).To(ConsistOf("gorilla-watchdog", "gaia-watchdog"))
+	})
+
+	It("logs alerts without the labels the template refers to", func() {
+		alert := watchdog("gorilla")
+		delete(alert.Labels, "installation")
+
+		Expect(post(alert).Code).To(Equal(http.StatusOK))
+		Expect(logs.LastEntry().Message).To(Equal("failed to render heartbeat name"))
+		Expect(metrics()).To(ContainSubstring(`heartbeatctl_bridge_alerts_total{result="failed"} 1`))
+	})
+
+	It("rejects malformed payloads and other methods", func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{")))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhook", nil))
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/nope", nil))
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+	})
+
+	Context("with a shared secret", func() {
+		BeforeEach(func() {
+			logger, _ := logtest.NewNullLogger()
+			b, err := bridge.New(repo, bridge.Config{
+				NameTemplate: "{{ .Labels.installation }}-watchdog",
+				Secret:       "s3cr3t",
+			}, logger, func() time.Time { return now })
+			Expect(err).NotTo(HaveOccurred())
+			handler = b.Handler()
+		})
+
+		postWithToken := func(token string) *httptest.ResponseRecorder {
+			payload, err := json.Marshal(bridge.Message{Version: "4", Status: bridge.StatusFiring, Alerts: []bridge.Alert{watchdog("gorilla")}})
+			Expect(err).NotTo(HaveOccurred())
+			req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(string(payload)))
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			return rec
+		}
+
+		It("pings heartbeats for webhooks carrying the secret", func() {
+			Expect(postWithToken("s3cr3t").Code).To(Equal(http.StatusOK))
+			Expect(pinged()).To(Equal([]string{"gorilla-watchdog"}))
+		})
+
+		It("rejects webhooks without the secret", func() {
+			for _, token := range []string{"", "wrong"} {
+				rec := postWithToken(token)
+				Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+				Expect(rec.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+			}
+			Expect(pinged()).To(BeEmpty())
+			Expect(metrics()).To(ContainSubstring("heartbeatctl_bridge_webhooks_unauthorized_total 2\n"))
+		})
+	})
+
+	It("rejects malformed templates", func() {
+		_, err := bridge.New(repo, bridge.Config{NameTemplate: "{{ .Labels.installation"}, nil, time.Now)
+		Expect(err).To(MatchError(HavePrefix("invalid heartbeat name template:")))
+	})
+})
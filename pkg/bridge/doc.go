@@ -0,0 +1,5 @@
+// bridge package pings heartbeats for alerts received from Alertmanager
+// webhooks, so always-firing alerts like Prometheus' Watchdog keep
+// heartbeats of their installations from expiring without routing
+// configuration per installation.
+package bridge
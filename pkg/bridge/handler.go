@@ -0,0 +1,96 @@
+package bridge
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/heartbeatctl/pkg/metrics"
+)
+
+// maxPayloadSize limits the size of webhook payloads read.
+const maxPayloadSize = 4 << 20
+
+// Handler returns an HTTP handler accepting Alertmanager webhook payloads at
+// '/' and '/webhook', and serving metrics in the Prometheus text format at
+// '/metrics' and liveness at '/healthz'. Webhook requests fail with a server
+// error if any ping failed, so Alertmanager retries them, with heartbeats
+// pinged already deduplicated. If a shared secret is configured, webhook
+// requests must carry it in an 'Authorization: Bearer <secret>' header.
+func (b *Bridge) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", b.serveWebhook)
+	mux.HandleFunc("/webhook", b.serveWebhook)
+	mux.HandleFunc("/metrics", b.serveMetrics)
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+func (b *Bridge) serveWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/webhook" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "only POST requests are accepted", http.StatusMethodNotAllowed)
+		return
+	}
+	if !b.authorized(r) {
+		b.mu.Lock()
+		b.unauthorized++
+		b.mu.Unlock()
+		b.logger.Warn("rejected webhook without the shared secret")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "missing or wrong bearer token", http.StatusUnauthorized)
+		return
+	}
+
+	var msg Message
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPayloadSize)).Decode(&msg); err != nil {
+		b.logger.WithError(err).Warn("received malformed webhook payload")
+		http.Error(w, fmt.Sprintf("malformed webhook payload: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	if failed := b.Forward(r.Context(), &msg); failed > 0 {
+		http.Error(w, fmt.Sprintf("failed to ping %d heartbeat(s)", failed), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// authorized returns true if no shared secret is configured or given request
+// carries it.
+func (b *Bridge) authorized(r *http.Request) bool {
+	if b.config.Secret == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(b.config.Secret)) == 1
+}
+
+func (b *Bridge) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	m := metrics.NewWriter(w)
+
+	m.Metric("heartbeatctl_bridge_alerts_received_total", metrics.Counter, "Alerts received in webhook payloads.")
+	m.Sample("heartbeatctl_bridge_alerts_received_total", float64(b.received))
+
+	m.Metric("heartbeatctl_bridge_alerts_total", metrics.Counter, "Received alerts by the result of forwarding them as pings.")
+	for _, result := range results {
+		m.Sample("heartbeatctl_bridge_alerts_total", float64(b.results[result]), "result", result)
+	}
+
+	m.Metric("heartbeatctl_bridge_pings_forwarded_total", metrics.Counter, "Heartbeats pinged for received alerts.")
+	m.Sample("heartbeatctl_bridge_pings_forwarded_total", float64(b.results[ResultForwarded]))
+
+	m.Metric("heartbeatctl_bridge_webhooks_unauthorized_total", metrics.Counter, "Webhook requests rejected for not carrying the shared secret.")
+	m.Sample("heartbeatctl_bridge_webhooks_unauthorized_total", float64(b.unauthorized))
+}
@@ -0,0 +1,146 @@
+package check
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+)
+
+// States of a check, which are also the exit codes of plugins.
+const (
+	OK       = 0
+	Warning  = 1
+	Critical = 2
+	Unknown  = 3
+)
+
+// service is the name of the checked service the status line starts with.
+const service = "HEARTBEATS"
+
+// maxNames limits how many heartbeat names are listed in the status line.
+const maxNames = 10
+
+var stateNames = map[int]string{
+	OK:       "OK",
+	Warning:  "WARNING",
+	Critical: "CRITICAL",
+	Unknown:  "UNKNOWN",
+}
+
+// Thresholds configure which heartbeats make a check fail. Like thresholds
+// of plugins, a state is reached when there are more expired heartbeats than
+// its threshold.
+type Thresholds struct {
+	// WarnExpired is how many enabled heartbeats may be expired before the
+	// state is Warning.
+	WarnExpired int
+	// CritExpired is how many enabled heartbeats may be expired before the
+	// state is Critical.
+	CritExpired int
+	// CritDisabled makes the state Critical if any heartbeat is disabled.
+	CritDisabled bool
+}
+
+// Validate returns an error if thresholds are negative or the warning
+// threshold is above the critical one.
+func (t Thresholds) Validate() error {
+	switch {
+	case t.WarnExpired < 0:
+		return fmt.Errorf("warning threshold must not be negative, got %d", t.WarnExpired)
+	case t.CritExpired < 0:
+		return fmt.Errorf("critical threshold must not be negative, got %d", t.CritExpired)
+	case t.WarnExpired > t.CritExpired:
+		return fmt.Errorf("warning threshold %d must not be above critical threshold %d", t.WarnExpired, t.CritExpired)
+	}
+	return nil
+}
+
+// Result is the outcome of checking heartbeats.
+type Result struct {
+	// State is one of OK, Warning or Critical.
+	State int
+	// Thresholds are the thresholds the heartbeats were checked against.
+	Thresholds Thresholds
+	// Total is how many heartbeats were checked.
+	Total int
+	// Enabled is how many of the heartbeats are enabled.
+	Enabled int
+	// Expired holds sorted names of enabled heartbeats that are expired.
+	Expired []string
+	// Disabled holds sorted names of disabled heartbeats.
+	Disabled []string
+}
+
+// Evaluate checks given heartbeats against given thresholds. Disabled
+// heartbeats are never counted as expired.
+func Evaluate(heartbeats []heartbeat.Heartbeat, t Thresholds) *Result {
+	r := &Result{State: OK, Thresholds: t, Total: len(heartbeats)}
+	for _, h := range heartbeats {
+		switch {
+		case !h.Enabled:
+			r.Disabled = append(r.Disabled, h.Name)
+		case h.Expired:
+			r.Enabled++
+			r.Expired = append(r.Expired, h.Name)
+		default:
+			r.Enabled++
+		}
+	}
+	sort.Strings(r.Expired)
+	sort.Strings(r.Disabled)
+
+	switch {
+	case len(r.Expired) > t.CritExpired, t.CritDisabled && len(r.Disabled) > 0:
+		r.State = Critical
+	case len(r.Expired) > t.WarnExpired:
+		r.State = Warning
+	}
+	return r
+}
+
+// String returns the status line of the result, with a summary followed by
+// performance data of checked, enabled, expired and disabled heartbeats.
+func (r *Result) String() string {
+	var problems []string
+	if len(r.Expired) > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d enabled heartbeats expired: %s", len(r.Expired), r.Enabled, names(r.Expired)))
+	}
+	if r.Thresholds.CritDisabled && len(r.Disabled) > 0 {
+		problems = append(problems, fmt.Sprintf("%d disabled: %s", len(r.Disabled), names(r.Disabled)))
+	}
+
+	summary := strings.Join(problems, "; ")
+	if summary == "" {
+		summary = fmt.Sprintf("%d of %d heartbeats enabled, none expired", r.Enabled, r.Total)
+	}
+
+	disabledCrit := ""
+	if r.Thresholds.CritDisabled {
+		disabledCrit = "0"
+	}
+	perfData := []string{
+		fmt.Sprintf("heartbeats=%d;;;0", r.Total),
+		fmt.Sprintf("enabled=%d;;;0;%d", r.Enabled, r.Total),
+		fmt.Sprintf("expired=%d;%d;%d;0;%d", len(r.Expired), r.Thresholds.WarnExpired, r.Thresholds.CritExpired, r.Enabled),
+		fmt.Sprintf("disabled=%d;;%s;0;%d", len(r.Disabled), disabledCrit, r.Total),
+	}
+
+	return fmt.Sprintf("%s %s - %s | %s", service, stateNames[r.State], summary, strings.Join(perfData, " "))
+}
+
+// UnknownStatus returns the status line reporting that heartbeats couldn't
+// be checked because of given error.
+func UnknownStatus(err error) string {
+	return fmt.Sprintf("%s %s - %s", service, stateNames[Unknown], strings.Join(strings.Fields(err.Error()), " "))
+}
+
+// names returns given names joined by commas, with names above maxNames
+// only counted.
+func names(list []string) string {
+	if len(list) <= maxNames {
+		return strings.Join(list, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(list[:maxNames], ", "), len(list)-maxNames)
+}
@@ -0,0 +1,74 @@
+package check_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/check"
+)
+
+var _ = Describe("Evaluate", func() {
+	var heartbeats []heartbeat.Heartbeat
+
+	BeforeEach(func() {
+		heartbeats = []heartbeat.Heartbeat{
+			{Name: "foo", Enabled: true},
+			{Name: "bar", Enabled: true, Expired: true},
+			{Name: "baz", Enabled: true, Expired: true},
+			{Name: "qux", Enabled: false, Expired: true},
+		}
+	})
+
+	DescribeTable("reports the state and status line",
+		func(thresholds check.Thresholds, state int, status string) {
+			r := check.Evaluate(heartbeats, thresholds)
+			Expect(r.State).To(Equal(state))
+			Expect(r.String()).To(Equal(status))
+		},
+		Entry("critical by default", check.Thresholds{}, check.Critical,
+			"HEARTBEATS CRITICAL - 2 of 3 enabled heartbeats expired: bar, baz | heartbeats=4;;;0 enabled=3;;;0;4 expired=2;0;0;0;3 disabled=1;;;0;4"),
+		Entry("warning", check.Thresholds{WarnExpired: 1, CritExpired: 2}, check.Warning,
+			"HEARTBEATS WARNING - 2 of 3 enabled heartbeats expired: bar, baz | heartbeats=4;;;0 enabled=3;;;0;4 expired=2;1;2;0;3 disabled=1;;;0;4"),
+		Entry("ok within thresholds", check.Thresholds{WarnExpired: 2, CritExpired: 2}, check.OK,
+			"HEARTBEATS OK - 2 of 3 enabled heartbeats expired: bar, baz | heartbeats=4;;;0 enabled=3;;;0;4 expired=2;2;2;0;3 disabled=1;;;0;4"),
+		Entry("critical with disabled heartbeats", check.Thresholds{WarnExpired: 2, CritExpired: 2, CritDisabled: true}, check.Critical,
+			"HEARTBEATS CRITICAL - 2 of 3 enabled heartbeats expired: bar, baz; 1 disabled: qux | heartbeats=4;;;0 enabled=3;;;0;4 expired=2;2;2;0;3 disabled=1;;0;0;4"),
+	)
+
+	It("is ok without expired heartbeats", func() {
+		r := check.Evaluate(heartbeats[:1], check.Thresholds{CritDisabled: true})
+		Expect(r.State).To(Equal(check.OK))
+		Expect(r.String()).To(Equal("HEARTBEATS OK - 1 of 1 heartbeats enabled, none expired | heartbeats=1;;;0 enabled=1;;;0;1 expired=0;0;0;0;1 disabled=0;;0;0;1"))
+	})
+
+	It("lists a limited number of heartbeats", func() {
+		heartbeats = nil
+		for i := 0; i < 12; i++ {
+			heartbeats = append(heartbeats, heartbeat.Heartbeat{Name: fmt.Sprintf("hb-%02d", i), Enabled: true, Expired: true})
+		}
+		Expect(check.Evaluate(heartbeats, check.Thresholds{}).String()).To(HavePrefix(
+			"HEARTBEATS CRITICAL - 12 of 12 enabled heartbeats expired: hb-00, hb-01, hb-02, hb-03, hb-04, hb-05, hb-06, hb-07, hb-08, hb-09 and 2 more |",
+		))
+	})
+})
+
+var _ = Describe("Thresholds", func() {
+	DescribeTable("are validated",
+		func(thresholds check.Thresholds, message string) {
+			Expect(thresholds.Validate()).To(MatchError(message))
+		},
+		Entry("negative warning", check.Thresholds{WarnExpired: -1}, "warning threshold must not be negative, got -1"),
+		Entry("negative critical", check.Thresholds{CritExpired: -1}, "critical threshold must not be negative, got -1"),
+		Entry("warning above critical", check.Thresholds{WarnExpired: 2, CritExpired: 1}, "warning threshold 2 must not be above critical threshold 1"),
+	)
+})
+
+var _ = Describe("UnknownStatus", func() {
+	It("reports errors on a single line", func() {
+		Expect(check.UnknownStatus(errors.New("failed:\n  boom"))).To(Equal("HEARTBEATS UNKNOWN - failed: boom"))
+	})
+})
@@ -0,0 +1,4 @@
+// check package evaluates heartbeats against thresholds of expired and
+// disabled heartbeats, reporting the result in the format of Nagios and
+// Icinga plugins.
+package check
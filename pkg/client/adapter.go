@@ -1,7 +1,8 @@
 package client
 
 import (
-	"fmt"
+	"errors"
+	"net/http"
 	"os"
 
 	"github.com/opsgenie/opsgenie-go-sdk-v2/client"
@@ -10,18 +11,57 @@ import (
 )
 
 const (
-	tokenEnvVar = "HEARTBEATCTL_TOKEN"
+	// TokenEnvVar is the environment variable the API key is read from by
+	// default.
+	TokenEnvVar = "HEARTBEATCTL_TOKEN"
 )
 
+// ErrMissingAPIKey is returned by New when no API key was configured nor
+// given via the environment.
+var ErrMissingAPIKey = errors.New("API key missing, set " + TokenEnvVar + " env var")
+
+// MissingAPIKeyError is returned by NewFromEnv when the environment variable
+// the API key should be read from is empty. It matches ErrMissingAPIKey.
+type MissingAPIKeyError struct {
+	EnvVar string
+}
+
+func (e *MissingAPIKeyError) Error() string {
+	return "API key missing, set " + e.EnvVar + " env var"
+}
+
+func (e *MissingAPIKeyError) Is(target error) bool {
+	return target == ErrMissingAPIKey
+}
+
+// NewFromEnv returns a Port like New does, with the API key read from given
+// environment variable.
+func NewFromEnv(envVar string, cfg *client.Config) (Port, error) {
+	token := os.Getenv(envVar)
+	if token == "" {
+		return nil, &MissingAPIKeyError{EnvVar: envVar}
+	}
+
+	c := client.Config{}
+	if cfg != nil {
+		c = *cfg
+	}
+	c.ApiKey = token
+	return New(&c)
+}
+
+// New returns a Port backed by the OpsGenie SDK client configured with given
+// config. Missing API key is taken from the environment, and HTTP requests are
+// traced using the config's Logger, see TracingTransport.
 func New(cfg *client.Config) (Port, error) {
 	if cfg == nil {
 		cfg = &client.Config{}
 	}
 
 	if cfg.ApiKey == "" {
-		token := os.Getenv(tokenEnvVar)
+		token := os.Getenv(TokenEnvVar)
 		if token == "" {
-			return nil, fmt.Errorf("API key missing, set %s env var", tokenEnvVar)
+			return nil, ErrMissingAPIKey
 		}
 		cfg.ApiKey = token
 	}
@@ -32,5 +72,14 @@ func New(cfg *client.Config) (Port, error) {
 		cfg.Logger = logger
 	}
 
+	// Trace requests with the same logger the SDK uses, so a single log level
+	// controls both.
+	httpClient := &http.Client{}
+	if cfg.HttpClient != nil {
+		*httpClient = *cfg.HttpClient
+	}
+	httpClient.Transport = NewTracingTransport(httpClient.Transport, cfg.Logger)
+	cfg.HttpClient = httpClient
+
 	return heartbeat.NewClient(cfg)
 }
@@ -0,0 +1,27 @@
+package client_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+)
+
+var _ = Describe("NewFromEnv", func() {
+	It("reads the API key from given environment variable", func() {
+		GinkgoT().Setenv("OTHER_TOKEN", "t0k3n")
+
+		repo, err := client.NewFromEnv("OTHER_TOKEN", &ogclient.Config{OpsGenieAPIURL: ogclient.API_URL_EU})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo).NotTo(BeNil())
+	})
+
+	It("fails when the environment variable is empty", func() {
+		GinkgoT().Setenv("OTHER_TOKEN", "")
+
+		_, err := client.NewFromEnv("OTHER_TOKEN", nil)
+		Expect(err).To(MatchError(client.ErrMissingAPIKey))
+		Expect(err).To(MatchError("API key missing, set OTHER_TOKEN env var"))
+	})
+})
@@ -0,0 +1,263 @@
+package fake
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+)
+
+const (
+	// PingMessage is the message returned by a successful Ping, same as the
+	// one returned by OpsGenie.
+	PingMessage = "PONG - Heartbeat received"
+
+	// DefaultAlertPriority is the alert priority OpsGenie gives heartbeats
+	// created without one.
+	DefaultAlertPriority = "P3"
+)
+
+// DefaultAlertMessage returns the alert message OpsGenie gives the heartbeat
+// with given name if created without one.
+func DefaultAlertMessage(name string) string {
+	return name + " is expired"
+}
+
+// Client is an in-memory OpsGenie Heartbeat client. Mutating methods change
+// the stored heartbeats so subsequent calls observe their effects, following
+// semantics of the real API: Add applies OpsGenie's defaults, Update leaves
+// fields the SDK omits when empty unchanged, and Ping succeeds for unknown
+// heartbeats.
+type Client struct {
+	mu         sync.Mutex
+	heartbeats map[string]heartbeat.Heartbeat
+	failures   map[string]error
+}
+
+// NewClient returns a Client pre-populated with given heartbeats.
+func NewClient(heartbeats ...heartbeat.Heartbeat) *Client {
+	c := &Client{
+		heartbeats: make(map[string]heartbeat.Heartbeat),
+		failures:   make(map[string]error),
+	}
+	for _, h := range heartbeats {
+		c.heartbeats[h.Name] = h
+	}
+	return c
+}
+
+// Fail makes calls of given method (e.g. "Enable") fail with given error. If
+// name is empty, all calls of the method fail, otherwise only calls targeting
+// the named heartbeat.
+func (c *Client) Fail(method, name string, err error) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures[failureKey(method, name)] = err
+	return c
+}
+
+// Heartbeats returns a copy of currently stored heartbeats sorted by name.
+func (c *Client) Heartbeats() []heartbeat.Heartbeat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := make([]heartbeat.Heartbeat, 0, len(c.heartbeats))
+	for _, h := range c.heartbeats {
+		ret = append(ret, h)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// Ping marks the heartbeat as not expired. Like OpsGenie, it succeeds even if
+// the heartbeat doesn't exist.
+func (c *Client) Ping(_ context.Context, heartbeatName string) (*heartbeat.PingResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failureLocked("Ping", heartbeatName); err != nil {
+		return nil, err
+	}
+	if h, ok := c.heartbeats[heartbeatName]; ok {
+		h.Expired = false
+		c.heartbeats[h.Name] = h
+	}
+
+	return &heartbeat.PingResult{ResultMetadata: metadata(), Message: PingMessage}, nil
+}
+
+func (c *Client) Get(_ context.Context, heartbeatName string) (*heartbeat.GetResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, err := c.lookup("Get", heartbeatName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &heartbeat.GetResult{ResultMetadata: metadata(), Heartbeat: h}, nil
+}
+
+func (c *Client) List(_ context.Context) (*heartbeat.ListResult, error) {
+	if err := c.failure("List", ""); err != nil {
+		return nil, err
+	}
+	return &heartbeat.ListResult{ResultMetadata: metadata(), Heartbeats: c.Heartbeats()}, nil
+}
+
+// Update changes the heartbeat like the PATCH request sent by the SDK, which
+// omits empty descriptions, alert messages, tags and priorities, so these are
+// never cleared.
+func (c *Client) Update(_ context.Context, request *heartbeat.UpdateRequest) (*heartbeat.HeartbeatInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, err := c.lookup("Update", request.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	h.Interval = request.Interval
+	h.IntervalUnit = string(request.IntervalUnit)
+	h.OwnerTeam = request.OwnerTeam
+	if request.Description != "" {
+		h.Description = request.Description
+	}
+	if request.AlertMessage != "" {
+		h.AlertMessage = request.AlertMessage
+	}
+	if len(request.AlertTag) > 0 {
+		h.AlertTags = request.AlertTag
+	}
+	if request.AlertPriority != "" {
+		h.AlertPriority = request.AlertPriority
+	}
+	if request.Enabled != nil {
+		h.Enabled = *request.Enabled
+	}
+	c.heartbeats[h.Name] = h
+
+	return info(h), nil
+}
+
+// Add creates a heartbeat, with OpsGenie's default alert message and priority
+// unless given, enabled unless disabled explicitly.
+func (c *Client) Add(_ context.Context, request *heartbeat.AddRequest) (*heartbeat.AddResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.failureLocked("Add", request.Name); err != nil {
+		return nil, err
+	}
+	if _, ok := c.heartbeats[request.Name]; ok {
+		return nil, apiError(http.StatusConflict, fmt.Sprintf("Heartbeat with name [%s] already exists", request.Name))
+	}
+
+	h := heartbeat.Heartbeat{
+		Name:          request.Name,
+		Description:   request.Description,
+		Interval:      request.Interval,
+		IntervalUnit:  string(request.IntervalUnit),
+		Enabled:       request.Enabled == nil || *request.Enabled,
+		OwnerTeam:     request.OwnerTeam,
+		AlertTags:     request.AlertTag,
+		AlertPriority: request.AlertPriority,
+		AlertMessage:  request.AlertMessage,
+	}
+	if h.AlertMessage == "" {
+		h.AlertMessage = DefaultAlertMessage(h.Name)
+	}
+	if h.AlertPriority == "" {
+		h.AlertPriority = DefaultAlertPriority
+	}
+	c.heartbeats[h.Name] = h
+
+	return &heartbeat.AddResult{ResultMetadata: metadata(), Heartbeat: h}, nil
+}
+
+func (c *Client) Enable(_ context.Context, heartbeatName string) (*heartbeat.HeartbeatInfo, error) {
+	return c.setEnabled("Enable", heartbeatName, true)
+}
+
+func (c *Client) Disable(_ context.Context, heartbeatName string) (*heartbeat.HeartbeatInfo, error) {
+	return c.setEnabled("Disable", heartbeatName, false)
+}
+
+func (c *Client) Delete(_ context.Context, heartbeatName string) (*heartbeat.DeleteResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.lookup("Delete", heartbeatName); err != nil {
+		return nil, err
+	}
+	delete(c.heartbeats, heartbeatName)
+
+	return &heartbeat.DeleteResult{ResultMetadata: metadata(), Message: "Deleted"}, nil
+}
+
+func (c *Client) setEnabled(method, heartbeatName string, enabled bool) (*heartbeat.HeartbeatInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, err := c.lookup(method, heartbeatName)
+	if err != nil {
+		return nil, err
+	}
+	h.Enabled = enabled
+	c.heartbeats[h.Name] = h
+
+	return info(h), nil
+}
+
+// lookup returns the named heartbeat, or an error if the method was
+// configured to fail or the heartbeat doesn't exist. Must be called with the
+// mutex held.
+func (c *Client) lookup(method, heartbeatName string) (heartbeat.Heartbeat, error) {
+	if err := c.failureLocked(method, heartbeatName); err != nil {
+		return heartbeat.Heartbeat{}, err
+	}
+
+	h, ok := c.heartbeats[heartbeatName]
+	if !ok {
+		return heartbeat.Heartbeat{}, apiError(http.StatusNotFound, fmt.Sprintf("Heartbeat with name [%s] does not exist", heartbeatName))
+	}
+	return h, nil
+}
+
+func (c *Client) failure(method, heartbeatName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failureLocked(method, heartbeatName)
+}
+
+func (c *Client) failureLocked(method, heartbeatName string) error {
+	if err, ok := c.failures[failureKey(method, "")]; ok {
+		return err
+	}
+	return c.failures[failureKey(method, heartbeatName)]
+}
+
+func failureKey(method, name string) string {
+	return method + "/" + name
+}
+
+func info(h heartbeat.Heartbeat) *heartbeat.HeartbeatInfo {
+	return &heartbeat.HeartbeatInfo{
+		ResultMetadata: metadata(),
+		Name:           h.Name,
+		Enabled:        h.Enabled,
+		Expired:        h.Expired,
+	}
+}
+
+func metadata() client.ResultMetadata {
+	return client.ResultMetadata{RequestId: "fake", ResponseTime: 0}
+}
+
+func apiError(status int, message string) error {
+	return &client.ApiError{StatusCode: status, Message: message, RequestId: "fake"}
+}
@@ -0,0 +1,4 @@
+// fake package provides an in-memory implementation of the OpsGenie Heartbeat
+// client Port, that keeps state between calls and can be used to exercise
+// commands end-to-end without talking to the real API.
+package fake
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	redacted = "<redacted>"
+)
+
+// sensitiveHeaders lists headers whose values are redacted when tracing.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// levelChecker is implemented by both logrus Logger and Entry.
+type levelChecker interface {
+	IsLevelEnabled(logrus.Level) bool
+}
+
+// TracingTransport is an http.RoundTripper that logs every request made
+// through it. At debug level it logs a single line with the request method,
+// URL, response status and latency, at trace level it also logs headers and
+// bodies of both the request and the response, with credentials redacted.
+type TracingTransport struct {
+	next   http.RoundTripper
+	logger logrus.FieldLogger
+}
+
+// NewTracingTransport returns a TracingTransport logging to given logger and
+// delegating requests to next, or to `http.DefaultTransport` if nil.
+func NewTracingTransport(next http.RoundTripper, logger logrus.FieldLogger) *TracingTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &TracingTransport{next: next, logger: logger}
+}
+
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.enabled(logrus.DebugLevel) {
+		return t.next.RoundTrip(req)
+	}
+
+	tracing := t.enabled(logrus.TraceLevel)
+	logger := t.logger.WithFields(logrus.Fields{
+		"method": req.Method,
+		"url":    req.URL.String(),
+	})
+
+	if tracing {
+		body, err := peekRequestBody(req)
+		if err != nil {
+			return nil, err
+		}
+		logger.WithFields(logrus.Fields{
+			"headers": redactHeaders(req.Header),
+			"body":    body,
+		}).Trace("HTTP request")
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	logger = logger.WithField("latency", time.Since(start).String())
+	if err != nil {
+		logger.WithError(err).Debug("HTTP request failed")
+		return nil, err
+	}
+
+	logger = logger.WithField("status", resp.StatusCode)
+	if !tracing {
+		logger.Debug("HTTP response")
+		return resp, nil
+	}
+
+	body, err := peekResponseBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	logger.WithFields(logrus.Fields{
+		"headers": redactHeaders(resp.Header),
+		"body":    body,
+	}).Trace("HTTP response")
+
+	return resp, nil
+}
+
+func (t *TracingTransport) enabled(level logrus.Level) bool {
+	lc, ok := t.logger.(levelChecker)
+	return !ok || lc.IsLevelEnabled(level)
+}
+
+// redactHeaders returns headers formatted for logging, with values of
+// sensitive headers replaced. For the Authorization header the scheme is
+// preserved, e.g. "GenieKey <redacted>".
+func redactHeaders(h http.Header) map[string]string {
+	ret := make(map[string]string, len(h))
+	for name, values := range h {
+		ret[name] = strings.Join(values, ", ")
+	}
+
+	for _, name := range sensitiveHeaders {
+		value := h.Get(name)
+		if value == "" {
+			continue
+		}
+		if scheme, _, found := strings.Cut(value, " "); found {
+			ret[name] = scheme + " " + redacted
+		} else {
+			ret[name] = redacted
+		}
+	}
+
+	return ret
+}
+
+// peekRequestBody reads and returns the request body, replacing it with a
+// copy so it can still be sent.
+func peekRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
+
+// peekResponseBody reads and returns the response body, replacing it with a
+// copy so it can still be parsed.
+func peekResponseBody(resp *http.Response) (string, error) {
+	if resp.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return "", err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return string(body), nil
+}
@@ -0,0 +1,113 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/sirupsen/logrus"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+)
+
+var _ = Describe("TracingTransport", func() {
+	var (
+		server *httptest.Server
+		logs   *bytes.Buffer
+		logger *logrus.Logger
+		repo   client.Port
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req-1")
+			w.Header().Set("Set-Cookie", "session=s3cr3t")
+			_, _ = w.Write([]byte(`{"data":{"name":"foo","enabled":true},"took":0.1,"requestId":"req-1"}`))
+		}))
+
+		logs = new(bytes.Buffer)
+		logger = logrus.New()
+		logger.SetOutput(logs)
+		logger.SetFormatter(&logrus.JSONFormatter{})
+
+		var err error
+		repo, err = client.New(&ogclient.Config{
+			ApiKey:         "t0k3n",
+			OpsGenieAPIURL: ogclient.ApiUrl(strings.TrimPrefix(server.URL, "http://")),
+			Logger:         logger,
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	// entries returns log entries logged by the transport.
+	entries := func() []map[string]interface{} {
+		var ret []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			entry := map[string]interface{}{}
+			Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+			if strings.HasPrefix(entry["msg"].(string), "HTTP") {
+				ret = append(ret, entry)
+			}
+		}
+		return ret
+	}
+
+	It("logs nothing below debug level", func() {
+		logger.SetLevel(logrus.InfoLevel)
+		_, err := repo.Get(context.Background(), "foo")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs.String()).NotTo(ContainSubstring("HTTP"))
+	})
+
+	It("logs a summary of each request with latency at debug level", func() {
+		logger.SetLevel(logrus.DebugLevel)
+		_, err := repo.Get(context.Background(), "foo")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(entries()).To(ConsistOf(SatisfyAll(
+			HaveKeyWithValue("msg", "HTTP response"),
+			HaveKeyWithValue("method", "GET"),
+			HaveKeyWithValue("url", server.URL+"/v2/heartbeats/foo"),
+			HaveKeyWithValue("status", BeEquivalentTo(200)),
+			HaveKey("latency"),
+			Not(HaveKey("headers")),
+		)))
+	})
+
+	It("logs redacted requests and responses at trace level", func() {
+		logger.SetLevel(logrus.TraceLevel)
+		hb, err := repo.Get(context.Background(), "foo")
+		Expect(err).NotTo(HaveOccurred())
+
+		By("still passing the response body to the SDK")
+		Expect(hb.Name).To(Equal("foo"))
+
+		By("logging both request and response")
+		Expect(entries()).To(ConsistOf(
+			SatisfyAll(
+				HaveKeyWithValue("msg", "HTTP request"),
+				HaveKeyWithValue("headers", HaveKeyWithValue("Authorization", "GenieKey <redacted>")),
+			),
+			SatisfyAll(
+				HaveKeyWithValue("msg", "HTTP response"),
+				HaveKey("latency"),
+				HaveKeyWithValue("headers", HaveKeyWithValue("Set-Cookie", "<redacted>")),
+				HaveKeyWithValue("body", ContainSubstring(`"name":"foo"`)),
+			),
+		))
+
+		By("never logging credentials")
+		Expect(logs.String()).NotTo(ContainSubstring("t0k3n"))
+		Expect(logs.String()).NotTo(ContainSubstring("s3cr3t"))
+	})
+})
@@ -0,0 +1,197 @@
+package cmdutil
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/giantswarm/heartbeatctl/pkg/conv"
+)
+
+const (
+	// CompletionCacheTTL is how long heartbeats cached for completion are
+	// considered fresh.
+	CompletionCacheTTL = 5 * time.Minute
+)
+
+// completionCache is the content of the completion cache file.
+type completionCache struct {
+	Time       time.Time             `json:"time"`
+	Heartbeats []heartbeat.Heartbeat `json:"heartbeats"`
+}
+
+// Completer provides functions for dynamic shell completion of heartbeat names,
+// labels and fields. Heartbeats are listed using the factory's client and
+// cached in its CacheDir for CompletionCacheTTL, in a file of the account they
+// were listed in, so completion stays fast.
+type Completer struct {
+	f *Factory
+}
+
+func NewCompleter(f *Factory) *Completer {
+	return &Completer{f: f}
+}
+
+// HeartbeatNames completes names of heartbeats that were not given as
+// arguments yet.
+func (c *Completer) HeartbeatNames(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	heartbeats, err := c.heartbeats()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	given := make(map[string]bool, len(args))
+	for _, arg := range args {
+		given[arg] = true
+	}
+
+	var names []string
+	for _, h := range heartbeats {
+		if !given[h.Name] && strings.HasPrefix(h.Name, toComplete) {
+			names = append(names, h.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// LabelSelector completes label keys and values of a label selector, using
+// labels of heartbeats as given by `conv.HeartbeatAsLabels`.
+func (c *Completer) LabelSelector(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return c.selector(toComplete, func(h heartbeat.Heartbeat) map[string]string {
+		// HeartbeatAsLabels returns a labels.Set, which is needed to list keys.
+		ls, _ := conv.HeartbeatAsLabels(h).(labels.Set)
+		return ls
+	})
+}
+
+// FieldSelector completes field names and values of a field selector, using
+// fields of heartbeats as given by `conv.HeartbeatAsFields`.
+func (c *Completer) FieldSelector(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return c.selector(toComplete, func(h heartbeat.Heartbeat) map[string]string {
+		return conv.HeartbeatAsFields(h)
+	})
+}
+
+// selector completes the last requirement of a comma-separated selector,
+// either its key or, once an operator was typed, its value.
+func (c *Completer) selector(toComplete string, set func(heartbeat.Heartbeat) map[string]string) ([]string, cobra.ShellCompDirective) {
+	heartbeats, err := c.heartbeats()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	prefix, requirement := "", toComplete
+	if i := strings.LastIndex(toComplete, ","); i >= 0 {
+		prefix, requirement = toComplete[:i+1], toComplete[i+1:]
+	}
+	if strings.HasPrefix(requirement, "!") {
+		prefix, requirement = prefix+"!", requirement[1:]
+	}
+
+	values := make(map[string]map[string]bool)
+	for _, h := range heartbeats {
+		for key, value := range set(h) {
+			if values[key] == nil {
+				values[key] = make(map[string]bool)
+			}
+			values[key][value] = true
+		}
+	}
+
+	var completions []string
+	if i := strings.IndexAny(requirement, "=!<>~"); i > 0 {
+		key := requirement[:i]
+		op := requirement[i:]
+		valuePrefix := strings.TrimLeft(op, "=!<>~")
+		op = op[:len(op)-len(valuePrefix)]
+		for value := range values[key] {
+			if value != "" && strings.HasPrefix(value, valuePrefix) {
+				completions = append(completions, prefix+key+op+value)
+			}
+		}
+	} else {
+		for key := range values {
+			if strings.HasPrefix(key, requirement) {
+				completions = append(completions, prefix+key)
+			}
+		}
+	}
+
+	sort.Strings(completions)
+	return completions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// heartbeats returns cached heartbeats if fresh, otherwise lists them using
+// the factory's client and refreshes the cache.
+func (c *Completer) heartbeats() ([]heartbeat.Heartbeat, error) {
+	path := ""
+	if c.f.CacheDir != "" {
+		path = filepath.Join(c.f.CacheDir, c.cacheFile())
+		if cached, err := readCompletionCache(path); err == nil && c.f.Now().Sub(cached.Time) < CompletionCacheTTL {
+			return cached.Heartbeats, nil
+		}
+	}
+
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	result, err := repo.List(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	if path != "" {
+		// Failing to write the cache only makes the next completion slower.
+		_ = writeCompletionCache(path, &completionCache{Time: c.f.Now(), Heartbeats: result.Heartbeats})
+	}
+
+	return result.Heartbeats, nil
+}
+
+// cacheFile returns the name of the file in factory's CacheDir heartbeats of
+// its account are cached in, named after a hash of the account's API URL and
+// key, so the key isn't stored in plain text.
+func (c *Completer) cacheFile() string {
+	apiURL, apiKey := "", ""
+	if c.f.Account != nil {
+		apiURL, apiKey = c.f.Account()
+	}
+	sum := sha256.Sum256([]byte(apiURL + "\n" + apiKey))
+	return "completion-" + hex.EncodeToString(sum[:8]) + ".json"
+}
+
+func readCompletionCache(path string) (*completionCache, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &completionCache{}
+	if err := json.Unmarshal(data, cached); err != nil {
+		return nil, err
+	}
+	return cached, nil
+}
+
+func writeCompletionCache(path string, cached *completionCache) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
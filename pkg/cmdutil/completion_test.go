@@ -0,0 +1,128 @@
+package cmdutil_test
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+)
+
+var _ = Describe("Completer", func() {
+	var (
+		repo     *fake.Client
+		cacheDir string
+		calls    int
+		now      time.Time
+		apiKey   string
+	)
+
+	// newCompleter returns a completer using a new factory, as each completion
+	// runs in a new process.
+	newCompleter := func() *cmdutil.Completer {
+		f := cmdutil.NewFactory()
+		f.CacheDir = cacheDir
+		f.ClientFunc = func() (client.Port, error) {
+			calls++
+			return repo, nil
+		}
+		f.Account = func() (string, string) { return "api.opsgenie.com", apiKey }
+		f.Now = func() time.Time { return now }
+		return cmdutil.NewCompleter(f)
+	}
+
+	BeforeEach(func() {
+		calls = 0
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+		apiKey = "key"
+		cacheDir = GinkgoT().TempDir()
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Enabled: true, AlertPriority: "P3", AlertTags: []string{"team: a"}},
+			heartbeat.Heartbeat{Name: "bar", AlertPriority: "P4", AlertTags: []string{"team: b", "tagged"}},
+		)
+	})
+
+	It("completes heartbeat names not given yet", func() {
+		names, directive := newCompleter().HeartbeatNames(nil, []string{"foo"}, "")
+		Expect(names).To(Equal([]string{"bar"}))
+		Expect(directive).To(Equal(cobra.ShellCompDirectiveNoFileComp))
+	})
+
+	It("completes label keys and values of the last requirement", func() {
+		c := newCompleter()
+
+		keys, directive := c.LabelSelector(nil, nil, "enabled,t")
+		Expect(keys).To(Equal([]string{"enabled,tagged", "enabled,team"}))
+		Expect(directive).To(Equal(cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp))
+
+		values, _ := c.LabelSelector(nil, nil, "team!=")
+		Expect(values).To(Equal([]string{"team!=a", "team!=b"}))
+
+		negated, _ := c.LabelSelector(nil, nil, "!ena")
+		Expect(negated).To(Equal([]string{"!enabled"}))
+	})
+
+	It("completes field names and values", func() {
+		c := newCompleter()
+
+		names, _ := c.FieldSelector(nil, nil, "alertP")
+		Expect(names).To(Equal([]string{"alertPriority"}))
+
+		values, _ := c.FieldSelector(nil, nil, "alertPriority==P")
+		Expect(values).To(Equal([]string{"alertPriority==P3", "alertPriority==P4"}))
+	})
+
+	It("caches heartbeats between completions", func() {
+		first, _ := newCompleter().HeartbeatNames(nil, nil, "")
+		Expect(repo.Delete(context.Background(), "bar")).Error().NotTo(HaveOccurred())
+
+		second, _ := newCompleter().HeartbeatNames(nil, nil, "")
+		Expect(second).To(Equal(first))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("refreshes a stale cache", func() {
+		newCompleter().HeartbeatNames(nil, nil, "")
+		Expect(repo.Delete(context.Background(), "bar")).Error().NotTo(HaveOccurred())
+		now = now.Add(cmdutil.CompletionCacheTTL)
+
+		names, _ := newCompleter().HeartbeatNames(nil, nil, "")
+		Expect(names).To(Equal([]string{"foo"}))
+		Expect(calls).To(Equal(2))
+	})
+
+	It("keeps caches of different accounts apart without storing keys", func() {
+		newCompleter().HeartbeatNames(nil, nil, "")
+		apiKey = "other-key"
+		newCompleter().HeartbeatNames(nil, nil, "")
+		Expect(calls).To(Equal(2))
+
+		files, err := filepath.Glob(filepath.Join(cacheDir, "*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(2))
+		for _, file := range files {
+			Expect(file).NotTo(ContainSubstring("key"))
+		}
+	})
+
+	It("doesn't cache without a cache directory", func() {
+		cacheDir = ""
+		newCompleter().HeartbeatNames(nil, nil, "")
+		newCompleter().HeartbeatNames(nil, nil, "")
+		Expect(calls).To(Equal(2))
+	})
+
+	It("reports an error when heartbeats can't be listed", func() {
+		repo.Fail("List", "", client.ErrMissingAPIKey)
+		names, directive := newCompleter().HeartbeatNames(nil, nil, "")
+		Expect(names).To(BeEmpty())
+		Expect(directive).To(Equal(cobra.ShellCompDirectiveError))
+	})
+})
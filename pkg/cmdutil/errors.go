@@ -0,0 +1,181 @@
+package cmdutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// Exit codes returned by heartbeatctl, so scripts can tell different kinds of
+// failures apart.
+const (
+	// ExitOK means the command succeeded.
+	ExitOK = 0
+	// ExitAPIError means a request to OpsGenie failed, or some other
+	// unclassified error occurred.
+	ExitAPIError = 1
+	// ExitUsageError means the command was invoked incorrectly, e.g. with
+	// unknown flags or malformed selectors.
+	ExitUsageError = 2
+	// ExitAuthError means the API key is missing or was rejected by OpsGenie.
+	ExitAuthError = 3
+	// ExitNotFound means the requested heartbeat doesn't exist, or selectors
+	// matched no heartbeats.
+	ExitNotFound = 4
+	// ExitPartialFailure means the operation succeeded for some of the
+	// selected heartbeats but failed for others.
+	ExitPartialFailure = 5
+)
+
+// Formats errors can be reported in.
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+// reasons maps exit codes to short machine-readable reasons used in error
+// reports.
+var reasons = map[int]string{
+	ExitAPIError:       "APIError",
+	ExitUsageError:     "UsageError",
+	ExitAuthError:      "AuthError",
+	ExitNotFound:       "NotFound",
+	ExitPartialFailure: "PartialFailure",
+}
+
+// UsageError is an error caused by invalid command line usage.
+type UsageError struct {
+	Err error
+}
+
+// UsageErrorf returns a UsageError with a message formatted according to
+// given format specifier.
+func UsageErrorf(format string, a ...interface{}) error {
+	return &UsageError{Err: fmt.Errorf(format, a...)}
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+// StatusError makes heartbeatctl exit with given code without reporting an
+// error, for commands whose exit code is their result, like 'check', which
+// print the result themselves.
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit code heartbeatctl should exit with when a command
+// fails with given error.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+
+	var usageErr *UsageError
+	var selectorErr *ctl.InvalidSelectorError
+	if errors.As(err, &usageErr) || errors.As(err, &selectorErr) || errors.Is(err, ctl.ErrNoSelector) ||
+		errors.Is(err, ctl.ErrUnscopedPrune) {
+		return ExitUsageError
+	}
+
+	var hbErr *ctl.HeartbeatsError
+	if errors.As(err, &hbErr) && hbErr.Partial() {
+		return ExitPartialFailure
+	}
+
+	if errors.Is(err, client.ErrMissingAPIKey) {
+		return ExitAuthError
+	}
+
+	var apiErr *ogclient.ApiError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return ExitAuthError
+		case http.StatusNotFound:
+			return ExitNotFound
+		}
+	}
+
+	if errors.Is(err, ctl.ErrNoMatch) {
+		return ExitNotFound
+	}
+
+	return ExitAPIError
+}
+
+// ErrorReport is a machine-readable description of an error a command failed
+// with.
+type ErrorReport struct {
+	// ExitCode is the code heartbeatctl exits with.
+	ExitCode int `json:"exitCode"`
+	// Reason is a short CamelCase description of the exit code.
+	Reason string `json:"reason"`
+	// Message is the human-readable error message.
+	Message string `json:"message"`
+	// FailedHeartbeats lists names of heartbeats the operation failed for.
+	FailedHeartbeats []string `json:"failedHeartbeats,omitempty"`
+	// SucceededHeartbeats lists names of heartbeats the operation succeeded
+	// for before or despite other failures.
+	SucceededHeartbeats []string `json:"succeededHeartbeats,omitempty"`
+}
+
+// NewErrorReport returns an ErrorReport describing given error.
+func NewErrorReport(err error) *ErrorReport {
+	code := ExitCode(err)
+	report := &ErrorReport{
+		ExitCode: code,
+		Reason:   reasons[code],
+		Message:  err.Error(),
+	}
+
+	var hbErr *ctl.HeartbeatsError
+	if errors.As(err, &hbErr) {
+		report.FailedHeartbeats = hbErr.Failed
+		report.SucceededHeartbeats = hbErr.Succeeded
+	}
+
+	return report
+}
+
+// ReportError writes given error to w in given format and returns the exit
+// code the process should exit with. A StatusError is not written.
+func ReportError(w io.Writer, format string, err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+
+	report := NewErrorReport(err)
+
+	if format == ErrorFormatJSON {
+		enc := json.NewEncoder(w)
+		if encErr := enc.Encode(report); encErr == nil {
+			return report.ExitCode
+		}
+	}
+
+	fmt.Fprintf(w, "Error: %s\n", report.Message)
+	return report.ExitCode
+}
@@ -0,0 +1,92 @@
+package cmdutil_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Errors", func() {
+	Describe("ExitCode", func() {
+		DescribeTable("classifies errors",
+			func(err error, expected int) {
+				Expect(cmdutil.ExitCode(err)).To(Equal(expected))
+			},
+			Entry("no error", nil, cmdutil.ExitOK),
+			Entry("unclassified error", errors.New("boom"), cmdutil.ExitAPIError),
+			Entry("usage error", cmdutil.UsageErrorf("bad flag"), cmdutil.ExitUsageError),
+			Entry("missing selector", fmt.Errorf("wrapped: %w", ctl.ErrNoSelector), cmdutil.ExitUsageError),
+			Entry("unscoped prune", ctl.ErrUnscopedPrune, cmdutil.ExitUsageError),
+			Entry("invalid selector", &ctl.InvalidSelectorError{Selector: "in in", Err: errors.New("bad")}, cmdutil.ExitUsageError),
+			Entry("missing API key", client.ErrMissingAPIKey, cmdutil.ExitAuthError),
+			Entry("unauthorized", &ogclient.ApiError{StatusCode: 401}, cmdutil.ExitAuthError),
+			Entry("forbidden", &ogclient.ApiError{StatusCode: 403}, cmdutil.ExitAuthError),
+			Entry("not found", &ogclient.ApiError{StatusCode: 404}, cmdutil.ExitNotFound),
+			Entry("server error", &ogclient.ApiError{StatusCode: 500}, cmdutil.ExitAPIError),
+			Entry("no match", ctl.ErrNoMatch, cmdutil.ExitNotFound),
+			Entry("status", &cmdutil.StatusError{Code: 2}, 2),
+			Entry(
+				"partial failure",
+				&ctl.HeartbeatsError{
+					Failed:    []string{"foo"},
+					Succeeded: []string{"bar"},
+					Errors:    map[string]error{"foo": &ogclient.ApiError{StatusCode: 401}},
+				},
+				cmdutil.ExitPartialFailure,
+			),
+			Entry(
+				"complete failure classified by the underlying error",
+				&ctl.HeartbeatsError{
+					Failed: []string{"foo"},
+					Errors: map[string]error{"foo": &ogclient.ApiError{StatusCode: 401}},
+				},
+				cmdutil.ExitAuthError,
+			),
+		)
+	})
+
+	Describe("ReportError", func() {
+		var (
+			buf *bytes.Buffer
+			err error
+		)
+
+		BeforeEach(func() {
+			buf = new(bytes.Buffer)
+			err = &ctl.HeartbeatsError{
+				Failed:    []string{"foo"},
+				Succeeded: []string{"bar"},
+				Errors:    map[string]error{"foo": errors.New("boom")},
+			}
+		})
+
+		It("doesn't write status errors", func() {
+			Expect(cmdutil.ReportError(buf, cmdutil.ErrorFormatJSON, &cmdutil.StatusError{Code: 3})).To(Equal(3))
+			Expect(buf.String()).To(BeEmpty())
+		})
+
+		It("writes a human-readable message in text format", func() {
+			Expect(cmdutil.ReportError(buf, cmdutil.ErrorFormatText, err)).To(Equal(cmdutil.ExitPartialFailure))
+			Expect(buf.String()).To(Equal("Error: heartbeat \"foo\" failed: boom\n"))
+		})
+
+		It("writes an error object in JSON format", func() {
+			Expect(cmdutil.ReportError(buf, cmdutil.ErrorFormatJSON, err)).To(Equal(cmdutil.ExitPartialFailure))
+			Expect(buf.String()).To(MatchJSON(`{
+				"exitCode": 5,
+				"reason": "PartialFailure",
+				"message": "heartbeat \"foo\" failed: boom",
+				"failedHeartbeats": ["foo"],
+				"succeededHeartbeats": ["bar"]
+			}`))
+		})
+	})
+})
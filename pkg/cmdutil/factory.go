@@ -0,0 +1,256 @@
+package cmdutil
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/sirupsen/logrus"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// IOStreams holds the streams commands read input from and write their output
+// to, so they can be replaced with buffers when commands are run in tests.
+type IOStreams struct {
+	In     io.Reader
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+// Factory provides commands with their dependencies, like the OpsGenie client
+// and IO streams, instead of commands creating them on their own.
+type Factory struct {
+	IOStreams
+
+	// Logger is shared by commands and the OpsGenie client, and configured
+	// from logging flags before a command runs.
+	Logger *logrus.Logger
+
+	// ClientFunc returns the client commands should use to talk to OpsGenie.
+	// It is called at most once, when a command first needs the client, so
+	// commands that don't talk to OpsGenie work without an API key.
+	ClientFunc func() (client.Port, error)
+
+	// AccountClientFunc returns a client for the OpsGenie account with the
+	// API key read from given environment variable and given API URL, the
+	// default one if empty. It's used by commands working with more than one
+	// account.
+	AccountClientFunc func(tokenEnvVar, apiURL string) (client.Port, error)
+
+	// Account returns the API URL and key of the account the client returned
+	// by ClientFunc talks to, so data cached for different accounts is kept
+	// apart.
+	Account func() (apiURL, apiKey string)
+
+	// CacheDir is the directory commands may cache data in, like heartbeats
+	// used for shell completion. Caching is disabled when empty.
+	CacheDir string
+
+	// IsTerminal returns true if commands can interact with the user, i.e.
+	// both input and error output are connected to a terminal.
+	IsTerminal func() bool
+
+	// ConfigDir is the directory user configuration, like saved selectors,
+	// is read from.
+	ConfigDir string
+
+	// StateDir is the directory state that should persist between runs, like
+	// the change journal, is kept in. Journaling is disabled when empty.
+	StateDir string
+
+	// User is the name of the user running commands, recorded in the journal.
+	User string
+
+	// Now returns the current time, used to timestamp journal entries.
+	Now func() time.Time
+
+	// CommandLine holds the arguments heartbeatctl was run with, including
+	// the program name, and is set by `cmd.Run`.
+	CommandLine []string
+
+	clientOnce sync.Once
+	client     client.Port
+	clientErr  error
+}
+
+// NewFactory returns a Factory using standard IO streams, a quiet logger
+// writing to stderr, an OpsGenie client configured from the environment
+// that logs using the same logger, and heartbeatctl directories in the user's
+// cache, configuration and state directories.
+func NewFactory() *Factory {
+	logger := logrus.New()
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(logrus.PanicLevel)
+
+	cacheDir := ""
+	if dir, err := os.UserCacheDir(); err == nil {
+		cacheDir = filepath.Join(dir, "heartbeatctl")
+	}
+	configDir := ""
+	if dir, err := os.UserConfigDir(); err == nil {
+		configDir = filepath.Join(dir, "heartbeatctl")
+	}
+	stateDir := ""
+	if dir, err := userStateDir(); err == nil {
+		stateDir = filepath.Join(dir, "heartbeatctl")
+	}
+
+	return &Factory{
+		IOStreams: IOStreams{
+			In:     os.Stdin,
+			Out:    os.Stdout,
+			ErrOut: os.Stderr,
+		},
+		Logger: logger,
+		ClientFunc: func() (client.Port, error) {
+			return client.New(&ogclient.Config{Logger: logger})
+		},
+		AccountClientFunc: func(tokenEnvVar, apiURL string) (client.Port, error) {
+			return client.NewFromEnv(tokenEnvVar, &ogclient.Config{
+				OpsGenieAPIURL: ogclient.ApiUrl(apiURL),
+				Logger:         logger,
+			})
+		},
+		Account: func() (string, string) {
+			return string(ogclient.API_URL), os.Getenv(client.TokenEnvVar)
+		},
+		IsTerminal: func() bool {
+			return isTerminal(os.Stdin) && isTerminal(os.Stderr)
+		},
+		CacheDir:  cacheDir,
+		ConfigDir: configDir,
+		StateDir:  stateDir,
+		User:      currentUser(),
+		Now:       time.Now,
+	}
+}
+
+// Client returns an OpsGenie client obtained from the configured ClientFunc,
+// constructing it on first use.
+func (f *Factory) Client() (client.Port, error) {
+	f.clientOnce.Do(func() {
+		f.client, f.clientErr = f.ClientFunc()
+	})
+	return f.client, f.clientErr
+}
+
+// SavedSelectorsPath returns the path of the file saved selectors are read
+// from and written to.
+func (f *Factory) SavedSelectorsPath() string {
+	return filepath.Join(f.ConfigDir, SavedSelectorsFile)
+}
+
+// Ctl returns a `ctl.Port` backed by the configured OpsGenie client and
+// configured with given options. The client is only constructed once ctl
+// needs to make a request, so invalid selectors are reported before a missing
+// API key.
+func (f *Factory) Ctl(opts ...ctl.Option) ctl.Port {
+	return ctl.NewCtl(lazyClient{f: f}, opts...)
+}
+
+// userStateDir returns the directory user-specific state should be kept in,
+// following the XDG Base Directory Specification.
+func userStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state"), nil
+}
+
+// currentUser returns the name of the user running the process, or an empty
+// string if it can't be determined.
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// isTerminal returns true if given file is a character device, like a
+// terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// lazyClient is a client.Port obtaining the actual client from the factory on
+// first call of any of its methods.
+type lazyClient struct {
+	f *Factory
+}
+
+func (c lazyClient) Ping(ctx context.Context, heartbeatName string) (*heartbeat.PingResult, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Ping(ctx, heartbeatName)
+}
+
+func (c lazyClient) Get(ctx context.Context, heartbeatName string) (*heartbeat.GetResult, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Get(ctx, heartbeatName)
+}
+
+func (c lazyClient) List(ctx context.Context) (*heartbeat.ListResult, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.List(ctx)
+}
+
+func (c lazyClient) Update(ctx context.Context, request *heartbeat.UpdateRequest) (*heartbeat.HeartbeatInfo, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Update(ctx, request)
+}
+
+func (c lazyClient) Add(ctx context.Context, request *heartbeat.AddRequest) (*heartbeat.AddResult, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Add(ctx, request)
+}
+
+func (c lazyClient) Enable(ctx context.Context, heartbeatName string) (*heartbeat.HeartbeatInfo, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Enable(ctx, heartbeatName)
+}
+
+func (c lazyClient) Disable(ctx context.Context, heartbeatName string) (*heartbeat.HeartbeatInfo, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Disable(ctx, heartbeatName)
+}
+
+func (c lazyClient) Delete(ctx context.Context, heartbeatName string) (*heartbeat.DeleteResult, error) {
+	repo, err := c.f.Client()
+	if err != nil {
+		return nil, err
+	}
+	return repo.Delete(ctx, heartbeatName)
+}
@@ -0,0 +1,61 @@
+package cmdutil_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Factory", func() {
+	var (
+		f     *cmdutil.Factory
+		calls int
+	)
+
+	BeforeEach(func() {
+		calls = 0
+		f = cmdutil.NewFactory()
+	})
+
+	It("constructs the client lazily and only once", func() {
+		repo := fake.NewClient()
+		f.ClientFunc = func() (client.Port, error) {
+			calls++
+			return repo, nil
+		}
+
+		c := f.Ctl()
+		Expect(calls).To(Equal(0))
+
+		_, err := c.Get(&ctl.SelectorConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Client()).To(BeIdenticalTo(repo))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("remembers the error of a failed construction", func() {
+		f.ClientFunc = func() (client.Port, error) {
+			calls++
+			return nil, errors.New("boom")
+		}
+
+		_, err := f.Client()
+		Expect(err).To(MatchError("boom"))
+
+		_, err = f.Ctl().Get(&ctl.SelectorConfig{})
+		Expect(err).To(MatchError("boom"))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("fails to construct the default client without an API key", func() {
+		GinkgoT().Setenv("HEARTBEATCTL_TOKEN", "")
+		_, err := f.Client()
+		Expect(err).To(MatchError(client.ErrMissingAPIKey))
+	})
+})
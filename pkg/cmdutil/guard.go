@@ -0,0 +1,149 @@
+package cmdutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+const (
+	// ConfirmThresholdEnv is the environment variable overriding how many
+	// heartbeats can be changed at once without confirmation.
+	ConfirmThresholdEnv = "HEARTBEATCTL_CONFIRM_THRESHOLD"
+
+	// DefaultConfirmThreshold is how many heartbeats can be changed at once
+	// without confirmation by default.
+	DefaultConfirmThreshold = 10
+)
+
+// ErrAborted is returned when the user declines to confirm an operation.
+var ErrAborted = errors.New("aborted, no heartbeats were changed")
+
+// TooManyTargetsError is returned, wrapped in a UsageError, when selectors
+// match more heartbeats than allowed with `--max-targets`.
+type TooManyTargetsError struct {
+	Action  string
+	Matched int
+	Max     int
+}
+
+func (e *TooManyTargetsError) Error() string {
+	return fmt.Sprintf("refusing to %s %d heartbeats, at most %d allowed by '--max-targets'", e.Action, e.Matched, e.Max)
+}
+
+// GuardOptions holds values for safety options of commands changing
+// heartbeats, and provides a `ctl.Guard` enforcing them.
+type GuardOptions struct {
+	maxTargets int
+	yes        bool
+	noConfirm  bool
+}
+
+func NewGuardOptions() *GuardOptions {
+	return &GuardOptions{}
+}
+
+// WithoutConfirmation configures the guard to never ask for confirmation,
+// only enforcing `--max-targets`, for commands that are run unattended with
+// many heartbeats, like `ping`.
+func (g *GuardOptions) WithoutConfirmation() *GuardOptions {
+	g.noConfirm = true
+	return g
+}
+
+// AddFlags adds max targets and, unless configured without confirmation,
+// confirmation flags to given cobra command.
+func (g *GuardOptions) AddFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+	flags.IntVar(
+		&g.maxTargets, "max-targets", g.maxTargets,
+		"Fail without changing anything if selectors match more heartbeats than this, 0 means no limit.",
+	)
+	if g.noConfirm {
+		return
+	}
+	flags.BoolVarP(
+		&g.yes, "yes", "y", g.yes,
+		"Don't ask for confirmation regardless of how many heartbeats are affected.",
+	)
+}
+
+// Guard returns a `ctl.Guard` that fails if more than `--max-targets`
+// heartbeats were selected, and unless `--yes` was given or it's configured
+// without confirmation, asks for
+// confirmation if more heartbeats than the threshold set with
+// ConfirmThresholdEnv were selected. Confirmation is read from the factory's
+// input, and when it's not a terminal the operation fails instead.
+func (g *GuardOptions) Guard(f *Factory) ctl.Guard {
+	return func(action string, heartbeats []heartbeat.Heartbeat) error {
+		if g.maxTargets > 0 && len(heartbeats) > g.maxTargets {
+			return &UsageError{Err: &TooManyTargetsError{Action: action, Matched: len(heartbeats), Max: g.maxTargets}}
+		}
+		if g.yes || g.noConfirm {
+			return nil
+		}
+
+		threshold, err := confirmThreshold()
+		if err != nil {
+			return err
+		}
+		if len(heartbeats) <= threshold {
+			return nil
+		}
+
+		if !f.IsTerminal() {
+			return UsageErrorf(
+				"refusing to %s %d heartbeats without confirmation, pass '--yes' to confirm changing more than %d heartbeats",
+				action, len(heartbeats), threshold,
+			)
+		}
+
+		return confirm(f, action, heartbeats)
+	}
+}
+
+// confirmThreshold returns the threshold set with ConfirmThresholdEnv, or the
+// default one.
+func confirmThreshold() (int, error) {
+	value, ok := os.LookupEnv(ConfirmThresholdEnv)
+	if !ok || value == "" {
+		return DefaultConfirmThreshold, nil
+	}
+
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 0 {
+		return 0, UsageErrorf("%s must be a non-negative number, got %q", ConfirmThresholdEnv, value)
+	}
+	return threshold, nil
+}
+
+// confirm lists given heartbeats and asks the user to confirm applying the
+// action to them.
+func confirm(f *Factory, action string, heartbeats []heartbeat.Heartbeat) error {
+	fmt.Fprintf(f.ErrOut, "About to %s %d heartbeats:\n", action, len(heartbeats))
+	for _, h := range heartbeats {
+		fmt.Fprintf(f.ErrOut, "  %s\n", h.Name)
+	}
+	fmt.Fprint(f.ErrOut, "Continue? [y/N]: ")
+
+	answer, err := bufio.NewReader(f.In).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(f.ErrOut)
+		return ErrAborted
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	default:
+		return ErrAborted
+	}
+}
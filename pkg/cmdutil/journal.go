@@ -0,0 +1,74 @@
+package cmdutil
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/journal"
+)
+
+// JournalFile is the name of the file in factory's StateDir changes made to
+// heartbeats are recorded in.
+const JournalFile = "journal.jsonl"
+
+// Journal returns the journal changes made to heartbeats are recorded in, or
+// nil if journaling is disabled.
+func (f *Factory) Journal() *journal.Journal {
+	if f.StateDir == "" {
+		return nil
+	}
+	return journal.Open(filepath.Join(f.StateDir, JournalFile))
+}
+
+// JournalEntry returns a journal entry recording that given action is about
+// to be applied to given heartbeats by the current user and command line.
+func (f *Factory) JournalEntry(action string, heartbeats []heartbeat.Heartbeat) journal.Entry {
+	return journal.Entry{
+		Time:       f.Now().UTC(),
+		User:       f.User,
+		Command:    shellJoin(f.CommandLine),
+		Action:     action,
+		Heartbeats: heartbeats,
+	}
+}
+
+// Recorder returns a `ctl.Recorder` appending an entry to the journal before
+// each change, or nil if journaling is disabled. Changes are not made if
+// they can't be recorded.
+func (f *Factory) Recorder() ctl.Recorder {
+	return f.RecorderFor(0)
+}
+
+// RecorderFor returns a `ctl.Recorder` like Recorder does, with entries
+// marked as undoing the journal entry with given ID, unless it's zero.
+func (f *Factory) RecorderFor(undoes int) ctl.Recorder {
+	j := f.Journal()
+	if j == nil {
+		return nil
+	}
+	return func(action string, heartbeats []heartbeat.Heartbeat) error {
+		entry := f.JournalEntry(action, heartbeats)
+		entry.Undoes = undoes
+		if _, err := j.Append(entry); err != nil {
+			return fmt.Errorf("failed to record change in journal: %w", err)
+		}
+		return nil
+	}
+}
+
+// shellJoin joins given arguments into a command line, quoting those that
+// would otherwise be split or interpreted by a shell.
+func shellJoin(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`!*?[](){}<>|&;#~") {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,66 @@
+package cmdutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/journal"
+)
+
+var _ = Describe("Recorder", func() {
+	var f *cmdutil.Factory
+
+	BeforeEach(func() {
+		f = cmdutil.NewFactory()
+		f.StateDir = GinkgoT().TempDir()
+		f.User = "alice"
+		f.Now = func() time.Time { return time.Date(2022, 10, 5, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60)) }
+		f.CommandLine = []string{"heartbeatctl", "disable", "-l", "!enabled", "foo bar", "it's"}
+	})
+
+	It("appends entries to the journal in the state directory", func() {
+		heartbeats := []heartbeat.Heartbeat{{Name: "foo", Enabled: true}}
+		Expect(f.Recorder()("disable", heartbeats)).To(Succeed())
+		Expect(f.RecorderFor(1)("restore", heartbeats)).To(Succeed())
+
+		entries, err := journal.Open(filepath.Join(f.StateDir, cmdutil.JournalFile)).Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]journal.Entry{
+			{
+				ID:         1,
+				Time:       time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC),
+				User:       "alice",
+				Command:    `heartbeatctl disable -l '!enabled' 'foo bar' 'it'\''s'`,
+				Action:     "disable",
+				Heartbeats: heartbeats,
+			},
+			{
+				ID:         2,
+				Time:       time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC),
+				User:       "alice",
+				Command:    `heartbeatctl disable -l '!enabled' 'foo bar' 'it'\''s'`,
+				Action:     "restore",
+				Undoes:     1,
+				Heartbeats: heartbeats,
+			},
+		}))
+	})
+
+	It("fails if the change can't be recorded", func() {
+		Expect(os.WriteFile(filepath.Join(f.StateDir, cmdutil.JournalFile), []byte("nope\n"), 0600)).To(Succeed())
+		err := f.Recorder()("disable", nil)
+		Expect(err).To(MatchError(HavePrefix("failed to record change in journal: ")))
+	})
+
+	It("is disabled without a state directory", func() {
+		f.StateDir = ""
+		Expect(f.Journal()).To(BeNil())
+		Expect(f.Recorder()).To(BeNil())
+	})
+})
@@ -0,0 +1,80 @@
+package cmdutil
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Formats logs can be written in.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// verbosityLevels maps verbosity given on CLI to log levels, the last one is
+// used for any higher verbosity. By default only errors reported by the failing
+// command itself are printed, while the highest level also traces HTTP
+// requests and responses sent to OpsGenie.
+var verbosityLevels = []logrus.Level{
+	logrus.PanicLevel,
+	logrus.WarnLevel,
+	logrus.InfoLevel,
+	logrus.DebugLevel,
+	logrus.TraceLevel,
+}
+
+// LoggingOptions holds values for logging options given on CLI and provides
+// methods to add necessary flags to a Cobra command and to configure a logger
+// accordingly.
+type LoggingOptions struct {
+	verbosity int
+	format    string
+}
+
+func NewLoggingOptions() *LoggingOptions {
+	return &LoggingOptions{format: LogFormatText}
+}
+
+// AddFlags adds verbosity and log format flags as persistent flags of given
+// cobra command, so they are available to all its subcommands.
+func (lo *LoggingOptions) AddFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+	flags.IntVarP(
+		&lo.verbosity, "verbosity", "v", lo.verbosity,
+		fmt.Sprintf("log verbosity from 0 (quiet) to %d (trace HTTP requests)", len(verbosityLevels)-1),
+	)
+	flags.StringVar(
+		&lo.format, "log-format", lo.format,
+		fmt.Sprintf("format to write logs in, one of '%s' or '%s'", LogFormatText, LogFormatJSON),
+	)
+}
+
+// Level returns the log level corresponding to configured verbosity.
+func (lo *LoggingOptions) Level() logrus.Level {
+	switch {
+	case lo.verbosity < 0:
+		return verbosityLevels[0]
+	case lo.verbosity >= len(verbosityLevels):
+		return verbosityLevels[len(verbosityLevels)-1]
+	default:
+		return verbosityLevels[lo.verbosity]
+	}
+}
+
+// Configure sets level and formatter of given logger according to the
+// options, or returns a UsageError if they are invalid.
+func (lo *LoggingOptions) Configure(logger *logrus.Logger) error {
+	switch lo.format {
+	case LogFormatText:
+		logger.SetFormatter(&logrus.TextFormatter{})
+	case LogFormatJSON:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+	default:
+		return UsageErrorf("log format must be one of '%s' or '%s'", LogFormatText, LogFormatJSON)
+	}
+
+	logger.SetLevel(lo.Level())
+	return nil
+}
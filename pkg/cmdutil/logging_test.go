@@ -0,0 +1,64 @@
+package cmdutil_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+)
+
+var _ = Describe("Logging", func() {
+	var (
+		cmd    *cobra.Command
+		opts   *cmdutil.LoggingOptions
+		logger *logrus.Logger
+	)
+
+	BeforeEach(func() {
+		cmd = &cobra.Command{
+			Use: "fake",
+			Run: func(_ *cobra.Command, _ []string) {},
+		}
+		cmd.SetOut(new(bytes.Buffer))
+		cmd.SetErr(new(bytes.Buffer))
+
+		opts = cmdutil.NewLoggingOptions()
+		opts.AddFlags(cmd)
+		logger = logrus.New()
+	})
+
+	execute := func(args ...string) error {
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			return err
+		}
+		return opts.Configure(logger)
+	}
+
+	DescribeTable("maps verbosity to log levels",
+		func(verbosity string, level logrus.Level) {
+			Expect(execute("-v", verbosity)).To(Succeed())
+			Expect(logger.GetLevel()).To(Equal(level))
+		},
+		Entry("quiet by default", "0", logrus.PanicLevel),
+		Entry("warnings", "1", logrus.WarnLevel),
+		Entry("info", "2", logrus.InfoLevel),
+		Entry("debug", "3", logrus.DebugLevel),
+		Entry("trace", "4", logrus.TraceLevel),
+		Entry("trace for anything higher", "9", logrus.TraceLevel),
+	)
+
+	It("configures JSON formatter", func() {
+		Expect(execute("--log-format=json")).To(Succeed())
+		Expect(logger.Formatter).To(BeAssignableToTypeOf(&logrus.JSONFormatter{}))
+	})
+
+	It("rejects unknown formats with a usage error", func() {
+		err := execute("--log-format=xml")
+		Expect(cmdutil.ExitCode(err)).To(Equal(cmdutil.ExitUsageError))
+	})
+})
@@ -0,0 +1,56 @@
+package cmdutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// Formats commands can print their output in.
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
+// OutputOptions holds the output format given on CLI and provides methods to
+// add the necessary flag to a Cobra command and to validate it.
+type OutputOptions struct {
+	format string
+}
+
+func NewOutputOptions() *OutputOptions {
+	return &OutputOptions{format: OutputFormatText}
+}
+
+// AddFlags adds the output format flag to given cobra command.
+func (o *OutputOptions) AddFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(
+		&o.format, "output", "o", o.format,
+		fmt.Sprintf("Output format, one of '%s' or '%s'.", OutputFormatText, OutputFormatJSON),
+	)
+	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(
+		[]string{OutputFormatText, OutputFormatJSON}, cobra.ShellCompDirectiveNoFileComp,
+	))
+}
+
+// Validate returns a UsageError if the output format is not supported.
+func (o *OutputOptions) Validate() error {
+	if o.format != OutputFormatText && o.format != OutputFormatJSON {
+		return UsageErrorf("output format must be one of '%s' or '%s'", OutputFormatText, OutputFormatJSON)
+	}
+	return nil
+}
+
+// JSON returns true if output should be printed as JSON.
+func (o *OutputOptions) JSON() bool {
+	return o.format == OutputFormatJSON
+}
+
+// PrintJSON writes given value to w as indented JSON.
+func PrintJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,104 @@
+package cmdutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// SavedSelectorsFile is the name of the file in factory's ConfigDir named
+// selectors are saved in.
+const SavedSelectorsFile = "selectors.yaml"
+
+// SavedSelector is a named combination of selectors, which can be referred to
+// instead of repeating the same selector flags for every command.
+type SavedSelector struct {
+	LabelSelector string `yaml:"selector,omitempty"`
+	FieldSelector string `yaml:"fieldSelector,omitempty"`
+	Query         string `yaml:"query,omitempty"`
+}
+
+// ToConfig returns a `ctl.SelectorConfig` with the saved selectors.
+func (s SavedSelector) ToConfig() *ctl.SelectorConfig {
+	return &ctl.SelectorConfig{
+		LabelSelector: s.LabelSelector,
+		FieldSelector: s.FieldSelector,
+		Query:         s.Query,
+	}
+}
+
+// SavedSelectors is the content of the saved selectors file, e.g.:
+//
+//	selectors:
+//	  gorilla:
+//	    selector: managed-by=cluster-operator,installation=gorilla
+//	    fieldSelector: ownerTeam/name=team-rocket
+type SavedSelectors struct {
+	Selectors map[string]SavedSelector `yaml:"selectors"`
+}
+
+// LoadSavedSelectors reads saved selectors from given file. A missing file is
+// treated as having no saved selectors.
+func LoadSavedSelectors(path string) (*SavedSelectors, error) {
+	ret := &SavedSelectors{Selectors: map[string]SavedSelector{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, ret); err != nil {
+		return nil, fmt.Errorf("failed to parse saved selectors from %s: %w", path, err)
+	}
+	if ret.Selectors == nil {
+		ret.Selectors = map[string]SavedSelector{}
+	}
+
+	return ret, nil
+}
+
+// Save writes the saved selectors to given file, creating its directory if
+// necessary.
+func (s *SavedSelectors) Save(path string) error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Names returns names of saved selectors, sorted.
+func (s *SavedSelectors) Names() []string {
+	ret := make([]string, 0, len(s.Selectors))
+	for name := range s.Selectors {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Get returns the saved selector with given name, or a UsageError if there's
+// no such selector.
+func (s *SavedSelectors) Get(name string) (SavedSelector, error) {
+	selector, ok := s.Selectors[name]
+	if !ok {
+		return selector, UsageErrorf("saved selector %q not found", name)
+	}
+	return selector, nil
+}
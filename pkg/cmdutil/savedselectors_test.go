@@ -0,0 +1,64 @@
+package cmdutil_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+)
+
+var _ = Describe("SavedSelectors", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "heartbeatctl", cmdutil.SavedSelectorsFile)
+	})
+
+	It("treats a missing file as having no saved selectors", func() {
+		saved, err := cmdutil.LoadSavedSelectors(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(saved.Names()).To(BeEmpty())
+	})
+
+	It("reads selectors written by hand", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(`
+selectors:
+  gorilla:
+    selector: managed-by=cluster-operator,installation=gorilla
+    fieldSelector: ownerTeam/name=team-rocket
+  urgent:
+    query: alertPriority in (P1, P2)
+`), 0600)).To(Succeed())
+
+		saved, err := cmdutil.LoadSavedSelectors(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(saved.Names()).To(Equal([]string{"gorilla", "urgent"}))
+		Expect(saved.Get("gorilla")).To(Equal(cmdutil.SavedSelector{
+			LabelSelector: "managed-by=cluster-operator,installation=gorilla",
+			FieldSelector: "ownerTeam/name=team-rocket",
+		}))
+	})
+
+	It("saves selectors creating the directory", func() {
+		saved := &cmdutil.SavedSelectors{Selectors: map[string]cmdutil.SavedSelector{
+			"urgent": {Query: "alertPriority=P1"},
+		}}
+		Expect(saved.Save(path)).To(Succeed())
+
+		loaded, err := cmdutil.LoadSavedSelectors(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(saved))
+	})
+
+	It("reports malformed files", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("selectors: [nope"), 0600)).To(Succeed())
+
+		_, err := cmdutil.LoadSavedSelectors(path)
+		Expect(err).To(MatchError(ContainSubstring("failed to parse saved selectors")))
+	})
+})
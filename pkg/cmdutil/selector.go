@@ -1,6 +1,9 @@
 package cmdutil
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/spf13/cobra"
 
 	"github.com/giantswarm/heartbeatctl/pkg/ctl"
@@ -14,8 +17,12 @@ type SelectorOptions struct {
 	nameExpressions []string
 	labelSelector   string
 	fieldSelector   string
+	query           string
+	savedSelectors  []string
 
+	savedSelectorsPath        string
 	captureArgsUsingValidator bool
+	completer                 *Completer
 }
 
 func NewSelectorOptions() *SelectorOptions {
@@ -38,11 +45,30 @@ func (so *SelectorOptions) WithCapturingArgsUsingValidator() *SelectorOptions {
 	return so
 }
 
-// AddFlags adds label and field selector flags to given cobra command.
+// WithCompletion configures this SelectorOptions (specifically its AddFlags
+// method) to register dynamic shell completion of selector flags using given
+// Completer, and of heartbeat names if capturing arguments is also enabled.
+func (so *SelectorOptions) WithCompletion(c *Completer) *SelectorOptions {
+	so.completer = c
+	return so
+}
+
+// WithSavedSelectors configures this SelectorOptions (specifically its
+// AddFlags method) to add a flag referring to selectors saved in given file.
+// Saved selectors can also be referred to with name expressions prefixed with
+// '@', e.g. '@gorilla'.
+func (so *SelectorOptions) WithSavedSelectors(path string) *SelectorOptions {
+	so.savedSelectorsPath = path
+	return so
+}
+
+// AddFlags adds label selector, field selector and query flags to given cobra
+// command.
 // If capturing arguments was also previously enabled with a call to
 // WithCapturingArgsUsingValidator, this will also add hook to the command that
 // captures positional arguments and assigns them as name expressions in the
-// selector.
+// selector. If completion was enabled with a call to WithCompletion, this also
+// registers completion functions for the flags and positional arguments.
 func (so *SelectorOptions) AddFlags(cmd *cobra.Command) {
 	flags := cmd.Flags()
 	flags.StringVarP(
@@ -51,14 +77,35 @@ func (so *SelectorOptions) AddFlags(cmd *cobra.Command) {
 	)
 	flags.StringVar(
 		&so.fieldSelector, "field-selector", so.fieldSelector,
-		"Selector (field query) to filter characters, supports '=', '==', '!='.",
+		"Selector (field query) to filter characters, supports '=', '==', '!=', 'in', 'notin', '=~', '!~', '>', '>=', '<', '<='.",
 	)
 
+	flags.StringVar(
+		&so.query, "query", so.query,
+		"Query expression to filter characters, combining comparisons of fields and labels with 'and', 'or', 'not' and parentheses.",
+	)
+
+	if so.savedSelectorsPath != "" {
+		flags.StringSliceVar(
+			&so.savedSelectors, "saved-selector", so.savedSelectors,
+			"Name of a saved selector to filter characters, can be repeated to combine multiple saved selectors.",
+		)
+		_ = cmd.RegisterFlagCompletionFunc("saved-selector", so.completeSavedSelectors)
+	}
+
+	if so.completer != nil {
+		_ = cmd.RegisterFlagCompletionFunc("selector", so.completer.LabelSelector)
+		_ = cmd.RegisterFlagCompletionFunc("field-selector", so.completer.FieldSelector)
+	}
+
 	if !so.captureArgsUsingValidator {
 		return
 	}
 
 	cmd.Args = so.argsCapturingValidator()
+	if so.completer != nil {
+		cmd.ValidArgsFunction = so.completeArgs
+	}
 }
 
 // NameExpressions assigns given names as name expressions to use for filtering
@@ -69,13 +116,95 @@ func (so *SelectorOptions) NameExpressions(names ...string) *SelectorOptions {
 }
 
 // ToConfig takes values populated by CLI flags and produces a `SelectorConfig`
-// that can be used with `ctl` app Port methods.
-func (so *SelectorOptions) ToConfig() *ctl.SelectorConfig {
-	return &ctl.SelectorConfig{
-		NameExpressions: so.nameExpressions,
-		LabelSelector:   so.labelSelector,
-		FieldSelector:   so.fieldSelector,
+// that can be used with `ctl` app Port methods. Any referenced saved selectors
+// are merged with selectors given by flags, so heartbeats must match all of
+// them. It returns a UsageError if a saved selector doesn't exist.
+func (so *SelectorOptions) ToConfig() (*ctl.SelectorConfig, error) {
+	cfg := &ctl.SelectorConfig{
+		LabelSelector: so.labelSelector,
+		FieldSelector: so.fieldSelector,
+		Query:         so.query,
+	}
+
+	names := so.savedSelectors
+	for _, expr := range so.nameExpressions {
+		if name, ok := strings.CutPrefix(expr, "@"); ok && so.savedSelectorsPath != "" {
+			names = append(names, name)
+		} else {
+			cfg.NameExpressions = append(cfg.NameExpressions, expr)
+		}
+	}
+	if len(names) == 0 {
+		return cfg, nil
+	}
+
+	saved, err := LoadSavedSelectors(so.savedSelectorsPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		selector, err := saved.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		mergeSelectorConfig(cfg, selector.ToConfig())
+	}
+
+	return cfg, nil
+}
+
+// mergeSelectorConfig adds selectors from src to dst, so that objects must
+// match selectors of both.
+func mergeSelectorConfig(dst, src *ctl.SelectorConfig) {
+	join := func(a, b, sep string) string {
+		switch {
+		case a == "":
+			return b
+		case b == "":
+			return a
+		default:
+			return a + sep + b
+		}
+	}
+
+	dst.LabelSelector = join(dst.LabelSelector, src.LabelSelector, ",")
+	dst.FieldSelector = join(dst.FieldSelector, src.FieldSelector, ",")
+	if dst.Query != "" && src.Query != "" {
+		dst.Query = fmt.Sprintf("(%s) and (%s)", dst.Query, src.Query)
+	} else {
+		dst.Query = join(dst.Query, src.Query, "")
+	}
+}
+
+// completeSavedSelectors completes names of saved selectors.
+func (so *SelectorOptions) completeSavedSelectors(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	saved, err := LoadSavedSelectors(so.savedSelectorsPath)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var names []string
+	for _, name := range saved.Names() {
+		if strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeArgs completes heartbeat names, or saved selector names prefixed
+// with '@'.
+func (so *SelectorOptions) completeArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	name, ok := strings.CutPrefix(toComplete, "@")
+	if !ok || so.savedSelectorsPath == "" {
+		return so.completer.HeartbeatNames(cmd, args, toComplete)
+	}
+
+	names, directive := so.completeSavedSelectors(cmd, args, name)
+	for i := range names {
+		names[i] = "@" + names[i]
 	}
+	return names, directive
 }
 
 func (so *SelectorOptions) argsCapturingValidator() cobra.PositionalArgs {
@@ -2,6 +2,7 @@ package cmdutil_test
 
 import (
 	"bytes"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -9,6 +10,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
 )
 
 var _ = Describe("Selector", func() {
@@ -50,16 +52,19 @@ var _ = Describe("Selector", func() {
 					}, Equal("l")),
 				))
 				Expect(flags.Lookup("field-selector")).NotTo(BeNil())
+				Expect(flags.Lookup("query")).NotTo(BeNil())
 
 				By("parsing some flags and arguments")
 				Expect(execute([]string{
-					"--selector=enabled", "--field-selector=alertPriority=P2", "foo", "bar.*",
+					"--selector=enabled", "--field-selector=alertPriority=P2", "--query=not expired", "foo", "bar.*",
 				})).To(Succeed())
-				cfg := opts.ToConfig()
+				cfg, err := opts.ToConfig()
+				Expect(err).NotTo(HaveOccurred())
 
 				By("checking selector options are populated but positional args are not captured by default")
 				Expect(cfg.LabelSelector).To(Equal("enabled"))
 				Expect(cfg.FieldSelector).To(Equal("alertPriority=P2"))
+				Expect(cfg.Query).To(Equal("not expired"))
 				Expect(cfg.NameExpressions).To(BeEmpty())
 			})
 
@@ -68,7 +73,9 @@ var _ = Describe("Selector", func() {
 				opts.NameExpressions("foo", "bar.*")
 
 				By("checking they can be found in resulting config")
-				Expect(opts.ToConfig().NameExpressions).To(ConsistOf("foo", "bar.*"))
+				cfg, err := opts.ToConfig()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfg.NameExpressions).To(ConsistOf("foo", "bar.*"))
 			})
 		})
 
@@ -86,11 +93,62 @@ var _ = Describe("Selector", func() {
 				Expect(execute([]string{
 					"--selector=enabled", "--field-selector=alertPriority=P2", "foo", "bar.*",
 				})).To(Succeed())
-				cfg := opts.ToConfig()
+				cfg, err := opts.ToConfig()
+				Expect(err).NotTo(HaveOccurred())
 
 				By("checking positional args are captured")
 				Expect(cfg.NameExpressions).To(ConsistOf("foo", "bar.*"))
 			})
 		})
+
+		When("saved selectors are enabled", func() {
+			var path string
+
+			BeforeEach(func() {
+				path = filepath.Join(GinkgoT().TempDir(), cmdutil.SavedSelectorsFile)
+				saved := &cmdutil.SavedSelectors{Selectors: map[string]cmdutil.SavedSelector{
+					"gorilla": {LabelSelector: "installation=gorilla", FieldSelector: "ownerTeam/name=team-rocket"},
+					"urgent":  {LabelSelector: "enabled", Query: "alertPriority=P1"},
+				}}
+				Expect(saved.Save(path)).To(Succeed())
+
+				opts.WithCapturingArgsUsingValidator().WithSavedSelectors(path)
+			})
+
+			It("merges saved selectors referred to by flag with other flags", func() {
+				Expect(execute([]string{
+					"--saved-selector=gorilla", "-l", "managed-by=cluster-operator", "--query", "not expired", "foo",
+				})).To(Succeed())
+
+				cfg, err := opts.ToConfig()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfg).To(Equal(&ctl.SelectorConfig{
+					NameExpressions: []string{"foo"},
+					LabelSelector:   "managed-by=cluster-operator,installation=gorilla",
+					FieldSelector:   "ownerTeam/name=team-rocket",
+					Query:           "not expired",
+				}))
+			})
+
+			It("merges multiple saved selectors referred to by '@' arguments", func() {
+				Expect(execute([]string{"@gorilla", "--query", "interval>5", "@urgent"})).To(Succeed())
+
+				cfg, err := opts.ToConfig()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cfg).To(Equal(&ctl.SelectorConfig{
+					LabelSelector: "installation=gorilla,enabled",
+					FieldSelector: "ownerTeam/name=team-rocket",
+					Query:         "(interval>5) and (alertPriority=P1)",
+				}))
+			})
+
+			It("fails with a usage error for unknown saved selectors", func() {
+				Expect(execute([]string{"@nope"})).To(Succeed())
+
+				_, err := opts.ToConfig()
+				Expect(err).To(MatchError(`saved selector "nope" not found`))
+				Expect(cmdutil.ExitCode(err)).To(Equal(cmdutil.ExitUsageError))
+			})
+		})
 	})
 })
@@ -0,0 +1,35 @@
+package cmdutil_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/giantswarm/heartbeatctl/pkg/cmdutil"
+)
+
+var _ = Describe("Serve", func() {
+	It("serves until the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			done <- cmdutil.Serve(ctx, "127.0.0.1:0", http.NotFoundHandler())
+		}()
+
+		Consistently(done).ShouldNot(Receive())
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+
+	It("fails if it can't listen", func() {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer l.Close()
+
+		err = cmdutil.Serve(context.Background(), l.Addr().String(), http.NotFoundHandler())
+		Expect(err).To(MatchError(ContainSubstring("address already in use")))
+	})
+})
@@ -0,0 +1,20 @@
+package cmdutil
+
+import (
+	"path/filepath"
+
+	"github.com/giantswarm/heartbeatctl/pkg/spool"
+)
+
+// SpoolFile is the name of the file in factory's StateDir pings that failed
+// because OpsGenie couldn't be reached are kept in.
+const SpoolFile = "spool.jsonl"
+
+// Spool returns the spool pings that couldn't be delivered are kept in, or
+// nil if the state directory is unknown.
+func (f *Factory) Spool() *spool.Spool {
+	if f.StateDir == "" {
+		return nil
+	}
+	return spool.Open(filepath.Join(f.StateDir, SpoolFile))
+}
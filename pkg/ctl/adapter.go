@@ -3,29 +3,35 @@ package ctl
 import (
 	"context"
 	"fmt"
-	"log"
-	"regexp"
+	"reflect"
 	"sort"
-	"strings"
-	"sync"
 
 	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
-	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/labels"
 
 	"github.com/giantswarm/heartbeatctl/pkg/client"
-	"github.com/giantswarm/heartbeatctl/pkg/conv"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
 )
 
 type ctl struct {
-	repo client.Port
+	repo     client.Port
+	guard    Guard
+	recorder Recorder
 }
 
-func NewCtl(r client.Port) Port {
-	return &ctl{repo: r}
+func NewCtl(r client.Port, opts ...Option) Port {
+	c := &ctl{repo: r}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 func (c *ctl) Get(opts *SelectorConfig) ([]heartbeat.Heartbeat, error) {
+	sel, err := compileSelector(opts)
+	if err != nil {
+		return nil, err
+	}
+
 	ret, err := c.repo.List(context.TODO())
 	if err != nil {
 		return nil, err
@@ -33,117 +39,339 @@ func (c *ctl) Get(opts *SelectorConfig) ([]heartbeat.Heartbeat, error) {
 	// As expiry field is incorrect due to OpsGenie API bug,
 	// request each heartbeat individually (in parallel),
 	// which returns the correct expiry data.
-	var wg sync.WaitGroup
-	ch := make(chan heartbeat.Heartbeat)
+	heartbeats, err := c.getEach(ret.Heartbeats)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := []heartbeat.Heartbeat{}
+	for _, h := range heartbeats {
+		if sel.Matches(h) {
+			filtered = append(filtered, h)
+		}
+	}
 
-	for _, hb := range ret.Heartbeats {
-		wg.Add(1)
+	sortByName(filtered)
 
-		go func(hb heartbeat.Heartbeat, ch chan heartbeat.Heartbeat) {
-			newHb, err := c.repo.Get(context.Background(), hb.Name)
-			if err != nil {
-				log.Fatalf("%v\n", err)
-			}
+	return filtered, nil
+}
+
+func (c *ctl) Enable(opts *SelectorConfig) ([]heartbeat.HeartbeatInfo, error) {
+	return c.enableDisableHeartbeats(ActionEnable, c.repo.Enable, opts)
+}
+
+func (c *ctl) Disable(opts *SelectorConfig) ([]heartbeat.HeartbeatInfo, error) {
+	return c.enableDisableHeartbeats(ActionDisable, c.repo.Disable, opts)
+}
 
-			ch <- newHb.Heartbeat
-		}(hb, ch)
+func (c *ctl) Ping(opts *SelectorConfig) (map[string]heartbeat.PingResult, error) {
+	heartbeats, err := c.selectHeartbeats(ActionPing, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	heartbeats := make([]heartbeat.Heartbeat, 0)
-	go func(wg *sync.WaitGroup) {
-		for hb := range ch {
-			heartbeats = append(heartbeats, hb)
-			wg.Done()
+	var pingResults = make(map[string]heartbeat.PingResult)
+	var failedPings = make(map[string]error)
+	var succeeded = make([]string, 0)
+	for _, h := range heartbeats {
+		result, err := c.repo.Ping(context.Background(), h.Name)
+		if err != nil {
+			failedPings[h.Name] = err
+			continue
+		}
+
+		succeeded = append(succeeded, h.Name)
+		if result != nil {
+			pingResults[h.Name] = *result
 		}
-	}(&wg)
+	}
+
+	return pingResults, newHeartbeatsError(failedPings, succeeded)
+}
 
-	wg.Wait()
-	close(ch)
+// enableDisableHeartbeats applies given method (can be either `repo.Enable` or
+// `repo.Disable`) to all heartbeats matched by given selector options, which
+// must be non-empty.
+func (c *ctl) enableDisableHeartbeats(action string, meth func(context.Context, string) (*heartbeat.HeartbeatInfo, error), opts *SelectorConfig) ([]heartbeat.HeartbeatInfo, error) {
+	heartbeats, err := c.selectHeartbeats(action, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(opts.NameExpressions) > 0 {
-		heartbeats, err = filterNames(heartbeats, opts.NameExpressions)
+	var hbInfos []heartbeat.HeartbeatInfo
+	var succeeded []string
+	for _, h := range heartbeats {
+		// TODO: context.TODO
+		hbi, err := meth(context.TODO(), h.Name)
 		if err != nil {
-			return nil, err
+			return hbInfos, newHeartbeatsError(map[string]error{h.Name: err}, succeeded)
 		}
+		hbInfos = append(hbInfos, *hbi)
+		succeeded = append(succeeded, h.Name)
 	}
+	return hbInfos, nil
+}
 
-	ls := labels.Everything()
-	if opts.LabelSelector != "" {
-		ls, err = labels.Parse(opts.LabelSelector)
-		if err != nil {
+func (c *ctl) Plan(action string, opts *SelectorConfig) ([]PlannedAction, error) {
+	var unchanged func(heartbeat.Heartbeat) bool
+	switch action {
+	case ActionEnable:
+		unchanged = func(h heartbeat.Heartbeat) bool { return h.Enabled }
+	case ActionDisable:
+		unchanged = func(h heartbeat.Heartbeat) bool { return !h.Enabled }
+	case ActionPing, ActionDelete:
+		unchanged = func(heartbeat.Heartbeat) bool { return false }
+	default:
+		return nil, fmt.Errorf("unknown action \"%s\"", action)
+	}
+
+	heartbeats, err := c.resolveHeartbeats(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]PlannedAction, 0, len(heartbeats))
+	for _, h := range heartbeats {
+		plan = append(plan, PlannedAction{
+			Action:    action,
+			Heartbeat: h.Name,
+			Unchanged: unchanged(h),
+		})
+	}
+	return plan, nil
+}
+
+func (c *ctl) Restore(heartbeats []heartbeat.Heartbeat) ([]heartbeat.HeartbeatInfo, error) {
+	existing, err := c.existing(heartbeats)
+	if err != nil {
+		return nil, err
+	}
+	current := make([]heartbeat.Heartbeat, 0, len(existing))
+	for _, h := range existing {
+		current = append(current, h)
+	}
+	sortByName(current)
+	targets := append([]heartbeat.Heartbeat(nil), heartbeats...)
+	sortByName(targets)
+
+	// The guard is asked about all heartbeats restored, including those
+	// recreated, while only the current state of existing ones is recorded.
+	if c.guard != nil {
+		if err := c.guard(ActionRestore, targets); err != nil {
 			return nil, err
 		}
 	}
-
-	fs := fields.Everything()
-	if opts.FieldSelector != "" {
-		fs, err = fields.ParseSelector(opts.FieldSelector)
-		if err != nil {
+	if c.recorder != nil {
+		if err := c.recorder(ActionRestore, current); err != nil {
 			return nil, err
 		}
 	}
 
-	filtered := []heartbeat.Heartbeat{}
-	for _, h := range heartbeats {
-		if !ls.Matches(conv.HeartbeatAsLabels(h)) {
-			continue
+	var hbInfos []heartbeat.HeartbeatInfo
+	var succeeded []string
+	for _, h := range targets {
+		var hbi *heartbeat.HeartbeatInfo
+		if _, ok := existing[h.Name]; ok {
+			hbi, err = c.repo.Update(context.TODO(), manifest.FromHeartbeat(h).UpdateRequest())
+		} else {
+			hbi, err = c.add(h)
 		}
+		if err != nil {
+			return hbInfos, newHeartbeatsError(map[string]error{h.Name: err}, succeeded)
+		}
+		hbInfos = append(hbInfos, *hbi)
+		succeeded = append(succeeded, h.Name)
+	}
+	return hbInfos, nil
+}
+
+func (c *ctl) PlanRestore(heartbeats []heartbeat.Heartbeat) ([]PlannedAction, error) {
+	existing, err := c.existing(heartbeats)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := append([]heartbeat.Heartbeat(nil), heartbeats...)
+	sortByName(targets)
 
-		if !fs.Matches(conv.HeartbeatAsFields(h)) {
+	plan := make([]PlannedAction, 0, len(targets))
+	for _, h := range targets {
+		current, ok := existing[h.Name]
+		if !ok {
+			plan = append(plan, PlannedAction{Action: ActionCreate, Heartbeat: h.Name})
 			continue
 		}
+		plan = append(plan, PlannedAction{
+			Action:    ActionRestore,
+			Heartbeat: h.Name,
+			Unchanged: sameState(current, h),
+			Uncleared: manifest.FromHeartbeat(h).Uncleared(current),
+		})
+	}
+	return plan, nil
+}
 
-		filtered = append(filtered, h)
+// existing returns the current state of those of given heartbeats that
+// exist, by name.
+func (c *ctl) existing(heartbeats []heartbeat.Heartbeat) (map[string]heartbeat.Heartbeat, error) {
+	list, err := c.repo.List(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(list.Heartbeats))
+	for _, h := range list.Heartbeats {
+		names[h.Name] = true
 	}
 
-	sort.Slice(filtered, func(i, j int) bool {
-		return filtered[i].Name < filtered[j].Name
-	})
+	var found []heartbeat.Heartbeat
+	for _, h := range heartbeats {
+		if names[h.Name] {
+			found = append(found, h)
+		}
+	}
+	current, err := c.getEach(found)
+	if err != nil {
+		return nil, err
+	}
 
-	return filtered, nil
+	ret := make(map[string]heartbeat.Heartbeat, len(current))
+	for _, h := range current {
+		ret[h.Name] = h
+	}
+	return ret, nil
 }
 
-func (c *ctl) Enable(opts *SelectorConfig) ([]heartbeat.HeartbeatInfo, error) {
-	return c.enableDisableHeartbeats(c.repo.Enable, opts)
+// add creates given heartbeat.
+func (c *ctl) add(h heartbeat.Heartbeat) (*heartbeat.HeartbeatInfo, error) {
+	result, err := c.repo.Add(context.TODO(), manifest.FromHeartbeat(h).AddRequest())
+	if err != nil {
+		return nil, err
+	}
+	return &heartbeat.HeartbeatInfo{
+		Name:    result.Heartbeat.Name,
+		Enabled: result.Heartbeat.Enabled,
+		Expired: result.Heartbeat.Expired,
+	}, nil
 }
 
-func (c *ctl) Disable(opts *SelectorConfig) ([]heartbeat.HeartbeatInfo, error) {
-	return c.enableDisableHeartbeats(c.repo.Disable, opts)
-}
+func (c *ctl) Delete(opts *SelectorConfig) ([]string, error) {
+	heartbeats, err := c.selectHeartbeats(ActionDelete, opts)
+	if err != nil {
+		return nil, err
+	}
 
-func (c *ctl) Ping(opts *SelectorConfig) (map[string]heartbeat.PingResult, error) {
-	if opts.empty() {
-		return nil, ErrNoSelector
+	var deleted []string
+	for _, h := range heartbeats {
+		if _, err := c.repo.Delete(context.TODO(), h.Name); err != nil {
+			return deleted, newHeartbeatsError(map[string]error{h.Name: err}, deleted)
+		}
+		deleted = append(deleted, h.Name)
 	}
+	return deleted, nil
+}
 
-	heartbeats, err := c.Get(opts)
+func (c *ctl) Apply(manifests []manifest.Heartbeat) ([]PlannedAction, error) {
+	plan, existing, err := c.planApply(manifests)
 	if err != nil {
 		return nil, err
 	}
 
-	var pingResults = make(map[string]heartbeat.PingResult)
-	var failedPings = make([]string, 0)
-	for _, h := range heartbeats {
-		result, err := c.repo.Ping(context.Background(), h.Name)
-		if err != nil {
-			failedPings = append(failedPings, h.Name)
+	var targets, updated []heartbeat.Heartbeat
+	for _, a := range plan {
+		switch {
+		case a.Unchanged:
+		case a.Action == ActionUpdate:
+			targets = append(targets, existing[a.Heartbeat])
+			updated = append(updated, existing[a.Heartbeat])
+		default:
+			targets = append(targets, heartbeat.Heartbeat{Name: a.Heartbeat})
 		}
+	}
+	if len(targets) > 0 && c.guard != nil {
+		if err := c.guard(ActionApply, targets); err != nil {
+			return nil, err
+		}
+	}
+	if len(targets) > 0 && c.recorder != nil {
+		if err := c.recorder(ActionApply, updated); err != nil {
+			return nil, err
+		}
+	}
 
-		if result != nil {
-			pingResults[h.Name] = *result
+	byName := make(map[string]manifest.Heartbeat, len(manifests))
+	for _, m := range manifests {
+		byName[m.Metadata.Name] = m
+	}
+
+	applied := make([]PlannedAction, 0, len(plan))
+	var succeeded []string
+	for _, a := range plan {
+		if !a.Unchanged {
+			var err error
+			if a.Action == ActionCreate {
+				_, err = c.repo.Add(context.TODO(), byName[a.Heartbeat].AddRequest())
+			} else {
+				_, err = c.repo.Update(context.TODO(), byName[a.Heartbeat].UpdateRequest())
+			}
+			if err != nil {
+				return applied, newHeartbeatsError(map[string]error{a.Heartbeat: err}, succeeded)
+			}
+		}
+		applied = append(applied, a)
+		succeeded = append(succeeded, a.Heartbeat)
+	}
+	return applied, nil
+}
+
+func (c *ctl) PlanApply(manifests []manifest.Heartbeat) ([]PlannedAction, error) {
+	plan, _, err := c.planApply(manifests)
+	return plan, err
+}
+
+// planApply returns actions applying given manifests, sorted by heartbeat
+// name, along with existing heartbeats by name.
+func (c *ctl) planApply(manifests []manifest.Heartbeat) ([]PlannedAction, map[string]heartbeat.Heartbeat, error) {
+	names := make(map[string]bool, len(manifests))
+	for _, m := range manifests {
+		if names[m.Metadata.Name] {
+			return nil, nil, fmt.Errorf("heartbeat \"%s\" is defined more than once", m.Metadata.Name)
 		}
+		names[m.Metadata.Name] = true
+	}
+
+	list, err := c.repo.List(context.TODO())
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := make(map[string]heartbeat.Heartbeat, len(list.Heartbeats))
+	for _, h := range list.Heartbeats {
+		existing[h.Name] = h
 	}
 
-	if len(failedPings) > 0 {
-		return pingResults, fmt.Errorf("heartbeats \"%v\" failed", failedPings)
+	plan := make([]PlannedAction, 0, len(manifests))
+	for _, m := range manifests {
+		h, ok := existing[m.Metadata.Name]
+		if !ok {
+			plan = append(plan, PlannedAction{Action: ActionCreate, Heartbeat: m.Metadata.Name})
+			continue
+		}
+		plan = append(plan, PlannedAction{
+			Action:    ActionUpdate,
+			Heartbeat: m.Metadata.Name,
+			Unchanged: m.Matches(h),
+			Uncleared: m.Uncleared(h),
+		})
 	}
-	return pingResults, nil
+	sort.Slice(plan, func(i, j int) bool {
+		return plan[i].Heartbeat < plan[j].Heartbeat
+	})
+
+	return plan, existing, nil
 }
 
-// enableDisableHeartbeats applies given method (can be either `repo.Enable` or
-// `repo.Disable`) to all heartbeats matched by given selector options, which
-// must be non-empty.
-func (c *ctl) enableDisableHeartbeats(meth func(context.Context, string) (*heartbeat.HeartbeatInfo, error), opts *SelectorConfig) ([]heartbeat.HeartbeatInfo, error) {
+// resolveHeartbeats returns heartbeats matched by given selector options,
+// which must be non-empty and must match at least one heartbeat.
+func (c *ctl) resolveHeartbeats(opts *SelectorConfig) ([]heartbeat.Heartbeat, error) {
 	if opts.empty() {
 		return nil, ErrNoSelector
 	}
@@ -152,30 +380,96 @@ func (c *ctl) enableDisableHeartbeats(meth func(context.Context, string) (*heart
 	if err != nil {
 		return nil, err
 	}
-
-	var hbInfos []heartbeat.HeartbeatInfo
-	for _, h := range heartbeats {
-		// TODO: context.TODO
-		hbi, err := meth(context.TODO(), h.Name)
-		if err != nil {
-			return hbInfos, fmt.Errorf("heartbeat \"%s\" failed: %w", h.Name, err)
-		}
-		hbInfos = append(hbInfos, *hbi)
+	if len(heartbeats) == 0 {
+		return nil, ErrNoMatch
 	}
-	return hbInfos, nil
+
+	return heartbeats, nil
 }
 
-func filterNames(heartbeats []heartbeat.Heartbeat, nameExpressions []string) ([]heartbeat.Heartbeat, error) {
-	expr, err := regexp.Compile(fmt.Sprintf("^(%s)$", strings.Join(nameExpressions, "|")))
+// selectHeartbeats returns heartbeats matched by given selector options, which
+// must be non-empty and must match at least one heartbeat, to apply given
+// action to. The action is applied only if the configured guard allows it.
+func (c *ctl) selectHeartbeats(action string, opts *SelectorConfig) ([]heartbeat.Heartbeat, error) {
+	heartbeats, err := c.resolveHeartbeats(opts)
 	if err != nil {
 		return nil, err
 	}
 
-	filtered := []heartbeat.Heartbeat{}
-	for _, h := range heartbeats {
-		if expr.MatchString(h.Name) {
-			filtered = append(filtered, h)
+	if err := c.approve(action, heartbeats); err != nil {
+		return nil, err
+	}
+
+	return heartbeats, nil
+}
+
+// approve asks the configured guard whether given action can be applied to
+// given heartbeats and, if so, passes them to the configured recorder.
+func (c *ctl) approve(action string, heartbeats []heartbeat.Heartbeat) error {
+	if c.guard != nil {
+		if err := c.guard(action, heartbeats); err != nil {
+			return err
 		}
 	}
-	return filtered, nil
+	if c.recorder != nil {
+		if err := c.recorder(action, heartbeats); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEach requests each of given heartbeats individually (in parallel) and
+// returns the results, or the first error encountered.
+func (c *ctl) getEach(heartbeats []heartbeat.Heartbeat) ([]heartbeat.Heartbeat, error) {
+	type result struct {
+		heartbeat heartbeat.Heartbeat
+		err       error
+	}
+
+	ch := make(chan result)
+	for _, hb := range heartbeats {
+		go func(name string) {
+			newHb, err := c.repo.Get(context.Background(), name)
+			if err != nil {
+				ch <- result{err: fmt.Errorf("heartbeat \"%s\" failed: %w", name, err)}
+				return
+			}
+			ch <- result{heartbeat: newHb.Heartbeat}
+		}(hb.Name)
+	}
+
+	var firstErr error
+	ret := make([]heartbeat.Heartbeat, 0, len(heartbeats))
+	for range heartbeats {
+		r := <-ch
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			continue
+		}
+		ret = append(ret, r.heartbeat)
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return ret, nil
+}
+
+// sameState returns true if given heartbeats have the same state that can be
+// set with an update, i.e. ignoring whether they are expired.
+func sameState(a, b heartbeat.Heartbeat) bool {
+	a.Expired, b.Expired = false, false
+	if len(a.AlertTags) == 0 && len(b.AlertTags) == 0 {
+		a.AlertTags, b.AlertTags = nil, nil
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func sortByName(heartbeats []heartbeat.Heartbeat) {
+	sort.Slice(heartbeats, func(i, j int) bool {
+		return heartbeats[i].Name < heartbeats[j].Name
+	})
 }
@@ -195,6 +195,28 @@ var _ = Describe("Adapter", func() {
 				)
 			})
 
+			DescribeTable(
+				"fails with ErrNoMatch when selectors match no heartbeats",
+				func(methodName string) {
+					var err error
+					opts := &ctl.SelectorConfig{NameExpressions: []string{"nope"}}
+
+					switch methodName {
+					case EnableMethodName:
+						_, err = adapter.Enable(opts)
+					case DisableMethodName:
+						_, err = adapter.Disable(opts)
+					case PingMethodName:
+						_, err = adapter.Ping(opts)
+					}
+
+					Expect(err).To(MatchError(ctl.ErrNoMatch))
+				},
+				Entry(EnableMethodName, EnableMethodName),
+				Entry(DisableMethodName, DisableMethodName),
+				Entry(PingMethodName, PingMethodName),
+			)
+
 			// AssertMethodCalledOnSelectedHeartbeats asserts method `$name` is
 			// called on all heartbeats that should be matched by some selector.
 			AssertMethodCalledOnSelectedHeartbeats := func(methodName string) {
@@ -289,6 +311,11 @@ var _ = Describe("Adapter", func() {
 							),
 						))
 
+						var hbErr *ctl.HeartbeatsError
+						Expect(errors.As(err, &hbErr)).To(BeTrue())
+						Expect(hbErr.Failed).To(Equal([]string{"foo-oof1"}))
+						Expect(hbErr.Succeeded).To(Equal([]string{"foo"}))
+
 						By("ensuring we also get info about the heartbeat that succeeded")
 
 						Expect(hbInfos).To(Equal([]heartbeat.HeartbeatInfo{*fooHbi}))
@@ -348,14 +375,18 @@ var _ = Describe("Adapter", func() {
 					By("ensuring we get an error")
 
 					Expect(err).To(SatisfyAll(
-						MatchError(fmt.Errorf("heartbeats \"[foo-oof1]\" failed")),
-						// assert that error tells us which heartbeat caused the error
-						WithTransform(
-							func(e error) string { return e.Error() },
-							ContainSubstring("foo-oof1"),
-						),
+						MatchError(apiErr),
+						MatchError("heartbeat \"foo-oof1\" failed: API call failed"),
 					))
 
+					By("ensuring the error carries names of failed and succeeded heartbeats")
+
+					var hbErr *ctl.HeartbeatsError
+					Expect(errors.As(err, &hbErr)).To(BeTrue())
+					Expect(hbErr.Failed).To(Equal([]string{"foo-oof1"}))
+					Expect(hbErr.Succeeded).To(ConsistOf("foo", "foo-rab1"))
+					Expect(hbErr.Partial()).To(BeTrue())
+
 					By("ensuring we also get info about the heartbeat that succeeded")
 
 					Expect(pingResults).To(Equal(map[string]heartbeat.PingResult{
@@ -410,6 +441,23 @@ var _ = Describe("Adapter", func() {
 			AssertMethodPropagatesError(PingMethodName)
 		})
 
+		When("selectors are malformed", func() {
+			DescribeTable(
+				"fails with an InvalidSelectorError without calling the repo",
+				func(opts *ctl.SelectorConfig, selector string) {
+					_, err := adapter.Get(opts)
+
+					var selErr *ctl.InvalidSelectorError
+					Expect(errors.As(err, &selErr)).To(BeTrue())
+					Expect(selErr.Selector).To(Equal(selector))
+				},
+				Entry("label selector", &ctl.SelectorConfig{LabelSelector: "in in"}, "in in"),
+				Entry("field selector", &ctl.SelectorConfig{FieldSelector: "alertPriority~P3"}, "alertPriority~P3"),
+				Entry("query", &ctl.SelectorConfig{Query: "name=foo or"}, "name=foo or"),
+				Entry("name expressions", &ctl.SelectorConfig{NameExpressions: []string{"foo", "(bar"}}, "foo (bar"),
+			)
+		})
+
 		When("no selectors are given", func() {
 			var methods map[string]func(*ctl.SelectorConfig) ([]heartbeat.HeartbeatInfo, error)
 
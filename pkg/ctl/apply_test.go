@@ -0,0 +1,114 @@
+package ctl_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+var _ = Describe("Apply", func() {
+	var (
+		repo      *fake.Client
+		guarded   []string
+		recorded  []heartbeat.Heartbeat
+		adapter   ctl.Port
+		manifests []manifest.Heartbeat
+	)
+
+	BeforeEach(func() {
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Interval: 10, IntervalUnit: "minutes", Enabled: true},
+			heartbeat.Heartbeat{Name: "bar", Interval: 5, IntervalUnit: "minutes"},
+			heartbeat.Heartbeat{Name: "baz", Interval: 1, IntervalUnit: "hours"},
+		)
+		guarded, recorded = nil, nil
+		adapter = ctl.NewCtl(repo,
+			ctl.WithGuard(func(action string, heartbeats []heartbeat.Heartbeat) error {
+				Expect(action).To(Equal(ctl.ActionApply))
+				for _, h := range heartbeats {
+					guarded = append(guarded, h.Name)
+				}
+				return nil
+			}),
+			ctl.WithRecorder(func(action string, heartbeats []heartbeat.Heartbeat) error {
+				Expect(action).To(Equal(ctl.ActionApply))
+				recorded = append(recorded, heartbeats...)
+				return nil
+			}),
+		)
+
+		manifests = []manifest.Heartbeat{
+			manifest.FromHeartbeat(heartbeat.Heartbeat{Name: "foo", Interval: 10, IntervalUnit: "minutes", Enabled: true}),
+			manifest.FromHeartbeat(heartbeat.Heartbeat{Name: "bar", Interval: 30, IntervalUnit: "minutes"}),
+			manifest.FromHeartbeat(heartbeat.Heartbeat{Name: "new", Interval: 2, IntervalUnit: "days"}),
+		}
+	})
+
+	It("creates missing heartbeats and updates changed ones", func() {
+		applied, err := adapter.Apply(manifests)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionUpdate, Heartbeat: "bar"},
+			{Action: ctl.ActionUpdate, Heartbeat: "foo", Unchanged: true},
+			{Action: ctl.ActionCreate, Heartbeat: "new"},
+		}))
+
+		Expect(repo.Heartbeats()).To(Equal([]heartbeat.Heartbeat{
+			{Name: "bar", Interval: 30, IntervalUnit: "minutes"},
+			{Name: "baz", Interval: 1, IntervalUnit: "hours"},
+			{Name: "foo", Interval: 10, IntervalUnit: "minutes", Enabled: true},
+			{Name: "new", Interval: 2, IntervalUnit: "days", AlertPriority: fake.DefaultAlertPriority, AlertMessage: fake.DefaultAlertMessage("new")},
+		}))
+	})
+
+	It("asks the guard about changed heartbeats and records updated ones", func() {
+		_, err := adapter.Apply(manifests)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(guarded).To(Equal([]string{"bar", "new"}))
+		Expect(recorded).To(Equal([]heartbeat.Heartbeat{
+			{Name: "bar", Interval: 5, IntervalUnit: "minutes"},
+		}))
+	})
+
+	It("plans without changing heartbeats", func() {
+		before := repo.Heartbeats()
+
+		plan, err := adapter.PlanApply(manifests)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan).To(HaveLen(3))
+		Expect(repo.Heartbeats()).To(Equal(before))
+		Expect(guarded).To(BeEmpty())
+		Expect(recorded).To(BeEmpty())
+	})
+
+	It("leaves enabled state as is unless the manifest sets it", func() {
+		manifests[0].Spec.Enabled = nil
+		manifests[0].Spec.Interval = 20
+
+		_, err := adapter.Apply(manifests[:1])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()[2]).To(Equal(heartbeat.Heartbeat{Name: "foo", Interval: 20, IntervalUnit: "minutes", Enabled: true}))
+	})
+
+	It("rejects manifests defining a heartbeat more than once", func() {
+		_, err := adapter.Apply(append(manifests, manifests[0]))
+		Expect(err).To(MatchError(`heartbeat "foo" is defined more than once`))
+	})
+
+	It("stops at the first heartbeat that fails", func() {
+		repo.Fail("Add", "new", errors.New("boom"))
+
+		applied, err := adapter.Apply(manifests)
+		var hbErr *ctl.HeartbeatsError
+		Expect(errors.As(err, &hbErr)).To(BeTrue())
+		Expect(hbErr.Failed).To(Equal([]string{"new"}))
+		Expect(hbErr.Succeeded).To(Equal([]string{"bar", "foo"}))
+		Expect(applied).To(HaveLen(2))
+	})
+})
@@ -1,9 +1,90 @@
 package ctl
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
 
 // ErrNoSelector is an error returned when a particular method requires a
 // non-empty selector but none was given.
 var ErrNoSelector = errors.New(
 	"no selector options given, to target all heartbeats pass '.*' name expression explicitly",
 )
+
+// ErrNoMatch is an error returned when a method operating on selected
+// heartbeats was given selectors that didn't match any heartbeat.
+var ErrNoMatch = errors.New("no heartbeats matched given selectors")
+
+// ErrUnscopedPrune is an error returned when Sync is asked to prune without
+// a target prefix or selector limiting which heartbeats could be deleted.
+var ErrUnscopedPrune = errors.New(
+	"pruning requires a target prefix or selector, otherwise all heartbeats of the target account are in scope",
+)
+
+// InvalidSelectorError is returned when one of the given selectors or name
+// expressions cannot be parsed.
+type InvalidSelectorError struct {
+	// Selector is the selector that failed to parse.
+	Selector string
+	Err      error
+}
+
+func (e *InvalidSelectorError) Error() string {
+	return fmt.Sprintf("invalid selector \"%s\": %v", e.Selector, e.Err)
+}
+
+func (e *InvalidSelectorError) Unwrap() error {
+	return e.Err
+}
+
+// HeartbeatsError is returned when an operation failed for some or all of the
+// selected heartbeats, and carries names of heartbeats that failed along with
+// names of those that were processed successfully.
+type HeartbeatsError struct {
+	// Failed holds names of heartbeats the operation failed for, sorted.
+	Failed []string
+	// Succeeded holds names of heartbeats the operation succeeded for.
+	Succeeded []string
+	// Errors holds the error returned for each failed heartbeat.
+	Errors map[string]error
+}
+
+// newHeartbeatsError returns a HeartbeatsError for given failures, or nil if
+// there were none.
+func newHeartbeatsError(errs map[string]error, succeeded []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	failed := make([]string, 0, len(errs))
+	for name := range errs {
+		failed = append(failed, name)
+	}
+	sort.Strings(failed)
+
+	return &HeartbeatsError{Failed: failed, Succeeded: succeeded, Errors: errs}
+}
+
+func (e *HeartbeatsError) Error() string {
+	if len(e.Failed) == 1 {
+		return fmt.Sprintf("heartbeat \"%s\" failed: %v", e.Failed[0], e.Errors[e.Failed[0]])
+	}
+	return fmt.Sprintf("heartbeats \"%v\" failed: %v", e.Failed, e.Errors[e.Failed[0]])
+}
+
+// Unwrap returns errors of all failed heartbeats, so they can be inspected
+// with `errors.Is` and `errors.As`.
+func (e *HeartbeatsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, name := range e.Failed {
+		errs = append(errs, e.Errors[name])
+	}
+	return errs
+}
+
+// Partial returns true if the operation succeeded for at least some of the
+// heartbeats.
+func (e *HeartbeatsError) Partial() bool {
+	return len(e.Succeeded) > 0
+}
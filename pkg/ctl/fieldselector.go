@@ -0,0 +1,314 @@
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"k8s.io/apimachinery/pkg/fields"
+
+	"github.com/giantswarm/heartbeatctl/pkg/conv"
+)
+
+// Operators supported by field selectors, in addition to the equality-based
+// operators of K8s field selectors.
+const (
+	fieldOpEquals       = "="
+	fieldOpDoubleEquals = "=="
+	fieldOpNotEquals    = "!="
+	fieldOpMatches      = "=~"
+	fieldOpNotMatches   = "!~"
+	fieldOpGreater      = ">"
+	fieldOpGreaterEqual = ">="
+	fieldOpLess         = "<"
+	fieldOpLessEqual    = "<="
+	fieldOpIn           = "in"
+	fieldOpNotIn        = "notin"
+)
+
+// fieldSymbolOperators lists symbolic operators, longer ones first so they
+// take precedence over their prefixes.
+var fieldSymbolOperators = []string{
+	fieldOpDoubleEquals, fieldOpNotEquals, fieldOpMatches, fieldOpNotMatches,
+	fieldOpGreaterEqual, fieldOpLessEqual,
+	fieldOpEquals, fieldOpGreater, fieldOpLess,
+}
+
+// numericFields lists fields that can be used with numeric comparisons.
+var numericFields = map[string]bool{
+	"interval": true,
+}
+
+// knownFields lists fields heartbeats can be selected by.
+var knownFields = func() map[string]bool {
+	ret := map[string]bool{}
+	for field := range conv.HeartbeatAsFields(heartbeat.Heartbeat{}) {
+		ret[field] = true
+	}
+	return ret
+}()
+
+// fieldSelector is a parsed field selector, all of whose requirements must
+// match for a heartbeat to be selected.
+type fieldSelector []fieldRequirement
+
+// fieldRequirement is a single requirement of a field selector, like
+// `interval>10` or `alertPriority in (P1, P2)`.
+type fieldRequirement struct {
+	field  string
+	op     string
+	values []string
+	regex  *regexp.Regexp
+	number float64
+}
+
+// parseFieldSelector parses a field selector. Besides requirements supported
+// by `fields.ParseSelector`, i.e. '=', '==' and '!=', it supports set-based
+// requirements using 'in' and 'notin', regular expression matches using '=~'
+// and '!~' which must match the entire value, and numeric comparisons using
+// '>', '>=', '<' and '<=' for numeric fields.
+func parseFieldSelector(selector string) (fieldSelector, error) {
+	parts, err := splitFieldRequirements(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret fieldSelector
+	for _, part := range parts {
+		r, err := parseFieldRequirement(part)
+		if err != nil && len(parts) > 1 {
+			return nil, fmt.Errorf("requirement %q: %w", strings.TrimSpace(part), err)
+		} else if err != nil {
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+
+	return ret, nil
+}
+
+// Matches returns true if given field set satisfies all requirements.
+func (s fieldSelector) Matches(fs fields.Fields) bool {
+	for _, r := range s {
+		if !r.Matches(fs) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches returns true if given field set satisfies the requirement.
+func (r fieldRequirement) Matches(fs fields.Fields) bool {
+	value := fs.Get(r.field)
+
+	switch r.op {
+	case fieldOpEquals, fieldOpDoubleEquals:
+		return value == r.values[0]
+	case fieldOpNotEquals:
+		return value != r.values[0]
+	case fieldOpMatches:
+		return r.regex.MatchString(value)
+	case fieldOpNotMatches:
+		return !r.regex.MatchString(value)
+	case fieldOpIn:
+		return r.has(value)
+	case fieldOpNotIn:
+		return !r.has(value)
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+	switch r.op {
+	case fieldOpGreater:
+		return number > r.number
+	case fieldOpGreaterEqual:
+		return number >= r.number
+	case fieldOpLess:
+		return number < r.number
+	case fieldOpLessEqual:
+		return number <= r.number
+	default:
+		return false
+	}
+}
+
+func (r fieldRequirement) has(value string) bool {
+	for _, v := range r.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// splitFieldRequirements splits a selector on commas that are neither escaped
+// with a backslash nor nested in brackets, as used by sets and regular
+// expressions.
+func splitFieldRequirements(selector string) ([]string, error) {
+	var (
+		parts  []string
+		depth  int
+		start  int
+		escape bool
+	)
+
+	for i, c := range selector {
+		switch {
+		case escape:
+			escape = false
+		case c == '\\':
+			escape = true
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected %q at position %d", c, i+1)
+			}
+		case c == ',' && depth == 0:
+			parts = append(parts, selector[start:i])
+			start = i + 1
+		}
+	}
+	if depth > 0 {
+		return nil, errors.New("unclosed bracket")
+	}
+	parts = append(parts, selector[start:])
+
+	for i, part := range parts {
+		if strings.TrimSpace(part) == "" {
+			return nil, fmt.Errorf("requirement %d is empty", i+1)
+		}
+	}
+
+	return parts, nil
+}
+
+func parseFieldRequirement(s string) (fieldRequirement, error) {
+	s = strings.TrimSpace(s)
+
+	end := strings.IndexFunc(s, func(c rune) bool {
+		return !isFieldNameChar(c)
+	})
+	if end < 0 {
+		return fieldRequirement{}, errors.New("missing operator")
+	}
+	if end == 0 {
+		return fieldRequirement{}, errors.New("missing field name")
+	}
+
+	r := fieldRequirement{field: s[:end]}
+	if !knownFields[r.field] {
+		return r, fmt.Errorf("unknown field %q, must be one of %s", r.field, strings.Join(sortedKeys(knownFields), ", "))
+	}
+
+	rest := s[end:]
+	for _, op := range fieldSymbolOperators {
+		if strings.HasPrefix(rest, op) {
+			r.op = op
+			return r, r.parseValue(rest[len(op):])
+		}
+	}
+
+	trimmed := strings.TrimLeft(rest, " \t")
+	for _, op := range []string{fieldOpNotIn, fieldOpIn} {
+		if trimmed != rest && strings.HasPrefix(trimmed, op) {
+			r.op = op
+			return r, r.parseSet(trimmed[len(op):])
+		}
+	}
+
+	return r, fmt.Errorf("unknown operator in %q, must be one of %s, %s or %s",
+		rest, strings.Join(fieldSymbolOperators, ", "), fieldOpIn, fieldOpNotIn)
+}
+
+// parseValue parses the value of a requirement with a symbolic operator.
+func (r *fieldRequirement) parseValue(s string) error {
+	var err error
+
+	switch r.op {
+	case fieldOpMatches, fieldOpNotMatches:
+		r.regex, err = regexp.Compile(fmt.Sprintf("^(%s)$", s))
+		if err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	case fieldOpGreater, fieldOpGreaterEqual, fieldOpLess, fieldOpLessEqual:
+		if !numericFields[r.field] {
+			return fmt.Errorf("operator %s requires a numeric field, must be one of %s", r.op, strings.Join(sortedKeys(numericFields), ", "))
+		}
+		r.number, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return fmt.Errorf("value %q is not a number", s)
+		}
+	default:
+		value, err := fields.UnescapeValue(s)
+		if err != nil {
+			return err
+		}
+		r.values = []string{value}
+	}
+
+	return nil
+}
+
+// parseSet parses the parenthesized, comma separated values of a set-based
+// requirement.
+func (r *fieldRequirement) parseSet(s string) error {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "(") || !strings.HasSuffix(s, ")") {
+		return fmt.Errorf("values of operator %s must be enclosed in parentheses", r.op)
+	}
+
+	for _, v := range splitEscaped(s[1 : len(s)-1]) {
+		value, err := fields.UnescapeValue(strings.TrimSpace(v))
+		if err != nil {
+			return err
+		}
+		r.values = append(r.values, value)
+	}
+	if len(r.values) == 1 && r.values[0] == "" {
+		return fmt.Errorf("operator %s requires at least one value", r.op)
+	}
+
+	return nil
+}
+
+// splitEscaped splits s on commas not escaped with a backslash.
+func splitEscaped(s string) []string {
+	var (
+		parts  []string
+		start  int
+		escape bool
+	)
+	for i, c := range s {
+		switch {
+		case escape:
+			escape = false
+		case c == '\\':
+			escape = true
+		case c == ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func isFieldNameChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("/._-", c)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
@@ -0,0 +1,70 @@
+package ctl_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Field selector", func() {
+	var adapter ctl.Port
+
+	BeforeEach(func() {
+		adapter = ctl.NewCtl(fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Interval: 5, AlertPriority: "P1", Description: "foo, the first"},
+			heartbeat.Heartbeat{Name: "bar", Interval: 10, AlertPriority: "P2", Description: "bar checks"},
+			heartbeat.Heartbeat{Name: "baz", Interval: 30, AlertPriority: "P3", Description: "baz checks"},
+			heartbeat.Heartbeat{Name: "qux", Interval: 60, AlertPriority: "P4"},
+		))
+	})
+
+	DescribeTable("selects heartbeats matching all requirements",
+		func(selector string, names ...string) {
+			heartbeats, err := adapter.Get(&ctl.SelectorConfig{FieldSelector: selector})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(heartbeats).To(ConsistOfHeartbeats(names...))
+		},
+		Entry("equality", "alertPriority=P1", "foo"),
+		Entry("double equality", "alertPriority==P2", "bar"),
+		Entry("inequality", "alertPriority!=P1", "bar", "baz", "qux"),
+		Entry("set", "alertPriority in (P1, P2)", "foo", "bar"),
+		Entry("negated set", "alertPriority notin (P1,P2)", "baz", "qux"),
+		Entry("regular expression", "description=~.*checks", "bar", "baz"),
+		Entry("negated regular expression", "description!~ba.*", "foo", "qux"),
+		Entry("regular expression with commas", "name=~[a-z]{3,3}", "foo", "bar", "baz", "qux"),
+		Entry("escaped comma", `description=foo\, the first`, "foo"),
+		Entry("greater than", "interval>10", "baz", "qux"),
+		Entry("greater than or equal", "interval>=10", "bar", "baz", "qux"),
+		Entry("less than", "interval<10", "foo"),
+		Entry("less than or equal", "interval<=10.0", "foo", "bar"),
+		Entry("multiple requirements", "interval>5,alertPriority notin (P4),description=~.*checks", "bar", "baz"),
+	)
+
+	DescribeTable("fails with a clear error for malformed selectors",
+		func(selector string, message string) {
+			_, err := adapter.Get(&ctl.SelectorConfig{FieldSelector: selector})
+
+			var selErr *ctl.InvalidSelectorError
+			Expect(errors.As(err, &selErr)).To(BeTrue())
+			Expect(selErr.Selector).To(Equal(selector))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown operator", "alertPriority~P3", `unknown operator in "~P3"`),
+		Entry("missing operator", "name=foo,alertPriority", `requirement "alertPriority": missing operator`),
+		Entry("missing field", "=P3", `invalid selector "=P3": missing field name`),
+		Entry("unknown field", "priority=P3", `unknown field "priority", must be one of alertMessage,`),
+		Entry("empty requirement", "name=foo,,interval=5", "requirement 2 is empty"),
+		Entry("unclosed set", "alertPriority in (P1, P2", "unclosed bracket"),
+		Entry("unopened set", "alertPriority in P1)", `unexpected ')' at position 20`),
+		Entry("set without parentheses", "alertPriority in P1", "values of operator in must be enclosed in parentheses"),
+		Entry("empty set", "alertPriority notin ()", "operator notin requires at least one value"),
+		Entry("invalid regular expression", "name=~a**", "invalid regular expression"),
+		Entry("non-numeric value", "interval>ten", `value "ten" is not a number`),
+		Entry("non-numeric field", "alertPriority>1", "operator > requires a numeric field, must be one of interval"),
+	)
+})
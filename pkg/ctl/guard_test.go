@@ -0,0 +1,65 @@
+package ctl_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Guard", func() {
+	var (
+		repo    *fake.Client
+		calls   map[string][]string
+		guard   error
+		adapter ctl.Port
+	)
+
+	BeforeEach(func() {
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Enabled: true},
+			heartbeat.Heartbeat{Name: "bar"},
+		)
+		calls = map[string][]string{}
+		guard = nil
+		adapter = ctl.NewCtl(repo, ctl.WithGuard(func(action string, heartbeats []heartbeat.Heartbeat) error {
+			for _, h := range heartbeats {
+				calls[action] = append(calls[action], h.Name)
+			}
+			return guard
+		}))
+	})
+
+	It("is asked with the action and selected heartbeats", func() {
+		_, err := adapter.Enable(&ctl.SelectorConfig{NameExpressions: []string{".*"}})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = adapter.Disable(&ctl.SelectorConfig{NameExpressions: []string{"foo"}})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = adapter.Ping(&ctl.SelectorConfig{NameExpressions: []string{"bar"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(calls).To(Equal(map[string][]string{
+			ctl.ActionEnable:  {"bar", "foo"},
+			ctl.ActionDisable: {"foo"},
+			ctl.ActionPing:    {"bar"},
+		}))
+	})
+
+	It("is not asked when getting heartbeats", func() {
+		_, err := adapter.Get(&ctl.SelectorConfig{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(BeEmpty())
+	})
+
+	It("stops the operation before any heartbeat is changed", func() {
+		guard = errors.New("nope")
+
+		_, err := adapter.Disable(&ctl.SelectorConfig{NameExpressions: []string{".*"}})
+		Expect(err).To(MatchError("nope"))
+		Expect(repo.Heartbeats()).To(ContainElement(HaveField("Enabled", true)))
+	})
+})
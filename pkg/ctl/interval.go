@@ -0,0 +1,39 @@
+package ctl
+
+import (
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// SetInterval updates heartbeats selected by given SelectorConfig, which must
+// specify at least one selector or name, to expect pings at given interval,
+// leaving the rest of their configuration as is. Heartbeats already expecting
+// pings at the interval are marked as unchanged. In dry run mode it returns
+// actions it would take without changing anything. Returns ErrNoMatch if no
+// heartbeats were selected.
+func SetInterval(c Port, selector *SelectorConfig, interval int, unit string, dryRun bool) ([]PlannedAction, error) {
+	if selector.empty() {
+		return nil, ErrNoSelector
+	}
+
+	selected, err := c.Get(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(selected) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	manifests := make([]manifest.Heartbeat, 0, len(selected))
+	for _, h := range selected {
+		m := manifest.FromHeartbeat(h)
+		m.Spec.Enabled = nil
+		m.Spec.Interval = interval
+		m.Spec.IntervalUnit = unit
+		manifests = append(manifests, m)
+	}
+
+	if dryRun {
+		return c.PlanApply(manifests)
+	}
+	return c.Apply(manifests)
+}
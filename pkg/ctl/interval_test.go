@@ -0,0 +1,55 @@
+package ctl_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("SetInterval", func() {
+	var repo *fake.Client
+
+	BeforeEach(func() {
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "backup-a", Interval: 1, IntervalUnit: "days", OwnerTeam: og.OwnerTeam{Name: "ops"}, AlertTags: []string{"backup"}},
+			heartbeat.Heartbeat{Name: "backup-b", Interval: 4350, IntervalUnit: "minutes", Enabled: true},
+			heartbeat.Heartbeat{Name: "other", Interval: 5, IntervalUnit: "minutes"},
+		)
+	})
+
+	It("updates intervals of selected heartbeats, keeping the rest as is", func() {
+		actions, err := ctl.SetInterval(ctl.NewCtl(repo), &ctl.SelectorConfig{NameExpressions: []string{"backup-.*"}}, 4350, "minutes", false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionUpdate, Heartbeat: "backup-a"},
+			{Action: ctl.ActionUpdate, Heartbeat: "backup-b", Unchanged: true},
+		}))
+
+		Expect(repo.Heartbeats()).To(Equal([]heartbeat.Heartbeat{
+			{Name: "backup-a", Interval: 4350, IntervalUnit: "minutes", OwnerTeam: og.OwnerTeam{Name: "ops"}, AlertTags: []string{"backup"}},
+			{Name: "backup-b", Interval: 4350, IntervalUnit: "minutes", Enabled: true},
+			{Name: "other", Interval: 5, IntervalUnit: "minutes"},
+		}))
+	})
+
+	It("only plans changes in dry run mode", func() {
+		before := repo.Heartbeats()
+
+		actions, err := ctl.SetInterval(ctl.NewCtl(repo), &ctl.SelectorConfig{NameExpressions: []string{"other"}}, 2, "hours", true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{{Action: ctl.ActionUpdate, Heartbeat: "other"}}))
+		Expect(repo.Heartbeats()).To(Equal(before))
+	})
+
+	It("requires selectors matching some heartbeats", func() {
+		_, err := ctl.SetInterval(ctl.NewCtl(repo), &ctl.SelectorConfig{}, 1, "hours", false)
+		Expect(err).To(MatchError(ctl.ErrNoSelector))
+
+		_, err = ctl.SetInterval(ctl.NewCtl(repo), &ctl.SelectorConfig{NameExpressions: []string{"nope"}}, 1, "hours", false)
+		Expect(err).To(MatchError(ctl.ErrNoMatch))
+	})
+})
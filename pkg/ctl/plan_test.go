@@ -0,0 +1,68 @@
+package ctl_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var errUnexpectedCall = errors.New("unexpected call")
+
+var _ = Describe("Plan", func() {
+	var (
+		repo    *fake.Client
+		adapter ctl.Port
+	)
+
+	BeforeEach(func() {
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Enabled: true},
+			heartbeat.Heartbeat{Name: "bar"},
+		)
+		repo.Fail("Enable", "", errUnexpectedCall)
+		repo.Fail("Disable", "", errUnexpectedCall)
+		repo.Fail("Ping", "", errUnexpectedCall)
+		adapter = ctl.NewCtl(repo, ctl.WithGuard(func(string, []heartbeat.Heartbeat) error {
+			Fail("guard must not be asked when planning")
+			return nil
+		}))
+	})
+
+	DescribeTable("returns planned actions without changing heartbeats",
+		func(action string, expected ...ctl.PlannedAction) {
+			plan, err := adapter.Plan(action, &ctl.SelectorConfig{NameExpressions: []string{".*"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(plan).To(Equal(expected))
+		},
+		Entry("enable", ctl.ActionEnable,
+			ctl.PlannedAction{Action: ctl.ActionEnable, Heartbeat: "bar"},
+			ctl.PlannedAction{Action: ctl.ActionEnable, Heartbeat: "foo", Unchanged: true},
+		),
+		Entry("disable", ctl.ActionDisable,
+			ctl.PlannedAction{Action: ctl.ActionDisable, Heartbeat: "bar", Unchanged: true},
+			ctl.PlannedAction{Action: ctl.ActionDisable, Heartbeat: "foo"},
+		),
+		Entry("ping", ctl.ActionPing,
+			ctl.PlannedAction{Action: ctl.ActionPing, Heartbeat: "bar"},
+			ctl.PlannedAction{Action: ctl.ActionPing, Heartbeat: "foo"},
+		),
+	)
+
+	It("applies the same selection rules as mutating methods", func() {
+		_, err := adapter.Plan(ctl.ActionDisable, &ctl.SelectorConfig{})
+		Expect(err).To(MatchError(ctl.ErrNoSelector))
+
+		_, err = adapter.Plan(ctl.ActionDisable, &ctl.SelectorConfig{NameExpressions: []string{"nope"}})
+		Expect(err).To(MatchError(ctl.ErrNoMatch))
+	})
+
+	It("fails for unknown actions", func() {
+		_, err := adapter.Plan("frobnicate", &ctl.SelectorConfig{NameExpressions: []string{".*"}})
+		Expect(err).To(MatchError(`unknown action "frobnicate"`))
+	})
+})
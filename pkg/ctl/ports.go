@@ -1,6 +1,10 @@
 package ctl
 
-import "github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+import (
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
 
 // Port of the heartbeatctl application
 type Port interface {
@@ -13,15 +17,66 @@ type Port interface {
 	// Enable enables all heartbeats selected by given SelectorConfig, which
 	// in this case must specify at least one selector or name (to target all
 	// heartbeats specify a `nameExpressions=['.*']` rule explicitly).
+	// It stops at the first heartbeat that fails and returns a
+	// HeartbeatsError, or ErrNoMatch if no heartbeats were selected.
 	Enable(*SelectorConfig) ([]heartbeat.HeartbeatInfo, error)
 
 	// Disable disables all heartbeats selected by given SelectorConfig, which
 	// in this case must specify at least one selector or name (to target all
 	// heartbeats specify a `nameExpressions=['.*']` rule explicitly).
+	// It stops at the first heartbeat that fails and returns a
+	// HeartbeatsError, or ErrNoMatch if no heartbeats were selected.
 	Disable(*SelectorConfig) ([]heartbeat.HeartbeatInfo, error)
 
 	// Ping pings all heartbeats selected by given SelectorConfig, which
 	// in this case must specify at least one selector or name (to target all
 	// heartbeats specify a `nameExpressions=['.*']` rule explicitly).
+	// All selected heartbeats are pinged and those that failed are reported
+	// in a HeartbeatsError. Returns ErrNoMatch if no heartbeats were selected.
 	Ping(*SelectorConfig) (map[string]heartbeat.PingResult, error)
+
+	// Delete deletes all heartbeats selected by given SelectorConfig, which
+	// in this case must specify at least one selector or name (to target all
+	// heartbeats specify a `nameExpressions=['.*']` rule explicitly), and
+	// returns names of deleted heartbeats. It stops at the first heartbeat
+	// that fails and returns a HeartbeatsError, or ErrNoMatch if no
+	// heartbeats were selected.
+	Delete(*SelectorConfig) ([]string, error)
+
+	// Plan returns actions that the mutating method corresponding to given
+	// action, e.g. Disable for ActionDisable, would apply to heartbeats
+	// selected by given SelectorConfig, without changing any heartbeats.
+	// Actions that wouldn't change a heartbeat, like disabling an already
+	// disabled one, are marked as unchanged. The same selection rules as for
+	// the corresponding method apply, but the guard is not consulted.
+	Plan(action string, opts *SelectorConfig) ([]PlannedAction, error)
+
+	// Restore updates given heartbeats, identified by name, to have the given
+	// state, including whether they are enabled, recreating those that were
+	// deleted. Descriptions and alert tags empty in the given state can't be
+	// cleared and are left in place. Only the current state of existing
+	// heartbeats is passed to the recorder. It stops at the first heartbeat that fails and
+	// returns a HeartbeatsError.
+	Restore(heartbeats []heartbeat.Heartbeat) ([]heartbeat.HeartbeatInfo, error)
+
+	// PlanRestore returns actions Restore would apply to given heartbeats,
+	// without changing any heartbeats. Heartbeats that already have the
+	// given state are marked as unchanged, those that would be recreated
+	// are planned to be created, and fields Restore would leave in place are
+	// listed as uncleared.
+	PlanRestore(heartbeats []heartbeat.Heartbeat) ([]PlannedAction, error)
+
+	// Apply creates heartbeats described by given manifests that don't exist
+	// and updates those that don't match their manifest, returning the
+	// actions applied, with heartbeats matching their manifest marked as
+	// unchanged and descriptions and alert tags the manifest leaves empty,
+	// which can't be cleared, listed as uncleared. The guard is asked about all heartbeats to be created or
+	// updated, while the recorder is only given the current state of those to
+	// be updated. It stops at the first heartbeat that fails and returns a
+	// HeartbeatsError.
+	Apply(manifests []manifest.Heartbeat) ([]PlannedAction, error)
+
+	// PlanApply returns actions Apply would apply for given manifests,
+	// without changing any heartbeats.
+	PlanApply(manifests []manifest.Heartbeat) ([]PlannedAction, error)
 }
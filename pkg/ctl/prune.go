@@ -0,0 +1,51 @@
+package ctl
+
+import (
+	"regexp"
+
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// Prune deletes heartbeats tagged as belonging to the set with given ID,
+// i.e. with tags returned by manifest.OwnerTags, whose names are not among
+// names to keep. Heartbeats without these tags, e.g. created by hand or by
+// other tools, are never touched. In dry run mode it returns actions it would
+// take without deleting anything. Having nothing to prune is not an error.
+func Prune(c Port, owner string, keep []string, dryRun bool) ([]PlannedAction, error) {
+	owned, err := c.Get(&SelectorConfig{LabelSelector: manifest.OwnerSelector(owner)})
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make(map[string]bool, len(keep))
+	for _, name := range keep {
+		kept[name] = true
+	}
+
+	var expressions []string
+	for _, h := range owned {
+		if !kept[h.Name] {
+			expressions = append(expressions, regexp.QuoteMeta(h.Name))
+		}
+	}
+	if len(expressions) == 0 {
+		return []PlannedAction{}, nil
+	}
+
+	// Ownership is checked again when deleting, in case tags were changed in
+	// the meantime.
+	selector := &SelectorConfig{
+		NameExpressions: expressions,
+		LabelSelector:   manifest.OwnerSelector(owner),
+	}
+	if dryRun {
+		return c.Plan(ActionDelete, selector)
+	}
+
+	names, err := c.Delete(selector)
+	deleted := make([]PlannedAction, 0, len(names))
+	for _, name := range names {
+		deleted = append(deleted, PlannedAction{Action: ActionDelete, Heartbeat: name})
+	}
+	return deleted, err
+}
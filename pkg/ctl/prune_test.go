@@ -0,0 +1,97 @@
+package ctl_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Prune", func() {
+	var (
+		repo *fake.Client
+		c    ctl.Port
+	)
+
+	owned := func(name, owner string) heartbeat.Heartbeat {
+		return heartbeat.Heartbeat{
+			Name:         name,
+			Interval:     10,
+			IntervalUnit: "minutes",
+			AlertTags:    []string{"managed-by: heartbeatctl", "heartbeatctl.giantswarm.io/owner: " + owner},
+		}
+	}
+
+	names := func() []string {
+		ret := []string{}
+		for _, h := range repo.Heartbeats() {
+			ret = append(ret, h.Name)
+		}
+		return ret
+	}
+
+	BeforeEach(func() {
+		repo = fake.NewClient(
+			owned("gorilla", "installations"),
+			owned("gaia", "installations"),
+			owned("stale", "installations"),
+			owned("other", "clusters"),
+			heartbeat.Heartbeat{Name: "manual", Interval: 1, IntervalUnit: "hours"},
+			heartbeat.Heartbeat{Name: "foreign", Interval: 1, IntervalUnit: "hours", AlertTags: []string{"managed-by: foobricator", "heartbeatctl.giantswarm.io/owner: installations"}},
+		)
+		c = ctl.NewCtl(repo)
+	})
+
+	It("deletes owned heartbeats missing in the list of names to keep", func() {
+		actions, err := ctl.Prune(c, "installations", []string{"gorilla", "gaia", "manual", "foreign"}, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionDelete, Heartbeat: "stale"},
+		}))
+		Expect(names()).To(Equal([]string{"foreign", "gaia", "gorilla", "manual", "other"}))
+	})
+
+	It("never deletes heartbeats without ownership tags of the set", func() {
+		actions, err := ctl.Prune(c, "installations", nil, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(HaveLen(3))
+		Expect(names()).To(Equal([]string{"foreign", "manual", "other"}))
+	})
+
+	It("only plans deletions in dry run mode", func() {
+		actions, err := ctl.Prune(c, "installations", []string{"gorilla"}, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionDelete, Heartbeat: "gaia"},
+			{Action: ctl.ActionDelete, Heartbeat: "stale"},
+		}))
+		Expect(repo.Heartbeats()).To(HaveLen(6))
+	})
+
+	It("succeeds when there is nothing to prune", func() {
+		actions, err := ctl.Prune(c, "nobody", nil, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(BeEmpty())
+	})
+
+	It("asks the guard before deleting", func() {
+		errDenied := errors.New("denied")
+		var guarded []string
+		c = ctl.NewCtl(repo, ctl.WithGuard(func(action string, hbs []heartbeat.Heartbeat) error {
+			Expect(action).To(Equal(ctl.ActionDelete))
+			for _, h := range hbs {
+				guarded = append(guarded, h.Name)
+			}
+			return errDenied
+		}))
+
+		_, err := ctl.Prune(c, "installations", []string{"gorilla"}, false)
+		Expect(err).To(MatchError(errDenied))
+		Expect(guarded).To(Equal([]string{"gaia", "stale"}))
+		Expect(repo.Heartbeats()).To(HaveLen(6))
+	})
+})
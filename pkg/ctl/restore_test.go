@@ -0,0 +1,165 @@
+package ctl_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Restore", func() {
+	var (
+		repo     *fake.Client
+		recorded map[string][]heartbeat.Heartbeat
+		recorder error
+		adapter  ctl.Port
+
+		previous []heartbeat.Heartbeat
+	)
+
+	BeforeEach(func() {
+		previous = []heartbeat.Heartbeat{
+			{Name: "foo", Interval: 10, IntervalUnit: "minutes", Enabled: true, AlertPriority: "P2"},
+			{Name: "bar", Interval: 5, IntervalUnit: "minutes", AlertTags: []string{"tagged"}, OwnerTeam: og.OwnerTeam{Name: "ops"}},
+		}
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Interval: 10, IntervalUnit: "minutes", AlertPriority: "P2", Expired: true},
+			heartbeat.Heartbeat{Name: "bar", Interval: 1, IntervalUnit: "hours", Enabled: true},
+			heartbeat.Heartbeat{Name: "baz", Enabled: true},
+		)
+		recorded = map[string][]heartbeat.Heartbeat{}
+		recorder = nil
+		adapter = ctl.NewCtl(repo, ctl.WithRecorder(func(action string, heartbeats []heartbeat.Heartbeat) error {
+			recorded[action] = append(recorded[action], heartbeats...)
+			return recorder
+		}))
+	})
+
+	It("updates heartbeats to the given state", func() {
+		infos, err := adapter.Restore(previous)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(infos).To(HaveLen(2))
+		Expect(infos[0].Name).To(Equal("bar"))
+		Expect(infos[1].Name).To(Equal("foo"))
+
+		Expect(repo.Heartbeats()).To(Equal([]heartbeat.Heartbeat{
+			{Name: "bar", Interval: 5, IntervalUnit: "minutes", AlertTags: []string{"tagged"}, OwnerTeam: og.OwnerTeam{Name: "ops"}},
+			{Name: "baz", Enabled: true},
+			{Name: "foo", Interval: 10, IntervalUnit: "minutes", Enabled: true, AlertPriority: "P2", Expired: true},
+		}))
+	})
+
+	It("records the current state of heartbeats before restoring them", func() {
+		_, err := adapter.Restore(previous)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(recorded).To(Equal(map[string][]heartbeat.Heartbeat{
+			ctl.ActionRestore: {
+				{Name: "bar", Interval: 1, IntervalUnit: "hours", Enabled: true},
+				{Name: "foo", Interval: 10, IntervalUnit: "minutes", AlertPriority: "P2", Expired: true},
+			},
+		}))
+	})
+
+	It("stops before any heartbeat is changed if the recorder fails", func() {
+		recorder = errors.New("disk full")
+		before := repo.Heartbeats()
+
+		_, err := adapter.Restore(previous)
+		Expect(err).To(MatchError("disk full"))
+		Expect(repo.Heartbeats()).To(Equal(before))
+	})
+
+	It("recreates deleted heartbeats", func() {
+		previous = append(previous, heartbeat.Heartbeat{Name: "qux", Interval: 2, IntervalUnit: "days", AlertPriority: "P3"})
+
+		plan, err := adapter.PlanRestore(previous)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan[2]).To(Equal(ctl.PlannedAction{Action: ctl.ActionCreate, Heartbeat: "qux"}))
+
+		_, err = adapter.Restore(previous)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()[3]).To(Equal(heartbeat.Heartbeat{Name: "qux", Interval: 2, IntervalUnit: "days", AlertPriority: "P3", AlertMessage: fake.DefaultAlertMessage("qux")}))
+		Expect(recorded[ctl.ActionRestore]).To(HaveLen(2))
+	})
+
+	It("asks the guard about recreated heartbeats too", func() {
+		previous = append(previous, heartbeat.Heartbeat{Name: "qux", Interval: 2, IntervalUnit: "days"})
+		var guarded []string
+		adapter = ctl.NewCtl(repo, ctl.WithGuard(func(action string, heartbeats []heartbeat.Heartbeat) error {
+			for _, h := range heartbeats {
+				guarded = append(guarded, h.Name)
+			}
+			return errors.New("too many")
+		}))
+		before := repo.Heartbeats()
+
+		_, err := adapter.Restore(previous)
+		Expect(err).To(MatchError("too many"))
+		Expect(guarded).To(Equal([]string{"bar", "foo", "qux"}))
+		Expect(repo.Heartbeats()).To(Equal(before))
+	})
+
+	It("stops at the first heartbeat that fails", func() {
+		repo.Fail("Update", "foo", errors.New("boom"))
+
+		infos, err := adapter.Restore(previous)
+		var hbErr *ctl.HeartbeatsError
+		Expect(errors.As(err, &hbErr)).To(BeTrue())
+		Expect(hbErr.Failed).To(Equal([]string{"foo"}))
+		Expect(hbErr.Succeeded).To(Equal([]string{"bar"}))
+		Expect(infos).To(HaveLen(1))
+	})
+
+	It("plans restoring heartbeats, marking those already in the given state", func() {
+		previous[0].Enabled = false
+
+		plan, err := adapter.PlanRestore(previous)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionRestore, Heartbeat: "bar"},
+			{Action: ctl.ActionRestore, Heartbeat: "foo", Unchanged: true},
+		}))
+		Expect(recorded).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Recorder", func() {
+	It("is called with selected heartbeats once the guard allows it", func() {
+		repo := fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Enabled: true},
+			heartbeat.Heartbeat{Name: "bar"},
+		)
+		var calls []string
+		adapter := ctl.NewCtl(repo,
+			ctl.WithGuard(func(action string, _ []heartbeat.Heartbeat) error {
+				calls = append(calls, "guard "+action)
+				if action == ctl.ActionEnable {
+					return errors.New("nope")
+				}
+				return nil
+			}),
+			ctl.WithRecorder(func(action string, heartbeats []heartbeat.Heartbeat) error {
+				for _, h := range heartbeats {
+					calls = append(calls, "record "+action+" "+h.Name)
+				}
+				return nil
+			}),
+		)
+
+		_, err := adapter.Enable(&ctl.SelectorConfig{NameExpressions: []string{".*"}})
+		Expect(err).To(MatchError("nope"))
+		_, err = adapter.Disable(&ctl.SelectorConfig{NameExpressions: []string{"foo"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(calls).To(Equal([]string{
+			"guard enable",
+			"guard disable",
+			"record disable foo",
+		}))
+	})
+})
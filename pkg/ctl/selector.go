@@ -0,0 +1,86 @@
+package ctl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/giantswarm/heartbeatctl/pkg/conv"
+	"github.com/giantswarm/heartbeatctl/pkg/query"
+)
+
+// selector is a SelectorConfig with all its expressions parsed, ready to be
+// matched against heartbeats.
+type selector struct {
+	names  *regexp.Regexp
+	labels labels.Selector
+	fields fieldSelector
+	query  *query.Query
+}
+
+// compileSelector parses all expressions of given SelectorConfig, returning
+// an InvalidSelectorError if any of them is malformed.
+func compileSelector(opts *SelectorConfig) (*selector, error) {
+	var err error
+	s := &selector{
+		labels: labels.Everything(),
+	}
+
+	if len(opts.NameExpressions) > 0 {
+		s.names, err = regexp.Compile(fmt.Sprintf("^(%s)$", strings.Join(opts.NameExpressions, "|")))
+		if err != nil {
+			return nil, &InvalidSelectorError{Selector: strings.Join(opts.NameExpressions, " "), Err: err}
+		}
+	}
+
+	if opts.LabelSelector != "" {
+		s.labels, err = labels.Parse(opts.LabelSelector)
+		if err != nil {
+			return nil, &InvalidSelectorError{Selector: opts.LabelSelector, Err: err}
+		}
+	}
+
+	if opts.FieldSelector != "" {
+		s.fields, err = parseFieldSelector(opts.FieldSelector)
+		if err != nil {
+			return nil, &InvalidSelectorError{Selector: opts.FieldSelector, Err: err}
+		}
+	}
+
+	if opts.Query != "" {
+		s.query, err = query.Parse(opts.Query)
+		if err != nil {
+			return nil, &InvalidSelectorError{Selector: opts.Query, Err: err}
+		}
+	}
+
+	return s, nil
+}
+
+// ValidateSelector parses all expressions of given SelectorConfig, returning
+// an InvalidSelectorError if any of them is malformed.
+func ValidateSelector(opts *SelectorConfig) error {
+	_, err := compileSelector(opts)
+	return err
+}
+
+// Matches returns true if given heartbeat matches all expressions of the
+// selector.
+func (s *selector) Matches(h heartbeat.Heartbeat) bool {
+	if s.names != nil && !s.names.MatchString(h.Name) {
+		return false
+	}
+
+	if !s.labels.Matches(conv.HeartbeatAsLabels(h)) {
+		return false
+	}
+
+	if !s.fields.Matches(conv.HeartbeatAsFields(h)) {
+		return false
+	}
+
+	return s.query == nil || s.query.Matches(h)
+}
@@ -0,0 +1,44 @@
+package ctl_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Query", func() {
+	var adapter ctl.Port
+
+	BeforeEach(func() {
+		adapter = ctl.NewCtl(fake.NewClient(
+			heartbeat.Heartbeat{Name: "foo", Enabled: true, AlertPriority: "P1"},
+			heartbeat.Heartbeat{Name: "bar", AlertPriority: "P1"},
+			heartbeat.Heartbeat{Name: "baz", AlertPriority: "P2", AlertTags: []string{"team: x"}},
+			heartbeat.Heartbeat{Name: "qux", Enabled: true, AlertPriority: "P3", AlertTags: []string{"team: x"}},
+		))
+	})
+
+	It("selects heartbeats matching either side of an or-expression", func() {
+		heartbeats, err := adapter.Get(&ctl.SelectorConfig{Query: "(not enabled and alertPriority=P1) or team=x"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(heartbeats).To(ConsistOfHeartbeats("bar", "baz", "qux"))
+	})
+
+	It("combines the query with other selectors", func() {
+		heartbeats, err := adapter.Get(&ctl.SelectorConfig{
+			NameExpressions: []string{"ba.*"},
+			Query:           "alertPriority in (P1, P3) or team=x",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(heartbeats).To(ConsistOfHeartbeats("bar", "baz"))
+	})
+
+	It("counts as a selector for methods requiring one", func() {
+		heartbeats, err := adapter.Enable(&ctl.SelectorConfig{Query: "not enabled"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(heartbeats).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,153 @@
+package ctl
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// SyncConfig configures which heartbeats Sync copies between accounts and
+// how.
+type SyncConfig struct {
+	// Selector selects heartbeats in the source account to copy. It must
+	// not be empty unless FromPrefix is set.
+	Selector *SelectorConfig
+
+	// FromPrefix limits copied heartbeats to those with names starting with
+	// it, and is replaced with ToPrefix in names of heartbeats in the target
+	// account.
+	FromPrefix string
+	ToPrefix   string
+
+	// Teams maps owner team names in the source account to names of teams
+	// in the target account. Teams not mapped keep their names.
+	Teams map[string]string
+
+	// Prune enables deleting heartbeats in the target account that are in
+	// scope of the sync, but have no counterpart in the source account.
+	// Heartbeats are in scope if their names start with ToPrefix, and they
+	// match Selector, with name expressions matched against their names in
+	// the source account, i.e. with ToPrefix replaced with FromPrefix. It
+	// requires ToPrefix or Selector to be set.
+	Prune bool
+}
+
+// Sync makes heartbeats in the target account match heartbeats selected in
+// the source account, creating missing and updating changed heartbeats, and
+// with pruning enabled, deleting those with no counterpart. Changes are made
+// using the target Port, so its guard is asked before creating or updating
+// and again before deleting heartbeats. It returns actions applied, sorted by
+// heartbeat name, or only planned with dryRun. Returns ErrNoMatch if no
+// heartbeats were selected in the source account, and ErrUnscopedPrune if
+// pruning would put every heartbeat of the target account in scope.
+func Sync(source, target Port, cfg SyncConfig, dryRun bool) ([]PlannedAction, error) {
+	if cfg.Selector == nil {
+		cfg.Selector = &SelectorConfig{}
+	}
+	if cfg.Selector.empty() && cfg.FromPrefix == "" {
+		return nil, ErrNoSelector
+	}
+	if cfg.Prune && cfg.ToPrefix == "" && cfg.Selector.empty() {
+		return nil, ErrUnscopedPrune
+	}
+
+	heartbeats, err := source.Get(cfg.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []manifest.Heartbeat
+	desired := map[string]bool{}
+	for _, h := range heartbeats {
+		if !strings.HasPrefix(h.Name, cfg.FromPrefix) {
+			continue
+		}
+		m := manifest.FromHeartbeat(h)
+		m.Metadata.Name = cfg.ToPrefix + strings.TrimPrefix(h.Name, cfg.FromPrefix)
+		if team, ok := cfg.Teams[m.Spec.OwnerTeam]; ok {
+			m.Spec.OwnerTeam = team
+		}
+		manifests = append(manifests, m)
+		desired[m.Metadata.Name] = true
+	}
+	// Pruning everything in scope is more likely a mistake than intended.
+	if len(manifests) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	var actions []PlannedAction
+	if dryRun {
+		actions, err = target.PlanApply(manifests)
+	} else {
+		actions, err = target.Apply(manifests)
+	}
+	if err != nil {
+		return actions, err
+	}
+
+	if cfg.Prune {
+		prune, err := pruneSelector(target, cfg, desired)
+		if err != nil {
+			return actions, err
+		}
+		if prune != nil {
+			var deleted []PlannedAction
+			if dryRun {
+				deleted, err = target.Plan(ActionDelete, prune)
+			} else {
+				var names []string
+				names, err = target.Delete(prune)
+				for _, name := range names {
+					deleted = append(deleted, PlannedAction{Action: ActionDelete, Heartbeat: name})
+				}
+			}
+			actions = append(actions, deleted...)
+			if err != nil {
+				return actions, err
+			}
+		}
+	}
+
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].Heartbeat < actions[j].Heartbeat
+	})
+	return actions, nil
+}
+
+// pruneSelector returns a selector selecting heartbeats in the target account
+// to delete while syncing, or nil if there are none.
+func pruneSelector(target Port, cfg SyncConfig, desired map[string]bool) (*SelectorConfig, error) {
+	names, err := compileSelector(&SelectorConfig{NameExpressions: cfg.Selector.NameExpressions})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := target.Get(&SelectorConfig{
+		LabelSelector: cfg.Selector.LabelSelector,
+		FieldSelector: cfg.Selector.FieldSelector,
+		Query:         cfg.Selector.Query,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var expressions []string
+	for _, h := range candidates {
+		if desired[h.Name] || !strings.HasPrefix(h.Name, cfg.ToPrefix) {
+			continue
+		}
+		source := h
+		source.Name = cfg.FromPrefix + strings.TrimPrefix(h.Name, cfg.ToPrefix)
+		if !names.Matches(source) {
+			continue
+		}
+		expressions = append(expressions, regexp.QuoteMeta(h.Name))
+	}
+
+	if len(expressions) == 0 {
+		return nil, nil
+	}
+	return &SelectorConfig{NameExpressions: expressions}, nil
+}
@@ -0,0 +1,142 @@
+package ctl_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+var _ = Describe("Sync", func() {
+	var (
+		sourceRepo, targetRepo *fake.Client
+		source, target         ctl.Port
+		cfg                    ctl.SyncConfig
+	)
+
+	BeforeEach(func() {
+		sourceRepo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "template-foo", Interval: 10, IntervalUnit: "minutes", Enabled: true, OwnerTeam: og.OwnerTeam{Name: "ops"}, AlertTags: []string{"managed"}},
+			heartbeat.Heartbeat{Name: "template-bar", Interval: 5, IntervalUnit: "minutes", AlertTags: []string{"managed"}},
+			heartbeat.Heartbeat{Name: "other", Interval: 1, IntervalUnit: "hours", AlertTags: []string{"managed"}},
+		)
+		targetRepo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "acme-bar", Interval: 1, IntervalUnit: "minutes", AlertTags: []string{"managed"}},
+			heartbeat.Heartbeat{Name: "acme-stale", Interval: 1, IntervalUnit: "days", AlertTags: []string{"managed"}},
+			heartbeat.Heartbeat{Name: "acme-manual", Interval: 1, IntervalUnit: "days"},
+			heartbeat.Heartbeat{Name: "unrelated", Interval: 1, IntervalUnit: "days", AlertTags: []string{"managed"}},
+		)
+		source, target = ctl.NewCtl(sourceRepo), ctl.NewCtl(targetRepo)
+		cfg = ctl.SyncConfig{
+			Selector:   &ctl.SelectorConfig{LabelSelector: "managed"},
+			FromPrefix: "template-",
+			ToPrefix:   "acme-",
+			Teams:      map[string]string{"ops": "acme-ops"},
+		}
+	})
+
+	It("creates and updates heartbeats with renamed names and teams", func() {
+		actions, err := ctl.Sync(source, target, cfg, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionUpdate, Heartbeat: "acme-bar"},
+			{Action: ctl.ActionCreate, Heartbeat: "acme-foo"},
+		}))
+
+		Expect(targetRepo.Heartbeats()).To(Equal([]heartbeat.Heartbeat{
+			{Name: "acme-bar", Interval: 5, IntervalUnit: "minutes", AlertTags: []string{"managed"}},
+			{Name: "acme-foo", Interval: 10, IntervalUnit: "minutes", Enabled: true, OwnerTeam: og.OwnerTeam{Name: "acme-ops"}, AlertTags: []string{"managed"}, AlertPriority: fake.DefaultAlertPriority, AlertMessage: fake.DefaultAlertMessage("acme-foo")},
+			{Name: "acme-manual", Interval: 1, IntervalUnit: "days"},
+			{Name: "acme-stale", Interval: 1, IntervalUnit: "days", AlertTags: []string{"managed"}},
+			{Name: "unrelated", Interval: 1, IntervalUnit: "days", AlertTags: []string{"managed"}},
+		}))
+	})
+
+	It("deletes heartbeats in scope with no counterpart when pruning", func() {
+		cfg.Prune = true
+
+		actions, err := ctl.Sync(source, target, cfg, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionUpdate, Heartbeat: "acme-bar"},
+			{Action: ctl.ActionCreate, Heartbeat: "acme-foo"},
+			{Action: ctl.ActionDelete, Heartbeat: "acme-stale"},
+		}))
+
+		names := []string{}
+		for _, h := range targetRepo.Heartbeats() {
+			names = append(names, h.Name)
+		}
+		Expect(names).To(Equal([]string{"acme-bar", "acme-foo", "acme-manual", "unrelated"}))
+	})
+
+	It("matches name expressions against source names when pruning", func() {
+		cfg.Prune = true
+		cfg.Selector.NameExpressions = []string{"template-(bar|stale)"}
+
+		actions, err := ctl.Sync(source, target, cfg, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionUpdate, Heartbeat: "acme-bar"},
+			{Action: ctl.ActionDelete, Heartbeat: "acme-stale"},
+		}))
+	})
+
+	It("only plans changes in dry run mode", func() {
+		cfg.Prune = true
+		before := targetRepo.Heartbeats()
+
+		actions, err := ctl.Sync(source, target, cfg, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(HaveLen(3))
+		Expect(targetRepo.Heartbeats()).To(Equal(before))
+	})
+
+	It("requires a selector or prefix", func() {
+		_, err := ctl.Sync(source, target, ctl.SyncConfig{Selector: &ctl.SelectorConfig{}}, false)
+		Expect(err).To(MatchError(ctl.ErrNoSelector))
+	})
+
+	It("refuses to prune without a target prefix or selector", func() {
+		cfg.Prune = true
+		cfg.ToPrefix = ""
+		cfg.Selector = &ctl.SelectorConfig{}
+		before := targetRepo.Heartbeats()
+
+		_, err := ctl.Sync(source, target, cfg, false)
+		Expect(err).To(MatchError(ctl.ErrUnscopedPrune))
+		Expect(targetRepo.Heartbeats()).To(Equal(before))
+	})
+
+	It("fails without changes when nothing was selected", func() {
+		cfg.Prune = true
+		cfg.FromPrefix = "nope-"
+
+		_, err := ctl.Sync(source, target, cfg, false)
+		Expect(err).To(MatchError(ctl.ErrNoMatch))
+		Expect(targetRepo.Heartbeats()).To(HaveLen(4))
+	})
+})
+
+var _ = Describe("Delete", func() {
+	It("deletes selected heartbeats", func() {
+		repo := fake.NewClient(heartbeat.Heartbeat{Name: "foo"}, heartbeat.Heartbeat{Name: "bar"})
+		var guarded []string
+		adapter := ctl.NewCtl(repo, ctl.WithGuard(func(action string, heartbeats []heartbeat.Heartbeat) error {
+			guarded = append(guarded, action)
+			return nil
+		}))
+
+		deleted, err := adapter.Delete(&ctl.SelectorConfig{NameExpressions: []string{"f.*"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal([]string{"foo"}))
+		Expect(repo.Heartbeats()).To(Equal([]heartbeat.Heartbeat{{Name: "bar"}}))
+		Expect(guarded).To(Equal([]string{ctl.ActionDelete}))
+
+		_, err = adapter.Delete(&ctl.SelectorConfig{})
+		Expect(err).To(MatchError(ctl.ErrNoSelector))
+	})
+})
@@ -1,5 +1,66 @@
 package ctl
 
+import "github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+// Actions applied to selected heartbeats by mutating Port methods.
+const (
+	ActionEnable  = "enable"
+	ActionDisable = "disable"
+	ActionPing    = "ping"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+	ActionApply   = "apply"
+)
+
+// Actions applied to individual heartbeats when applying manifests.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+)
+
+// Guard is called by mutating Port methods with the action about to be
+// applied and the heartbeats selected for it. Returning an error stops the
+// method before any heartbeat is changed, with the error returned as is.
+type Guard func(action string, heartbeats []heartbeat.Heartbeat) error
+
+// Recorder is called by mutating Port methods, once the guard allowed it,
+// with the action about to be applied and the current state of heartbeats
+// selected for it, e.g. to keep a journal of changes. Returning an error stops
+// the method before any heartbeat is changed, with the error returned as is.
+type Recorder func(action string, heartbeats []heartbeat.Heartbeat) error
+
+// PlannedAction describes an action a mutating Port method would apply to a
+// heartbeat.
+type PlannedAction struct {
+	Action    string `json:"action"`
+	Heartbeat string `json:"heartbeat"`
+	// Unchanged is true if applying the action wouldn't change the heartbeat.
+	Unchanged bool `json:"unchanged"`
+	// Uncleared lists fields of the heartbeat the action would leave set
+	// although they're empty in its desired state, as OpsGenie can't clear
+	// them.
+	Uncleared []string `json:"uncleared,omitempty"`
+}
+
+// Option configures optional behaviour of the Port returned by NewCtl.
+type Option func(*ctl)
+
+// WithGuard configures the Port to ask given guard before applying any
+// action to selected heartbeats.
+func WithGuard(g Guard) Option {
+	return func(c *ctl) {
+		c.guard = g
+	}
+}
+
+// WithRecorder configures the Port to call given recorder before applying any
+// action to selected heartbeats.
+func WithRecorder(r Recorder) Option {
+	return func(c *ctl) {
+		c.recorder = r
+	}
+}
+
 // SelectorConfig allow configuring selectors that specify field or label
 // query expressions to filter a list of objects to operate on.
 type SelectorConfig struct {
@@ -16,9 +77,16 @@ type SelectorConfig struct {
 	LabelSelector string
 
 	// FieldSelector specifies a selector to filter the list of returned
-	// objects using a K8s-compatible field selector syntax.
+	// objects using a K8s-compatible field selector syntax, extended with
+	// set-based, regular expression and numeric comparison operators.
 	// Defaults to accepting everything.
 	FieldSelector string
+
+	// Query specifies an expression in the language of the `query` package
+	// to filter the list of returned objects with, allowing to combine
+	// comparisons of fields and labels with 'and', 'or' and 'not'.
+	// Defaults to accepting everything.
+	Query string
 }
 
 // empty returns true if all selector options are empty, i.e. selection space
@@ -31,6 +99,8 @@ func (so *SelectorConfig) empty() bool {
 		return false
 	case so.FieldSelector != "":
 		return false
+	case so.Query != "":
+		return false
 	default:
 		return true
 	}
@@ -0,0 +1,4 @@
+// generate package renders heartbeat manifests from a Go template for every
+// item of an inventory, e.g. one heartbeat per installation, so near-identical
+// heartbeats don't have to be written out by hand.
+package generate
@@ -0,0 +1,118 @@
+package generate_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/giantswarm/heartbeatctl/pkg/generate"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+var _ = Describe("Inventory", func() {
+	It("decodes YAML", func() {
+		items, err := generate.DecodeYAML(strings.NewReader("- name: gorilla\n  replicas: 3\n- name: gaia\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]generate.Item{
+			{"name": "gorilla", "replicas": 3},
+			{"name": "gaia", "replicas": ""},
+		}))
+	})
+
+	It("decodes CSV", func() {
+		items, err := generate.DecodeCSV(strings.NewReader("name, provider\ngorilla, aws\ngaia,\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(items).To(Equal([]generate.Item{
+			{"name": "gorilla", "provider": "aws"},
+			{"name": "gaia", "provider": ""},
+		}))
+	})
+
+	It("decodes empty inventories", func() {
+		Expect(generate.DecodeYAML(strings.NewReader(""))).To(BeEmpty())
+		Expect(generate.DecodeCSV(strings.NewReader(""))).To(BeEmpty())
+	})
+
+	DescribeTable("fails to decode malformed YAML",
+		func(input string) {
+			_, err := generate.DecodeYAML(strings.NewReader(input))
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("mapping", "name: gorilla"),
+		Entry("list of strings", "- gorilla"),
+	)
+
+	DescribeTable("fails to decode malformed CSV",
+		func(input, message string) {
+			_, err := generate.DecodeCSV(strings.NewReader(input))
+			Expect(err).To(MatchError(message))
+		},
+		Entry("unnamed column", "name,\ngorilla,aws", "column 2 has no name in the header"),
+		Entry("missing column", "name,provider\ngorilla", "record on line 2: wrong number of fields"),
+	)
+})
+
+var _ = Describe("Template", func() {
+	const text = `apiVersion: heartbeatctl.giantswarm.io/v1alpha1
+kind: Heartbeat
+metadata:
+  name: {{ .name }}
+spec:
+  interval: {{ default 10 .interval }}
+  intervalUnit: minutes
+  description: {{ quote (printf "Heartbeat of %s" (upper .name)) }}
+  alertTags:
+    - {{ tag "installation" .name }}
+    - {{ tag "provider" (lower .provider) }}
+`
+
+	var items []generate.Item
+
+	BeforeEach(func() {
+		items = []generate.Item{
+			{"name": "gorilla", "provider": "AWS", "interval": 5},
+			{"name": "gaia", "provider": "Azure", "interval": ""},
+		}
+	})
+
+	It("renders manifests for every item", func() {
+		tmpl, err := generate.Parse("tmpl.yaml", text)
+		Expect(err).NotTo(HaveOccurred())
+
+		manifests, err := tmpl.Render(items)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifests).To(HaveLen(2))
+
+		Expect(manifests[0].Metadata.Name).To(Equal("gorilla"))
+		Expect(manifests[0].Spec.Interval).To(Equal(5))
+		Expect(manifests[0].Spec.Description).To(Equal("Heartbeat of GORILLA"))
+		Expect(manifests[0].Spec.AlertTags).To(Equal([]string{"installation: gorilla", "provider: aws"}))
+		Expect(manifests[1].Metadata.Name).To(Equal("gaia"))
+		Expect(manifests[1].Spec.Interval).To(Equal(10))
+	})
+
+	It("fails on variables missing in items", func() {
+		tmpl, err := generate.Parse("tmpl.yaml", text)
+		Expect(err).NotTo(HaveOccurred())
+
+		delete(items[1], "provider")
+		delete(items[0], "provider")
+		_, err = tmpl.Render(items)
+		Expect(err).To(MatchError(ContainSubstring(`failed to render item 1 (gorilla): template: tmpl.yaml:11:31: executing "tmpl.yaml" at <.provider>: map has no entry for key "provider"`)))
+	})
+
+	It("reports problems in rendered manifests", func() {
+		tmpl, err := generate.Parse("tmpl.yaml", strings.Replace(text, "{{ .name }}", "cluster", 1))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = tmpl.Render(items)
+
+		var problemsErr *manifest.ProblemsError
+		Expect(err).To(BeAssignableToTypeOf(problemsErr))
+		Expect(err.Error()).To(Equal(strings.Join([]string{
+			"found 1 problem in manifests:",
+			`  tmpl.yaml[item 2 (gaia)]:4:9: heartbeat "cluster" is already defined at tmpl.yaml[item 1 (gorilla)]:4`,
+		}, "\n")))
+	})
+})
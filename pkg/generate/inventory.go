@@ -0,0 +1,121 @@
+package generate
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Item holds variables of a single inventory item, e.g. an installation,
+// available to templates as fields of the dot.
+type Item map[string]interface{}
+
+// Name returns the item's "name" variable, or an empty string if it has none.
+func (i Item) Name() string {
+	if name, ok := i["name"]; ok {
+		return fmt.Sprint(name)
+	}
+	return ""
+}
+
+// DecodeYAML reads inventory items from a YAML list of mappings of variables
+// to their values, e.g. `[{name: gorilla, provider: aws}, {name: gaia}]`.
+// Variables missing in some of the items are set to empty strings in them,
+// the same as empty cells in CSV.
+func DecodeYAML(r io.Reader) ([]Item, error) {
+	var items []Item
+	if err := yaml.NewDecoder(r).Decode(&items); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	keys := map[string]bool{}
+	for _, item := range items {
+		for key := range item {
+			keys[key] = true
+		}
+	}
+	for i, item := range items {
+		if item == nil {
+			item = Item{}
+			items[i] = item
+		}
+		for key := range keys {
+			if _, ok := item[key]; !ok {
+				item[key] = ""
+			}
+		}
+	}
+	return items, nil
+}
+
+// DecodeCSV reads inventory items from CSV with a header line naming
+// variables, e.g.:
+//
+//	name,provider
+//	gorilla,aws
+//	gaia,azure
+func DecodeCSV(r io.Reader) ([]Item, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for i, key := range header {
+		header[i] = strings.TrimSpace(key)
+		if header[i] == "" {
+			return nil, fmt.Errorf("column %d has no name in the header", i+1)
+		}
+	}
+
+	var items []Item
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		item := make(Item, len(header))
+		for i, key := range header {
+			item[key] = record[i]
+		}
+		items = append(items, item)
+	}
+}
+
+// ReadInventory reads inventory items from given file, as CSV if its name
+// ends with ".csv" and as YAML otherwise, or as YAML from standard input if
+// the path is "-".
+func ReadInventory(path string, stdin io.Reader) ([]Item, error) {
+	if path == "-" {
+		return DecodeYAML(stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decode := DecodeYAML
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		decode = DecodeCSV
+	}
+
+	items, err := decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory from %s: %w", path, err)
+	}
+	return items, nil
+}
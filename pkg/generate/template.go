@@ -0,0 +1,109 @@
+package generate
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// funcs are helpers available in templates in addition to the standard ones.
+var funcs = template.FuncMap{
+	// tag returns a quoted "key: value" alert tag, which selectors see as
+	// label "key" with value "value", e.g. `{{ tag "installation" .name }}`.
+	"tag": func(key string, value interface{}) string {
+		return strconv.Quote(key + ": " + fmt.Sprint(value))
+	},
+	// quote returns given value as a quoted string safe to use in YAML.
+	"quote": func(value interface{}) string {
+		return strconv.Quote(fmt.Sprint(value))
+	},
+	// default returns the value, or the default if the value is empty.
+	"default": func(def, value interface{}) interface{} {
+		if value == nil || fmt.Sprint(value) == "" {
+			return def
+		}
+		return value
+	},
+	// lower and upper change the case of given value.
+	"lower": func(value interface{}) string {
+		return strings.ToLower(fmt.Sprint(value))
+	},
+	"upper": func(value interface{}) string {
+		return strings.ToUpper(fmt.Sprint(value))
+	},
+	// replace replaces all occurrences of a substring of given value.
+	"replace": func(from, to string, value interface{}) string {
+		return strings.ReplaceAll(fmt.Sprint(value), from, to)
+	},
+}
+
+// Template renders heartbeat manifests for inventory items.
+type Template struct {
+	name string
+	tmpl *template.Template
+}
+
+// Parse returns a template with given name rendering given text, which is a
+// Go template of one or more heartbeat manifests. Referring to variables
+// missing in an item fails rendering for that item.
+func Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{name: name, tmpl: tmpl}, nil
+}
+
+// ReadTemplate parses the template in given file.
+func ReadTemplate(path string) (*Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, string(text))
+}
+
+// Render returns manifests the template renders for given items. Rendered
+// manifests are checked by manifest.Linter, and if any problems are found,
+// including heartbeat names rendered for more than one item, a
+// manifest.ProblemsError is returned, with problems positioned in the output
+// rendered for the respective item.
+func (t *Template) Render(items []Item) ([]manifest.Heartbeat, error) {
+	linter := manifest.NewLinter()
+	rendered := make([][]byte, 0, len(items))
+	for i, item := range items {
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, item); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", itemName(i, item), err)
+		}
+		linter.Lint(fmt.Sprintf("%s[%s]", t.name, itemName(i, item)), bytes.NewReader(buf.Bytes()))
+		rendered = append(rendered, buf.Bytes())
+	}
+	if problems := linter.Problems(); len(problems) > 0 {
+		return nil, &manifest.ProblemsError{Problems: problems}
+	}
+
+	var ret []manifest.Heartbeat
+	for i, output := range rendered {
+		manifests, err := manifest.Decode(bytes.NewReader(output))
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", itemName(i, items[i]), err)
+		}
+		ret = append(ret, manifests...)
+	}
+	return ret, nil
+}
+
+// itemName identifies an inventory item in messages, by its position and name
+// if it has one.
+func itemName(i int, item Item) string {
+	if name := item.Name(); name != "" {
+		return fmt.Sprintf("item %d (%s)", i+1, name)
+	}
+	return fmt.Sprintf("item %d", i+1)
+}
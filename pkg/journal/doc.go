@@ -0,0 +1,4 @@
+// journal package implements a local, append-only log of changes made to
+// heartbeats, recording the state heartbeats were in before each change so it
+// can be inspected and undone later.
+package journal
@@ -0,0 +1,151 @@
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+)
+
+// ErrEntryNotFound is returned when there's no journal entry with the
+// requested ID.
+var ErrEntryNotFound = errors.New("journal entry not found")
+
+// Entry records a single operation changing heartbeats.
+type Entry struct {
+	// ID identifies the entry, IDs are assigned sequentially starting at 1.
+	ID int `json:"id"`
+	// Time is when the operation was started.
+	Time time.Time `json:"time"`
+	// User is the name of the user who ran the operation.
+	User string `json:"user"`
+	// Command is the command line the operation was run with.
+	Command string `json:"command"`
+	// Action is the action applied to heartbeats, e.g. "disable".
+	Action string `json:"action"`
+	// Undoes is the ID of the entry this one undid, if any.
+	Undoes int `json:"undoes,omitempty"`
+	// Heartbeats holds the state of every touched heartbeat before the
+	// operation.
+	Heartbeats []heartbeat.Heartbeat `json:"heartbeats"`
+}
+
+// HeartbeatNames returns names of heartbeats touched by the operation.
+func (e Entry) HeartbeatNames() []string {
+	ret := make([]string, 0, len(e.Heartbeats))
+	for _, h := range e.Heartbeats {
+		ret = append(ret, h.Name)
+	}
+	return ret
+}
+
+// Journal is a file holding entries as JSON lines.
+type Journal struct {
+	path string
+}
+
+// Open returns the journal stored in given file. The file is created when
+// the first entry is appended.
+func Open(path string) *Journal {
+	return &Journal{path: path}
+}
+
+// Path returns the path of the file the journal is stored in.
+func (j *Journal) Path() string {
+	return j.path
+}
+
+// Append assigns given entry the next ID, writes it at the end of the
+// journal and returns it.
+func (j *Journal) Append(e Entry) (Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return e, err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return e, err
+	}
+
+	file, err := os.OpenFile(j.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return e, err
+	}
+	// Written in a single call so entries of concurrent writers don't
+	// interleave.
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return e, err
+	}
+	return e, file.Close()
+}
+
+// Entries returns all entries in the order they were appended. A missing
+// journal file is treated as having no entries.
+func (j *Journal) Entries() ([]Entry, error) {
+	file, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse journal %s line %d: %w", j.path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Get returns the entry with given ID, or ErrEntryNotFound.
+func (j *Journal) Get(id int) (Entry, error) {
+	entries, err := j.Entries()
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("%w: %d", ErrEntryNotFound, id)
+}
+
+// UndoneBy returns IDs of entries that were undone, mapped to IDs of the
+// entries that undid them.
+func UndoneBy(entries []Entry) map[int]int {
+	ret := map[int]int{}
+	for _, e := range entries {
+		if e.Undoes != 0 {
+			ret[e.Undoes] = e.ID
+		}
+	}
+	return ret
+}
@@ -0,0 +1,87 @@
+package journal_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/journal"
+)
+
+var _ = Describe("Journal", func() {
+	var (
+		path string
+		j    *journal.Journal
+		now  time.Time
+	)
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "state", "journal.jsonl")
+		j = journal.Open(path)
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+	})
+
+	It("has no entries when the file doesn't exist", func() {
+		entries, err := j.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("appends entries with sequential IDs", func() {
+		first, err := j.Append(journal.Entry{
+			Time:       now,
+			User:       "alice",
+			Command:    "heartbeatctl disable .*",
+			Action:     "disable",
+			Heartbeats: []heartbeat.Heartbeat{{Name: "foo", Enabled: true}, {Name: "bar"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.ID).To(Equal(1))
+
+		second, err := j.Append(journal.Entry{Time: now, Action: "restore", Undoes: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.ID).To(Equal(2))
+
+		entries, err := j.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]journal.Entry{first, second}))
+		Expect(entries[0].HeartbeatNames()).To(Equal([]string{"foo", "bar"}))
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+	})
+
+	It("gets entries by ID", func() {
+		_, err := j.Append(journal.Entry{Time: now, Action: "enable"})
+		Expect(err).NotTo(HaveOccurred())
+
+		e, err := j.Get(1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(e.Action).To(Equal("enable"))
+
+		_, err = j.Get(2)
+		Expect(err).To(MatchError(journal.ErrEntryNotFound))
+		Expect(err).To(MatchError("journal entry not found: 2"))
+	})
+
+	It("reports malformed lines", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("{\"id\":1}\nnope\n"), 0600)).To(Succeed())
+
+		_, err := j.Entries()
+		Expect(err).To(MatchError(ContainSubstring("line 2")))
+	})
+
+	It("maps undone entries to entries that undid them", func() {
+		Expect(journal.UndoneBy([]journal.Entry{
+			{ID: 1},
+			{ID: 2},
+			{ID: 3, Undoes: 1},
+		})).To(Equal(map[int]int{1: 3}))
+	})
+})
@@ -0,0 +1,4 @@
+// manifest package defines the versioned YAML representation of heartbeats,
+// used to export heartbeat definitions and to create or update heartbeats
+// from them, and checks manifests for mistakes before they are used.
+package manifest
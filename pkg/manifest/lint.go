@@ -0,0 +1,336 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"go.yaml.in/yaml/v3"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/giantswarm/heartbeatctl/pkg/conv"
+)
+
+// MinInterval is the shortest interval OpsGenie accepts for heartbeats, in
+// any interval unit.
+const MinInterval = 1
+
+var (
+	// IntervalUnits lists interval units supported by OpsGenie.
+	IntervalUnits = []string{"minutes", "hours", "days"}
+	// AlertPriorities lists priorities of alerts supported by OpsGenie.
+	AlertPriorities = []string{"P1", "P2", "P3", "P4", "P5"}
+)
+
+// fieldLabels are labels heartbeat fields are converted to, which take
+// precedence over labels from alert tags with the same keys.
+var fieldLabels = conv.HeartbeatAsLabels(heartbeat.Heartbeat{Enabled: true, Expired: true})
+
+// yamlErrorLine extracts the line number from YAML syntax errors.
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// Problem is a mistake found in a manifest.
+type Problem struct {
+	// File is the name of the file the problem was found in.
+	File string `json:"file"`
+	// Line and Column point at the offending value, starting at 1. Column is
+	// 0 if only the line is known.
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Column == 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.File, p.Line, p.Column, p.Message)
+}
+
+// Linter checks manifests for mistakes without talking to OpsGenie. It
+// remembers heartbeats it has seen so names defined more than once are found
+// across files.
+type Linter struct {
+	names    map[string]position
+	problems []Problem
+	file     string
+}
+
+// position is where a heartbeat name was defined.
+type position struct {
+	file string
+	line int
+}
+
+func NewLinter() *Linter {
+	return &Linter{names: map[string]position{}}
+}
+
+// Lint checks manifests read from r, reporting problems as found in the file
+// with given name, ordered by their positions.
+func (l *Linter) Lint(file string, r io.Reader) {
+	l.file = file
+	start := len(l.problems)
+	defer func() {
+		found := l.problems[start:]
+		sort.SliceStable(found, func(i, j int) bool {
+			if found[i].Line != found[j].Line {
+				return found[i].Line < found[j].Line
+			}
+			return found[i].Column < found[j].Column
+		})
+	}()
+
+	dec := yaml.NewDecoder(r)
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			l.syntaxError(err)
+			break
+		}
+		if len(doc.Content) > 0 {
+			l.lintDocument(doc.Content[0])
+		}
+	}
+}
+
+// Problems returns problems found in all manifests linted so far.
+func (l *Linter) Problems() []Problem {
+	return l.problems
+}
+
+func (l *Linter) report(node *yaml.Node, format string, a ...interface{}) {
+	l.problems = append(l.problems, Problem{
+		File:    l.file,
+		Line:    node.Line,
+		Column:  node.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func (l *Linter) syntaxError(err error) {
+	p := Problem{File: l.file, Line: 1, Message: err.Error()}
+	if m := yamlErrorLine.FindStringSubmatch(err.Error()); m != nil {
+		p.Line, _ = strconv.Atoi(m[1])
+		p.Message = m[2]
+	}
+	l.problems = append(l.problems, p)
+}
+
+func (l *Linter) lintDocument(node *yaml.Node) {
+	if node.ShortTag() == "!!null" {
+		return
+	}
+	fields := l.mapping(node, "", "apiVersion", "kind", "metadata", "spec")
+	if fields == nil {
+		return
+	}
+
+	if v := l.required(node, fields, "", "apiVersion"); v != nil && v.Value != APIVersion {
+		l.report(v, "unsupported apiVersion %q, expected %q", v.Value, APIVersion)
+	}
+	if v := l.required(node, fields, "", "kind"); v != nil && v.Value != KindHeartbeat {
+		l.report(v, "unsupported kind %q, expected %q", v.Value, KindHeartbeat)
+	}
+	if v := l.required(node, fields, "", "metadata"); v != nil {
+		l.lintMetadata(v)
+	}
+	if v := l.required(node, fields, "", "spec"); v != nil {
+		l.lintSpec(v)
+	}
+}
+
+func (l *Linter) lintMetadata(node *yaml.Node) {
+	fields := l.mapping(node, "metadata.", "name")
+	if fields == nil {
+		return
+	}
+
+	name := l.required(node, fields, "metadata.", "name")
+	if name == nil || !l.string(name, "metadata.name") {
+		return
+	}
+	if strings.TrimSpace(name.Value) == "" {
+		l.report(name, "metadata.name must not be empty")
+		return
+	}
+	if first, ok := l.names[name.Value]; ok {
+		l.report(name, "heartbeat %q is already defined at %s:%d", name.Value, first.file, first.line)
+		return
+	}
+	l.names[name.Value] = position{file: l.file, line: name.Line}
+}
+
+func (l *Linter) lintSpec(node *yaml.Node) {
+	fields := l.mapping(node, "spec.",
+		"description", "interval", "intervalUnit", "enabled", "ownerTeam",
+		"alertMessage", "alertTags", "alertPriority",
+	)
+	if fields == nil {
+		return
+	}
+
+	for _, key := range []string{"description", "ownerTeam", "alertMessage"} {
+		if v, ok := fields[key]; ok {
+			l.string(v, "spec."+key)
+		}
+	}
+	if v, ok := fields["enabled"]; ok && v.ShortTag() != "!!bool" {
+		l.report(v, "spec.enabled must be true or false")
+	}
+
+	if v := l.required(node, fields, "spec.", "interval"); v != nil {
+		interval, err := strconv.Atoi(v.Value)
+		switch {
+		case v.ShortTag() != "!!int" || err != nil:
+			l.report(v, "spec.interval must be a whole number")
+		case interval < MinInterval:
+			l.report(v, "spec.interval must be at least %d, got %d", MinInterval, interval)
+		}
+	}
+	if v := l.required(node, fields, "spec.", "intervalUnit"); v != nil && !contains(IntervalUnits, v.Value) {
+		l.report(v, "unsupported spec.intervalUnit %q, must be one of %s", v.Value, strings.Join(IntervalUnits, ", "))
+	}
+	if v, ok := fields["alertPriority"]; ok && !contains(AlertPriorities, v.Value) {
+		l.report(v, "unsupported spec.alertPriority %q, must be one of %s", v.Value, strings.Join(AlertPriorities, ", "))
+	}
+
+	if v, ok := fields["alertTags"]; ok {
+		l.lintTags(v)
+	}
+}
+
+// lintTags checks alert tags convert to labels selectors can match the way
+// their authors likely intended.
+func (l *Linter) lintTags(node *yaml.Node) {
+	if node.Kind != yaml.SequenceNode {
+		l.report(node, "spec.alertTags must be a list")
+		return
+	}
+
+	keys := map[string]string{}
+	for _, tag := range node.Content {
+		if !l.string(tag, "alert tag") {
+			continue
+		}
+
+		key, value, isPair := strings.Cut(tag.Value, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case key == "":
+			l.report(tag, "malformed alert tag %q, key must not be empty", tag.Value)
+			continue
+		case isPair && value == "":
+			l.report(tag, "malformed alert tag %q, value must not be empty", tag.Value)
+			continue
+		case strings.Contains(value, ":"):
+			l.report(tag, "malformed alert tag %q, only the first ':' separates key and value", tag.Value)
+			continue
+		}
+
+		if len(validation.IsQualifiedName(key)) > 0 {
+			l.report(tag, "alert tag %q can't be used in selectors, %q is not a valid label key", tag.Value, key)
+			continue
+		}
+		if isPair && len(validation.IsValidLabelValue(value)) > 0 {
+			l.report(tag, "alert tag %q can't be used in selectors, %q is not a valid label value", tag.Value, value)
+			continue
+		}
+
+		if fieldLabels.Has(key) {
+			l.report(tag, "alert tag %q is ignored by selectors, label %q is taken by a heartbeat field", tag.Value, key)
+			continue
+		}
+		if first, ok := keys[key]; ok {
+			l.report(tag, "alert tag %q is ignored by selectors, label %q is already set by tag %q", tag.Value, key, first)
+			continue
+		}
+		keys[key] = tag.Value
+	}
+}
+
+// mapping returns values of fields of given mapping node by their keys,
+// reporting fields other than given known ones. It returns nil if the node
+// is not a mapping.
+func (l *Linter) mapping(node *yaml.Node, path string, known ...string) map[string]*yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		what := "manifest"
+		if path != "" {
+			what = strings.TrimSuffix(path, ".")
+		}
+		l.report(node, "%s must be a mapping", what)
+		return nil
+	}
+
+	fields := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch {
+		case !contains(known, key.Value):
+			l.report(key, "unknown field %q", path+key.Value)
+		case fields[key.Value] != nil:
+			l.report(key, "field %q is set more than once", path+key.Value)
+		default:
+			fields[key.Value] = value
+		}
+	}
+	return fields
+}
+
+// required returns the value of the named field, reporting it at the
+// mapping node if it's missing or empty.
+func (l *Linter) required(node *yaml.Node, fields map[string]*yaml.Node, path, key string) *yaml.Node {
+	v, ok := fields[key]
+	if !ok || v.ShortTag() == "!!null" {
+		l.report(node, "missing required field %q", path+key)
+		return nil
+	}
+	return v
+}
+
+// string reports given node unless it holds a single value, and returns
+// whether it does.
+func (l *Linter) string(node *yaml.Node, what string) bool {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() == "!!null" {
+		l.report(node, "%s must be a string", what)
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ProblemsError is returned when manifests have problems found by Linter.
+type ProblemsError struct {
+	Problems []Problem
+}
+
+func (e *ProblemsError) Error() string {
+	var b strings.Builder
+	if len(e.Problems) == 1 {
+		b.WriteString("found 1 problem in manifests:")
+	} else {
+		fmt.Fprintf(&b, "found %d problems in manifests:", len(e.Problems))
+	}
+	for _, p := range e.Problems {
+		b.WriteString("\n  ")
+		b.WriteString(p.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,164 @@
+package manifest_test
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// lint returns problems found in given lines of a manifest file.
+func lint(lines ...string) []string {
+	l := manifest.NewLinter()
+	l.Lint("hb.yaml", strings.NewReader(strings.Join(lines, "\n")))
+
+	var problems []string
+	for _, p := range l.Problems() {
+		problems = append(problems, p.String())
+	}
+	return problems
+}
+
+// header is the beginning of a valid manifest up to its spec.
+var header = []string{
+	"apiVersion: heartbeatctl.giantswarm.io/v1alpha1",
+	"kind: Heartbeat",
+	"metadata:",
+	"  name: foo",
+}
+
+var _ = Describe("Linter", func() {
+	It("finds no problems in valid manifests", func() {
+		Expect(lint(append(header,
+			"spec:",
+			"  interval: 10",
+			"  intervalUnit: minutes",
+			"  enabled: false",
+			"  ownerTeam: team-rocket",
+			"  alertTags: [tagged, 'installation: gorilla', 'giantswarm.io/team: rocket']",
+			"  alertPriority: P2",
+			"---",
+			"# empty document",
+		)...)).To(BeEmpty())
+	})
+
+	DescribeTable("reports problems with their positions",
+		func(spec []string, expected ...string) {
+			Expect(lint(append(append(header, "spec:"), spec...)...)).To(Equal(expected))
+		},
+		Entry("invalid interval unit",
+			[]string{"  interval: 10", "  intervalUnit: minute"},
+			`hb.yaml:7:17: unsupported spec.intervalUnit "minute", must be one of minutes, hours, days`,
+		),
+		Entry("interval below minimum",
+			[]string{"  interval: 0", "  intervalUnit: minutes"},
+			"hb.yaml:6:13: spec.interval must be at least 1, got 0",
+		),
+		Entry("interval not a number",
+			[]string{"  interval: 10m", "  intervalUnit: minutes"},
+			"hb.yaml:6:13: spec.interval must be a whole number",
+		),
+		Entry("unknown priority",
+			[]string{"  interval: 10", "  intervalUnit: minutes", "  alertPriority: P0"},
+			`hb.yaml:8:18: unsupported spec.alertPriority "P0", must be one of P1, P2, P3, P4, P5`,
+		),
+		Entry("missing fields",
+			[]string{"  enabled: yes"},
+			`hb.yaml:6:3: missing required field "spec.interval"`,
+			`hb.yaml:6:3: missing required field "spec.intervalUnit"`,
+			"hb.yaml:6:12: spec.enabled must be true or false",
+		),
+		Entry("unknown field",
+			[]string{"  interval: 10", "  intervalUnit: minutes", "  intervall: 5"},
+			`hb.yaml:8:3: unknown field "spec.intervall"`,
+		),
+		Entry("malformed tags",
+			[]string{
+				"  interval: 10",
+				"  intervalUnit: minutes",
+				"  alertTags:",
+				"    - ': gorilla'",
+				"    - 'installation:'",
+				"    - 'url: https://example.com'",
+				"    - 'managed by: me'",
+				"    - 'team: rocket & co'",
+			},
+			`hb.yaml:9:7: malformed alert tag ": gorilla", key must not be empty`,
+			`hb.yaml:10:7: malformed alert tag "installation:", value must not be empty`,
+			`hb.yaml:11:7: malformed alert tag "url: https://example.com", only the first ':' separates key and value`,
+			`hb.yaml:12:7: alert tag "managed by: me" can't be used in selectors, "managed by" is not a valid label key`,
+			`hb.yaml:13:7: alert tag "team: rocket & co" can't be used in selectors, "rocket & co" is not a valid label value`,
+		),
+		Entry("tags shadowed by fields and other tags",
+			[]string{
+				"  interval: 10",
+				"  intervalUnit: minutes",
+				"  alertTags: ['name: bar', 'installation: gorilla', 'installation: gaia']",
+			},
+			`hb.yaml:8:15: alert tag "name: bar" is ignored by selectors, label "name" is taken by a heartbeat field`,
+			`hb.yaml:8:53: alert tag "installation: gaia" is ignored by selectors, label "installation" is already set by tag "installation: gorilla"`,
+		),
+	)
+
+	It("reports unsupported versions and missing sections", func() {
+		Expect(lint(
+			"apiVersion: heartbeatctl.giantswarm.io/v1",
+			"kind: Heartbeat",
+			"metadata: {}",
+		)).To(Equal([]string{
+			`hb.yaml:1:1: missing required field "spec"`,
+			`hb.yaml:1:13: unsupported apiVersion "heartbeatctl.giantswarm.io/v1", expected "heartbeatctl.giantswarm.io/v1alpha1"`,
+			`hb.yaml:3:11: missing required field "metadata.name"`,
+		}))
+	})
+
+	It("reports names defined more than once across files", func() {
+		valid := strings.Join(append(header, "spec: {interval: 1, intervalUnit: hours}"), "\n")
+
+		l := manifest.NewLinter()
+		l.Lint("a.yaml", strings.NewReader(valid))
+		l.Lint("b.yaml", strings.NewReader("---\n"+valid))
+
+		Expect(l.Problems()).To(Equal([]manifest.Problem{
+			{File: "b.yaml", Line: 5, Column: 9, Message: `heartbeat "foo" is already defined at a.yaml:4`},
+		}))
+	})
+
+	It("reports malformed YAML", func() {
+		Expect(lint(append(header, "spec: [")...)).To(Equal([]string{
+			"hb.yaml:5: did not find expected node content",
+		}))
+	})
+})
+
+var _ = Describe("Schema", func() {
+	var schema map[string]interface{}
+
+	BeforeEach(func() {
+		Expect(json.Unmarshal(manifest.Schema, &schema)).To(Succeed())
+	})
+
+	It("describes the current API version", func() {
+		Expect(schema).To(HaveKeyWithValue("properties", HaveKeyWithValue("apiVersion", HaveKeyWithValue("const", manifest.APIVersion))))
+		Expect(schema).To(HaveKeyWithValue("properties", HaveKeyWithValue("kind", HaveKeyWithValue("const", manifest.KindHeartbeat))))
+	})
+
+	It("allows the same values as the linter", func() {
+		spec := schema["properties"].(map[string]interface{})["spec"].(map[string]interface{})["properties"].(map[string]interface{})
+
+		Expect(spec["intervalUnit"]).To(HaveKeyWithValue("enum", ConsistOf(toInterfaces(manifest.IntervalUnits)...)))
+		Expect(spec["alertPriority"]).To(HaveKeyWithValue("enum", ConsistOf(toInterfaces(manifest.AlertPriorities)...)))
+		Expect(spec["interval"]).To(HaveKeyWithValue("minimum", BeNumerically("==", manifest.MinInterval)))
+	})
+})
+
+func toInterfaces(values []string) []interface{} {
+	ret := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		ret = append(ret, v)
+	}
+	return ret
+}
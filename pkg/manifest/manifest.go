@@ -0,0 +1,228 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	// APIVersion is the version of the manifest format.
+	APIVersion = "heartbeatctl.giantswarm.io/v1alpha1"
+	// KindHeartbeat is the kind of manifests describing a heartbeat.
+	KindHeartbeat = "Heartbeat"
+)
+
+// Heartbeat is a manifest describing the desired configuration of a
+// heartbeat, e.g.:
+//
+//	apiVersion: heartbeatctl.giantswarm.io/v1alpha1
+//	kind: Heartbeat
+//	metadata:
+//	  name: gorilla
+//	spec:
+//	  interval: 10
+//	  intervalUnit: minutes
+//	  ownerTeam: team-rocket
+//	  alertPriority: P2
+//	  alertTags:
+//	    - "installation: gorilla"
+type Heartbeat struct {
+	APIVersion string   `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string   `yaml:"kind" json:"kind"`
+	Metadata   Metadata `yaml:"metadata" json:"metadata"`
+	Spec       Spec     `yaml:"spec" json:"spec"`
+}
+
+// Metadata identifies the heartbeat a manifest describes.
+type Metadata struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+// Spec holds the configuration of a heartbeat, mirroring fields of OpsGenie
+// heartbeats.
+type Spec struct {
+	Description  string `yaml:"description,omitempty" json:"description,omitempty"`
+	Interval     int    `yaml:"interval" json:"interval"`
+	IntervalUnit string `yaml:"intervalUnit" json:"intervalUnit"`
+	// Enabled is whether the heartbeat should be enabled, left as is when
+	// unset.
+	Enabled *bool `yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// OwnerTeam is the name of the team owning the heartbeat.
+	OwnerTeam     string   `yaml:"ownerTeam,omitempty" json:"ownerTeam,omitempty"`
+	AlertMessage  string   `yaml:"alertMessage,omitempty" json:"alertMessage,omitempty"`
+	AlertTags     []string `yaml:"alertTags,omitempty" json:"alertTags,omitempty"`
+	AlertPriority string   `yaml:"alertPriority,omitempty" json:"alertPriority,omitempty"`
+}
+
+// FromHeartbeat returns a manifest describing given heartbeat, including
+// whether it's enabled.
+func FromHeartbeat(h heartbeat.Heartbeat) Heartbeat {
+	enabled := h.Enabled
+	return Heartbeat{
+		APIVersion: APIVersion,
+		Kind:       KindHeartbeat,
+		Metadata:   Metadata{Name: h.Name},
+		Spec: Spec{
+			Description:   h.Description,
+			Interval:      h.Interval,
+			IntervalUnit:  h.IntervalUnit,
+			Enabled:       &enabled,
+			OwnerTeam:     h.OwnerTeam.Name,
+			AlertMessage:  h.AlertMessage,
+			AlertTags:     h.AlertTags,
+			AlertPriority: h.AlertPriority,
+		},
+	}
+}
+
+// AddRequest returns a request creating the heartbeat described by the
+// manifest.
+func (m Heartbeat) AddRequest() *heartbeat.AddRequest {
+	return &heartbeat.AddRequest{
+		Name:          m.Metadata.Name,
+		Description:   m.Spec.Description,
+		Interval:      m.Spec.Interval,
+		IntervalUnit:  heartbeat.Unit(m.Spec.IntervalUnit),
+		Enabled:       m.Spec.Enabled,
+		OwnerTeam:     og.OwnerTeam{Name: m.Spec.OwnerTeam},
+		AlertMessage:  m.Spec.AlertMessage,
+		AlertTag:      m.Spec.AlertTags,
+		AlertPriority: m.Spec.AlertPriority,
+	}
+}
+
+// UpdateRequest returns a request updating an existing heartbeat to match the
+// manifest.
+func (m Heartbeat) UpdateRequest() *heartbeat.UpdateRequest {
+	return &heartbeat.UpdateRequest{
+		Name:          m.Metadata.Name,
+		Description:   m.Spec.Description,
+		Interval:      m.Spec.Interval,
+		IntervalUnit:  heartbeat.Unit(m.Spec.IntervalUnit),
+		Enabled:       m.Spec.Enabled,
+		OwnerTeam:     og.OwnerTeam{Name: m.Spec.OwnerTeam},
+		AlertMessage:  m.Spec.AlertMessage,
+		AlertTag:      m.Spec.AlertTags,
+		AlertPriority: m.Spec.AlertPriority,
+	}
+}
+
+// Matches returns true if given heartbeat already has the configuration
+// described by the manifest. Whether it's enabled is only compared if the
+// manifest sets it. Neither are its description, alert message, tags and
+// priority: OpsGenie only updates those when they're given and fills in
+// defaults for a missing message and priority, so a manifest leaving them
+// empty can't change them anyway.
+func (m Heartbeat) Matches(h heartbeat.Heartbeat) bool {
+	switch {
+	case h.Name != m.Metadata.Name,
+		h.Interval != m.Spec.Interval,
+		h.IntervalUnit != m.Spec.IntervalUnit,
+		h.OwnerTeam.Name != m.Spec.OwnerTeam,
+		m.Spec.Description != "" && h.Description != m.Spec.Description,
+		m.Spec.AlertMessage != "" && h.AlertMessage != m.Spec.AlertMessage,
+		m.Spec.AlertPriority != "" && h.AlertPriority != m.Spec.AlertPriority,
+		m.Spec.Enabled != nil && h.Enabled != *m.Spec.Enabled:
+		return false
+	}
+
+	if len(m.Spec.AlertTags) == 0 {
+		return true
+	}
+	if len(h.AlertTags) != len(m.Spec.AlertTags) {
+		return false
+	}
+	for i := range h.AlertTags {
+		if h.AlertTags[i] != m.Spec.AlertTags[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Uncleared returns names of fields given heartbeat has set that the manifest
+// leaves empty, which updating the heartbeat to match the manifest leaves in
+// place since OpsGenie only changes the description and alert tags when
+// they're given. Alert messages and priorities are left out, OpsGenie never
+// leaves them empty.
+func (m Heartbeat) Uncleared(h heartbeat.Heartbeat) []string {
+	var fields []string
+	if m.Spec.Description == "" && h.Description != "" {
+		fields = append(fields, "description")
+	}
+	if len(m.Spec.AlertTags) == 0 && len(h.AlertTags) > 0 {
+		fields = append(fields, "alertTags")
+	}
+	return fields
+}
+
+// Encode writes given manifests to w as a stream of YAML documents.
+func Encode(w io.Writer, manifests []Heartbeat) error {
+	if len(manifests) == 0 {
+		return nil
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	for _, m := range manifests {
+		if err := enc.Encode(m); err != nil {
+			return err
+		}
+	}
+	return enc.Close()
+}
+
+// Decode reads manifests from a stream of YAML documents, skipping empty
+// documents. Documents of unknown API versions or kinds, or with unknown
+// fields, are rejected.
+func Decode(r io.Reader) ([]Heartbeat, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var ret []Heartbeat
+	for i := 1; ; i++ {
+		var m Heartbeat
+		err := dec.Decode(&m)
+		if errors.Is(err, io.EOF) {
+			return ret, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %w", i, err)
+		}
+		if m.APIVersion == "" && m.Kind == "" && m.Metadata == (Metadata{}) {
+			continue
+		}
+		if m.APIVersion != APIVersion || m.Kind != KindHeartbeat {
+			return nil, fmt.Errorf(
+				"document %d: unsupported apiVersion %q and kind %q, expected %q and %q",
+				i, m.APIVersion, m.Kind, APIVersion, KindHeartbeat,
+			)
+		}
+		ret = append(ret, m)
+	}
+}
+
+// ReadFile reads manifests from given file, or from standard input if the
+// path is "-".
+func ReadFile(path string, stdin io.Reader) ([]Heartbeat, error) {
+	if path == "-" {
+		return Decode(stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	manifests, err := Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests from %s: %w", path, err)
+	}
+	return manifests, nil
+}
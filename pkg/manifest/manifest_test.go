@@ -0,0 +1,157 @@
+package manifest_test
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+var _ = Describe("Heartbeat", func() {
+	var h heartbeat.Heartbeat
+
+	BeforeEach(func() {
+		h = heartbeat.Heartbeat{
+			Name:          "foo",
+			Description:   "Foo heartbeat",
+			Interval:      10,
+			IntervalUnit:  "minutes",
+			Enabled:       true,
+			Expired:       true,
+			OwnerTeam:     og.OwnerTeam{Id: "123", Name: "team-rocket"},
+			AlertTags:     []string{"tagged", "installation: gorilla"},
+			AlertPriority: "P2",
+			AlertMessage:  "Foo is down",
+		}
+	})
+
+	It("round trips heartbeats through YAML", func() {
+		var buf bytes.Buffer
+		Expect(manifest.Encode(&buf, []manifest.Heartbeat{
+			manifest.FromHeartbeat(h),
+			manifest.FromHeartbeat(heartbeat.Heartbeat{Name: "bar", Interval: 1, IntervalUnit: "hours"}),
+		})).To(Succeed())
+
+		Expect(buf.String()).To(Equal(strings.Join([]string{
+			"apiVersion: heartbeatctl.giantswarm.io/v1alpha1",
+			"kind: Heartbeat",
+			"metadata:",
+			"  name: foo",
+			"spec:",
+			"  description: Foo heartbeat",
+			"  interval: 10",
+			"  intervalUnit: minutes",
+			"  enabled: true",
+			"  ownerTeam: team-rocket",
+			"  alertMessage: Foo is down",
+			"  alertTags:",
+			"    - tagged",
+			"    - 'installation: gorilla'",
+			"  alertPriority: P2",
+			"---",
+			"apiVersion: heartbeatctl.giantswarm.io/v1alpha1",
+			"kind: Heartbeat",
+			"metadata:",
+			"  name: bar",
+			"spec:",
+			"  interval: 1",
+			"  intervalUnit: hours",
+			"  enabled: false",
+			"",
+		}, "\n")))
+
+		manifests, err := manifest.Decode(&buf)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifests).To(HaveLen(2))
+		Expect(manifests[0]).To(Equal(manifest.FromHeartbeat(h)))
+		Expect(manifests[0].Matches(h)).To(BeTrue())
+	})
+
+	It("converts manifests to requests", func() {
+		m := manifest.FromHeartbeat(h)
+
+		add := m.AddRequest()
+		Expect(add.Name).To(Equal("foo"))
+		Expect(add.IntervalUnit).To(Equal(heartbeat.Minutes))
+		Expect(add.OwnerTeam).To(Equal(og.OwnerTeam{Name: "team-rocket"}))
+		Expect(add.AlertTag).To(Equal(h.AlertTags))
+		Expect(*add.Enabled).To(BeTrue())
+
+		m.Spec.Enabled = nil
+		update := m.UpdateRequest()
+		Expect(update.Name).To(Equal("foo"))
+		Expect(update.Interval).To(Equal(10))
+		Expect(update.Enabled).To(BeNil())
+	})
+
+	DescribeTable("matches heartbeats with the described configuration",
+		func(change func(*manifest.Heartbeat), matches bool) {
+			m := manifest.FromHeartbeat(h)
+			change(&m)
+			Expect(m.Matches(h)).To(Equal(matches))
+		},
+		Entry("same", func(*manifest.Heartbeat) {}, true),
+		Entry("enabled unset", func(m *manifest.Heartbeat) { m.Spec.Enabled = nil }, true),
+		Entry("disabled", func(m *manifest.Heartbeat) { m.Spec.Enabled = new(bool) }, false),
+		Entry("interval", func(m *manifest.Heartbeat) { m.Spec.Interval = 5 }, false),
+		Entry("owner team", func(m *manifest.Heartbeat) { m.Spec.OwnerTeam = "ops" }, false),
+		Entry("tags", func(m *manifest.Heartbeat) { m.Spec.AlertTags = []string{"tagged"} }, false),
+		Entry("tag order", func(m *manifest.Heartbeat) { m.Spec.AlertTags = []string{"installation: gorilla", "tagged"} }, false),
+		Entry("message", func(m *manifest.Heartbeat) { m.Spec.AlertMessage = "gone" }, false),
+		Entry("description unset", func(m *manifest.Heartbeat) { m.Spec.Description = "" }, true),
+		Entry("message unset", func(m *manifest.Heartbeat) { m.Spec.AlertMessage = "" }, true),
+		Entry("tags unset", func(m *manifest.Heartbeat) { m.Spec.AlertTags = nil }, true),
+		Entry("priority unset", func(m *manifest.Heartbeat) { m.Spec.AlertPriority = "" }, true),
+	)
+
+	It("lists fields it leaves empty that updates can't clear", func() {
+		m := manifest.FromHeartbeat(h)
+		Expect(m.Uncleared(h)).To(BeEmpty())
+
+		m.Spec.Description = ""
+		m.Spec.AlertMessage = ""
+		m.Spec.AlertTags = nil
+		Expect(m.Uncleared(h)).To(Equal([]string{"description", "alertTags"}))
+	})
+
+	DescribeTable("fails to decode invalid manifests",
+		func(input, message string) {
+			_, err := manifest.Decode(strings.NewReader(input))
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("unknown kind",
+			"apiVersion: heartbeatctl.giantswarm.io/v1alpha1\nkind: Alert\nmetadata: {name: foo}\n",
+			`document 1: unsupported apiVersion "heartbeatctl.giantswarm.io/v1alpha1" and kind "Alert"`,
+		),
+		Entry("unknown field",
+			"---\n---\napiVersion: heartbeatctl.giantswarm.io/v1alpha1\nkind: Heartbeat\nspec: {intervall: 5}\n",
+			"document 2: yaml: unmarshal errors:\n  line 5: field intervall not found",
+		),
+		Entry("malformed YAML", "kind: [", "document 1: yaml:"),
+	)
+
+	It("skips empty documents", func() {
+		manifests, err := manifest.Decode(strings.NewReader("---\n# nothing here\n---\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifests).To(BeEmpty())
+	})
+})
+
+var _ = Describe("SetOwner", func() {
+	It("replaces ownership tags of other sets", func() {
+		m := manifest.Heartbeat{Spec: manifest.Spec{AlertTags: []string{
+			"tagged", "managed-by: heartbeatctl", "heartbeatctl.giantswarm.io/owner: clusters",
+		}}}
+
+		m.SetOwner("installations")
+		Expect(m.Spec.AlertTags).To(Equal([]string{
+			"tagged", "managed-by: heartbeatctl", "heartbeatctl.giantswarm.io/owner: installations",
+		}))
+		Expect(manifest.OwnerSelector("installations")).To(Equal("heartbeatctl.giantswarm.io/owner=installations,managed-by=heartbeatctl"))
+	})
+})
@@ -0,0 +1,40 @@
+package manifest
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// ManagedByTag marks heartbeats as managed by heartbeatctl.
+	ManagedByTag = "managed-by: heartbeatctl"
+	// OwnerLabel is the key of the tag naming the set of heartbeats a
+	// heartbeat managed by heartbeatctl belongs to.
+	OwnerLabel = "heartbeatctl.giantswarm.io/owner"
+)
+
+// OwnerTags returns alert tags marking heartbeats as managed by heartbeatctl
+// as part of the set with given ID.
+func OwnerTags(owner string) []string {
+	return []string{ManagedByTag, OwnerLabel + ": " + owner}
+}
+
+// OwnerSelector returns a label selector matching heartbeats tagged with
+// OwnerTags of the set with given ID.
+func OwnerSelector(owner string) string {
+	return labels.Set{"managed-by": "heartbeatctl", OwnerLabel: owner}.String()
+}
+
+// SetOwner tags the heartbeat with OwnerTags of the set with given ID,
+// replacing tags of any other set.
+func (m *Heartbeat) SetOwner(owner string) {
+	tags := make([]string, 0, len(m.Spec.AlertTags)+2)
+	for _, tag := range m.Spec.AlertTags {
+		key, _, _ := strings.Cut(tag, ":")
+		if tag != ManagedByTag && strings.TrimSpace(key) != OwnerLabel {
+			tags = append(tags, tag)
+		}
+	}
+	m.Spec.AlertTags = append(tags, OwnerTags(owner)...)
+}
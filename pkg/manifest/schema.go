@@ -0,0 +1,11 @@
+package manifest
+
+import (
+	_ "embed"
+)
+
+// Schema is the JSON Schema of heartbeat manifests of the current API
+// version, for editors and other tools validating manifests.
+//
+//go:embed heartbeat.schema.json
+var Schema []byte
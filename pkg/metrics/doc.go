@@ -0,0 +1,3 @@
+// metrics package writes metrics in the Prometheus text format, served by
+// long-running commands like 'reconcile', 'alertmanager-bridge' and 'relay'.
+package metrics
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the content type of the Prometheus text format.
+const ContentType = "text/plain; version=0.0.4"
+
+// Types of metrics.
+const (
+	Counter = "counter"
+	Gauge   = "gauge"
+)
+
+// Writer writes metrics in the Prometheus text format.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer writing to given response, with its content
+// type set.
+func NewWriter(w http.ResponseWriter) *Writer {
+	w.Header().Set("Content-Type", ContentType)
+	return &Writer{w: w}
+}
+
+// Metric writes the help and type of the metric with given name, to be
+// followed by its samples.
+func (w *Writer) Metric(name, kind, help string) {
+	fmt.Fprintf(w.w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w.w, "# TYPE %s %s\n", name, kind)
+}
+
+// Sample writes a sample of the metric with given name, labelled with given
+// pairs of label names and values.
+func (w *Writer) Sample(name string, value float64, labels ...string) {
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+		}
+		name += "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(w.w, "%s %s\n", name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// Timestamp returns given time in seconds since the epoch, or zero if it's
+// the zero time.
+func Timestamp(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.Unix())
+}
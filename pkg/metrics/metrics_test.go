@@ -0,0 +1,40 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/giantswarm/heartbeatctl/pkg/metrics"
+)
+
+var _ = Describe("Writer", func() {
+	It("writes metrics in the Prometheus text format", func() {
+		rec := httptest.NewRecorder()
+		w := metrics.NewWriter(rec)
+		w.Metric("pings_total", metrics.Counter, "Pings by result.")
+		w.Sample("pings_total", 3, "result", "forwarded")
+		w.Sample("pings_total", 0, "result", `"denied"`)
+		w.Metric("duration_seconds", metrics.Gauge, "How long it took.")
+		w.Sample("duration_seconds", 1.5)
+		w.Metric("timestamp_seconds", metrics.Gauge, "When it happened.")
+		w.Sample("timestamp_seconds", metrics.Timestamp(time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)))
+		w.Sample("timestamp_seconds", metrics.Timestamp(time.Time{}), "a", "1", "b", "2")
+
+		Expect(rec.Header().Get("Content-Type")).To(Equal(metrics.ContentType))
+		Expect(rec.Body.String()).To(Equal(`# HELP pings_total Pings by result.
+# TYPE pings_total counter
+pings_total{result="forwarded"} 3
+pings_total{result="\"denied\""} 0
+# HELP duration_seconds How long it took.
+# TYPE duration_seconds gauge
+duration_seconds 1.5
+# HELP timestamp_seconds When it happened.
+# TYPE timestamp_seconds gauge
+timestamp_seconds 1664971200
+timestamp_seconds{a="1",b="2"} 0
+`))
+	})
+})
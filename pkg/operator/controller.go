@@ -0,0 +1,263 @@
+package operator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/giantswarm/heartbeatctl/pkg/apis/v1alpha1"
+	hbclient "github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/conv"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// Finalizer is added to Heartbeat resources so their heartbeats are deleted
+// before the resources are.
+const Finalizer = "heartbeatctl.giantswarm.io/heartbeat"
+
+// ResourceChanged filters events of Heartbeat resources down to changes of
+// their spec or deletion, which bump the generation, so status updates made
+// by the reconciler don't trigger reconciling the resource again.
+var ResourceChanged predicate.Predicate = predicate.GenerationChangedPredicate{}
+
+// HeartbeatReconciler makes heartbeats match Heartbeat resources.
+type HeartbeatReconciler struct {
+	// Client reads and updates Heartbeat resources.
+	Client client.Client
+	// Ctl changes heartbeats in OpsGenie.
+	Ctl ctl.Port
+	// OpsGenie reads the state of synced heartbeats.
+	OpsGenie hbclient.Port
+	// Owner is the ID of the set heartbeats are tagged as belonging to, see
+	// manifest.OwnerTags. If set, only heartbeats of the set are deleted.
+	Owner string
+	// ResyncPeriod is how often heartbeats are synced again to correct
+	// changes made in OpsGenie, never if zero.
+	ResyncPeriod time.Duration
+	// Logger logs every change made to heartbeats.
+	Logger logrus.FieldLogger
+	// Now returns the current time, recorded as the time of the last sync.
+	Now func() time.Time
+}
+
+// SetupWithManager registers the reconciler with given manager.
+func (r *HeartbeatReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.Heartbeat{}, builder.WithPredicates(ResourceChanged)).
+		Complete(r)
+}
+
+// Reconcile creates or updates the heartbeat described by the requested
+// resource, or deletes it if the resource is being deleted, and reports the
+// outcome in the Ready condition of the resource. Resources with specs
+// OpsGenie wouldn't accept are not retried until they change, while failed
+// requests to OpsGenie are.
+func (r *HeartbeatReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var hb v1alpha1.Heartbeat
+	if err := r.Client.Get(ctx, req.NamespacedName, &hb); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	logger := r.Logger.WithField("resource", req.NamespacedName.String())
+
+	if !hb.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalize(ctx, &hb, logger)
+	}
+	if controllerutil.AddFinalizer(&hb, Finalizer) {
+		if err := r.Client.Update(ctx, &hb); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	m := hb.Manifest()
+	if err := validate(m); err != nil {
+		r.setReady(&hb, metav1.ConditionFalse, v1alpha1.ReasonInvalidSpec, err.Error())
+		return ctrl.Result{}, r.Client.Status().Update(ctx, &hb)
+	}
+	if r.Owner != "" {
+		m.SetOwner(r.Owner)
+	}
+
+	state, err := r.sync(ctx, &hb, m, logger)
+	if err != nil {
+		r.setReady(&hb, metav1.ConditionFalse, v1alpha1.ReasonSyncFailed, err.Error())
+		if statusErr := r.Client.Status().Update(ctx, &hb); statusErr != nil {
+			logger.WithError(statusErr).Error("failed to update status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	var previous v1alpha1.HeartbeatStatus
+	hb.Status.DeepCopyInto(&previous)
+	hb.Status.Name = state.Name
+	hb.Status.Enabled = state.Enabled
+	hb.Status.Expired = state.Expired
+	hb.Status.ObservedGeneration = hb.Generation
+	r.setReady(&hb, metav1.ConditionTrue, v1alpha1.ReasonSynced, "heartbeat matches the resource")
+	// Status only recording the time of the sync is written at most once per
+	// resync period, so frequent events don't each cost an update of the
+	// resource.
+	now := r.Now()
+	if equality.Semantic.DeepEqual(previous, hb.Status) && previous.LastSyncTime != nil && now.Sub(previous.LastSyncTime.Time) < r.ResyncPeriod {
+		return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+	}
+	hb.Status.LastSyncTime = &metav1.Time{Time: now}
+	if err := r.Client.Status().Update(ctx, &hb); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+// sync applies given manifest of the resource, deleting the heartbeat synced
+// before if the resource was renamed, and returns the state of the heartbeat
+// afterwards.
+func (r *HeartbeatReconciler) sync(ctx context.Context, hb *v1alpha1.Heartbeat, m manifest.Heartbeat, logger logrus.FieldLogger) (heartbeatState, error) {
+	actions, err := r.Ctl.Apply([]manifest.Heartbeat{m})
+	for _, a := range actions {
+		if !a.Unchanged {
+			logger.WithFields(logrus.Fields{"heartbeat": a.Heartbeat, "action": a.Action}).Info(logMessages[a.Action])
+		}
+	}
+	if err != nil {
+		return heartbeatState{}, fmt.Errorf("failed to apply heartbeat: %w", err)
+	}
+
+	if previous := hb.Status.Name; previous != "" && previous != m.Metadata.Name {
+		if err := r.delete(ctx, previous, logger); err != nil {
+			return heartbeatState{}, err
+		}
+	}
+
+	result, err := r.OpsGenie.Get(ctx, m.Metadata.Name)
+	if err != nil {
+		return heartbeatState{}, fmt.Errorf("failed to get heartbeat: %w", err)
+	}
+	h := result.Heartbeat
+	return heartbeatState{Name: h.Name, Enabled: h.Enabled, Expired: h.Expired}, nil
+}
+
+// heartbeatState is the state of a heartbeat reported in resource status.
+type heartbeatState struct {
+	Name    string
+	Enabled bool
+	Expired bool
+}
+
+// finalize deletes the heartbeat last synced from given resource being
+// deleted, and then lets the resource go.
+func (r *HeartbeatReconciler) finalize(ctx context.Context, hb *v1alpha1.Heartbeat, logger logrus.FieldLogger) error {
+	if !controllerutil.ContainsFinalizer(hb, Finalizer) {
+		return nil
+	}
+	if hb.Status.Name != "" {
+		if err := r.delete(ctx, hb.Status.Name, logger); err != nil {
+			return err
+		}
+	}
+	controllerutil.RemoveFinalizer(hb, Finalizer)
+	return r.Client.Update(ctx, hb)
+}
+
+// delete deletes the heartbeat with given name, unless it's gone already or,
+// with an owner configured, no longer belongs to the set.
+func (r *HeartbeatReconciler) delete(ctx context.Context, name string, logger logrus.FieldLogger) error {
+	h, err := getOwned(ctx, r.OpsGenie, name, r.Owner)
+	if err != nil || h == nil {
+		return err
+	}
+
+	if _, err := r.OpsGenie.Delete(ctx, name); err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete heartbeat: %w", err)
+	}
+	logger.WithFields(logrus.Fields{"heartbeat": name, "action": ctl.ActionDelete}).Info(logMessages[ctl.ActionDelete])
+	return nil
+}
+
+// getOwned returns the heartbeat with given name, or nil if it doesn't exist
+// or, with an owner given, isn't tagged as belonging to the set.
+func getOwned(ctx context.Context, opsGenie hbclient.Port, name, owner string) (*heartbeat.Heartbeat, error) {
+	result, err := opsGenie.Get(ctx, name)
+	if isNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get heartbeat: %w", err)
+	}
+
+	h := result.Heartbeat
+	if owner == "" {
+		return &h, nil
+	}
+	selector, err := labels.Parse(manifest.OwnerSelector(owner))
+	if err != nil {
+		return nil, err
+	}
+	if !selector.Matches(conv.HeartbeatAsLabels(h)) {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+// isNotFound returns true if given error is OpsGenie reporting that the
+// heartbeat doesn't exist.
+func isNotFound(err error) bool {
+	var apiErr *ogclient.ApiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// logMessages maps actions to messages logged when they are taken.
+var logMessages = map[string]string{
+	ctl.ActionCreate: "created heartbeat",
+	ctl.ActionUpdate: "updated heartbeat",
+	ctl.ActionDelete: "deleted heartbeat",
+}
+
+func (r *HeartbeatReconciler) setReady(hb *v1alpha1.Heartbeat, status metav1.ConditionStatus, reason, message string) {
+	apimeta.SetStatusCondition(&hb.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionReady,
+		Status:             status,
+		ObservedGeneration: hb.Generation,
+		LastTransitionTime: metav1.NewTime(r.Now()),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// validate checks given manifest of a resource with manifest.Linter, so
+// resources OpsGenie wouldn't accept are reported the same way manifests
+// are.
+func validate(m manifest.Heartbeat) error {
+	var buf bytes.Buffer
+	if err := manifest.Encode(&buf, []manifest.Heartbeat{m}); err != nil {
+		return err
+	}
+
+	l := manifest.NewLinter()
+	l.Lint("spec", &buf)
+	problems := l.Problems()
+	if len(problems) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(problems))
+	for _, p := range problems {
+		messages = append(messages, p.Message)
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
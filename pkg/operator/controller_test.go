@@ -0,0 +1,277 @@
+package operator_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8sfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"github.com/giantswarm/heartbeatctl/pkg/apis/v1alpha1"
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+	"github.com/giantswarm/heartbeatctl/pkg/operator"
+)
+
+var _ = Describe("HeartbeatReconciler", func() {
+	var (
+		ctx        context.Context
+		repo       *fake.Client
+		kube       client.Client
+		logs       *logtest.Hook
+		now        time.Time
+		reconciler *operator.HeartbeatReconciler
+		key        types.NamespacedName
+	)
+
+	newResource := func(spec v1alpha1.HeartbeatSpec) *v1alpha1.Heartbeat {
+		return &v1alpha1.Heartbeat{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Generation: 1},
+			Spec:       spec,
+		}
+	}
+
+	setup := func(objects ...client.Object) {
+		scheme := runtime.NewScheme()
+		Expect(v1alpha1.AddToScheme(scheme)).To(Succeed())
+		kube = k8sfake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(objects...).
+			WithStatusSubresource(&v1alpha1.Heartbeat{}).
+			Build()
+
+		logger, hook := logtest.NewNullLogger()
+		logs = hook
+		reconciler = &operator.HeartbeatReconciler{
+			Client:       kube,
+			Ctl:          ctl.NewCtl(repo),
+			OpsGenie:     repo,
+			ResyncPeriod: 10 * time.Minute,
+			Logger:       logger,
+			Now:          func() time.Time { return now },
+		}
+	}
+
+	reconcile := func() (ctrl.Result, error) {
+		return reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	}
+
+	fetch := func() *v1alpha1.Heartbeat {
+		var hb v1alpha1.Heartbeat
+		Expect(kube.Get(ctx, key, &hb)).To(Succeed())
+		return &hb
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+		key = types.NamespacedName{Namespace: "monitoring", Name: "gorilla"}
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "drifted", Interval: 9, IntervalUnit: "minutes", Enabled: true, AlertTags: []string{"managed"}},
+		)
+	})
+
+	It("creates missing heartbeats and reports them synced", func() {
+		setup(newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes", OwnerTeam: "rocket"}}))
+
+		result, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+
+		Expect(repo.Heartbeats()).To(ContainElement(heartbeat.Heartbeat{
+			Name: "gorilla", Interval: 10, IntervalUnit: "minutes", Enabled: true, OwnerTeam: og.OwnerTeam{Name: "rocket"},
+			AlertPriority: fake.DefaultAlertPriority, AlertMessage: fake.DefaultAlertMessage("gorilla"),
+		}))
+		Expect(logs.LastEntry().Message).To(Equal("created heartbeat"))
+
+		hb := fetch()
+		Expect(hb.Finalizers).To(ConsistOf(operator.Finalizer))
+		Expect(hb.Status.Name).To(Equal("gorilla"))
+		Expect(hb.Status.Enabled).To(BeTrue())
+		Expect(hb.Status.ObservedGeneration).To(Equal(int64(1)))
+		Expect(hb.Status.LastSyncTime.Time).To(BeTemporally("==", now))
+
+		ready := apimeta.FindStatusCondition(hb.Status.Conditions, v1alpha1.ConditionReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+		Expect(ready.Reason).To(Equal(v1alpha1.ReasonSynced))
+	})
+
+	It("updates the sync time of unchanged status once per resync period", func() {
+		setup(newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes"}}))
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		synced := fetch()
+
+		now = now.Add(5 * time.Minute)
+		result, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+		Expect(fetch().ResourceVersion).To(Equal(synced.ResourceVersion))
+		Expect(fetch().Status.LastSyncTime.Time).To(BeTemporally("==", now.Add(-5*time.Minute)))
+
+		now = now.Add(5 * time.Minute)
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetch().Status.LastSyncTime.Time).To(BeTemporally("==", now))
+
+		now = now.Add(time.Minute)
+		_, err = repo.Disable(ctx, "gorilla")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetch().Status.LastSyncTime.Time).To(BeTemporally("==", now))
+	})
+
+	It("isn't triggered by status updates", func() {
+		old := newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes"}})
+		updated := old.DeepCopy()
+		updated.ResourceVersion = "2"
+		updated.Status.Name = "gorilla"
+		Expect(operator.ResourceChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated})).To(BeFalse())
+
+		updated.Generation = 2
+		Expect(operator.ResourceChanged.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated})).To(BeTrue())
+	})
+
+	It("corrects drifted heartbeats named in the spec", func() {
+		disabled := false
+		setup(newResource(v1alpha1.HeartbeatSpec{
+			Name: "drifted",
+			Spec: manifest.Spec{Interval: 5, IntervalUnit: "minutes", Enabled: &disabled, AlertTags: []string{"managed"}},
+		}))
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(Equal([]heartbeat.Heartbeat{
+			{Name: "drifted", Interval: 5, IntervalUnit: "minutes", AlertTags: []string{"managed"}},
+		}))
+		Expect(fetch().Status.Name).To(Equal("drifted"))
+		Expect(fetch().Status.Enabled).To(BeFalse())
+	})
+
+	It("deletes the heartbeat synced before when it's renamed", func() {
+		hb := newResource(v1alpha1.HeartbeatSpec{Name: "renamed", Spec: manifest.Spec{Interval: 9, IntervalUnit: "minutes"}})
+		hb.Status.Name = "drifted"
+		setup(hb)
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(Equal([]heartbeat.Heartbeat{
+			{Name: "renamed", Interval: 9, IntervalUnit: "minutes", Enabled: true, AlertPriority: fake.DefaultAlertPriority, AlertMessage: fake.DefaultAlertMessage("renamed")},
+		}))
+		Expect(fetch().Status.Name).To(Equal("renamed"))
+	})
+
+	It("tags heartbeats with the owner and only deletes heartbeats of the set", func() {
+		hb := newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes"}})
+		setup(hb)
+		reconciler.Owner = "operator"
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(ContainElement(HaveField("AlertTags", Equal(manifest.OwnerTags("operator")))))
+
+		Expect(kube.Delete(ctx, fetch())).To(Succeed())
+		_, err = repo.Add(ctx, &heartbeat.AddRequest{Name: "gorilla-manual"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(HaveLen(2))
+		Expect(repo.Heartbeats()).NotTo(ContainElement(HaveField("Name", "gorilla")))
+	})
+
+	It("deletes the heartbeat through the finalizer", func() {
+		setup(newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes"}}))
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(kube.Delete(ctx, fetch())).To(Succeed())
+		Expect(fetch().DeletionTimestamp).NotTo(BeNil())
+		// Only the heartbeat of the resource is looked up.
+		repo.Fail("List", "", errors.New("too many heartbeats"))
+
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(HaveLen(1))
+		Expect(logs.LastEntry().Message).To(Equal("deleted heartbeat"))
+
+		var hb v1alpha1.Heartbeat
+		Expect(kube.Get(ctx, key, &hb)).NotTo(Succeed())
+	})
+
+	It("doesn't delete the heartbeat once it left the set", func() {
+		setup(newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes"}}))
+		reconciler.Owner = "operator"
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = repo.Update(ctx, &heartbeat.UpdateRequest{Name: "gorilla", Interval: 10, IntervalUnit: heartbeat.Minutes, AlertTag: []string{"adopted"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(kube.Delete(ctx, fetch())).To(Succeed())
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(ContainElement(HaveField("Name", "gorilla")))
+	})
+
+	It("lets resources go when their heartbeat is gone already", func() {
+		setup(newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes"}}))
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = repo.Delete(ctx, "gorilla")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(kube.Delete(ctx, fetch())).To(Succeed())
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("reports invalid specs without retrying", func() {
+		setup(newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 0, IntervalUnit: "fortnights"}}))
+
+		result, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(repo.Heartbeats()).To(HaveLen(1))
+
+		ready := apimeta.FindStatusCondition(fetch().Status.Conditions, v1alpha1.ConditionReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(v1alpha1.ReasonInvalidSpec))
+		Expect(ready.Message).To(Equal(
+			`spec.interval must be at least 1, got 0; unsupported spec.intervalUnit "fortnights", must be one of minutes, hours, days`,
+		))
+	})
+
+	It("reports failed syncs and retries them", func() {
+		setup(newResource(v1alpha1.HeartbeatSpec{Spec: manifest.Spec{Interval: 10, IntervalUnit: "minutes"}}))
+		repo.Fail("Add", "gorilla", errors.New("boom"))
+
+		_, err := reconcile()
+		Expect(err).To(MatchError(ContainSubstring("boom")))
+
+		ready := apimeta.FindStatusCondition(fetch().Status.Conditions, v1alpha1.ConditionReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(v1alpha1.ReasonSyncFailed))
+		Expect(ready.Message).To(ContainSubstring("boom"))
+	})
+
+	It("ignores resources that are gone", func() {
+		setup()
+		result, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+	})
+})
@@ -0,0 +1,47 @@
+package operator_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/giantswarm/heartbeatctl/pkg/apis/v1alpha1"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+var _ = Describe("CRD", func() {
+	var crd apiextensionsv1.CustomResourceDefinition
+
+	BeforeEach(func() {
+		data, err := os.ReadFile("../../config/crd/heartbeatctl.giantswarm.io_heartbeats.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(yaml.UnmarshalStrict(data, &crd)).To(Succeed())
+	})
+
+	It("defines the Heartbeat resource", func() {
+		Expect(crd.Spec.Group).To(Equal(v1alpha1.GroupVersion.Group))
+		Expect(crd.Spec.Names.Kind).To(Equal(manifest.KindHeartbeat))
+		Expect(crd.Spec.Versions).To(HaveLen(1))
+		Expect(crd.Spec.Versions[0].Name).To(Equal(v1alpha1.GroupVersion.Version))
+		Expect(crd.Spec.Versions[0].Subresources.Status).NotTo(BeNil())
+	})
+
+	It("allows the same values as the linter", func() {
+		spec := crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties
+
+		Expect(enum(spec["intervalUnit"])).To(Equal(manifest.IntervalUnits))
+		Expect(enum(spec["alertPriority"])).To(Equal(manifest.AlertPriorities))
+		Expect(*spec["interval"].Minimum).To(BeNumerically("==", manifest.MinInterval))
+	})
+})
+
+func enum(schema apiextensionsv1.JSONSchemaProps) []string {
+	values := make([]string, 0, len(schema.Enum))
+	for _, v := range schema.Enum {
+		values = append(values, string(v.Raw[1:len(v.Raw)-1]))
+	}
+	return values
+}
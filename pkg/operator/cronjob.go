@@ -0,0 +1,379 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	hbclient "github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+	"github.com/giantswarm/heartbeatctl/pkg/schedule"
+)
+
+// Annotations of CronJobs configuring their heartbeats.
+const (
+	// AnnotationEnabled must be "true" for a CronJob to get a heartbeat.
+	AnnotationEnabled = "heartbeatctl.giantswarm.io/enabled"
+	// AnnotationName overrides the name of the heartbeat, by default the
+	// namespace and name of the CronJob joined with '.', see DefaultName.
+	AnnotationName = "heartbeatctl.giantswarm.io/name"
+	// AnnotationGracePeriod overrides the time added to the longest gap
+	// between runs, e.g. "30m".
+	AnnotationGracePeriod = "heartbeatctl.giantswarm.io/grace-period"
+	// AnnotationOwnerTeam sets the team owning the heartbeat.
+	AnnotationOwnerTeam = "heartbeatctl.giantswarm.io/owner-team"
+	// AnnotationAlertPriority sets the priority of alerts of the heartbeat.
+	AnnotationAlertPriority = "heartbeatctl.giantswarm.io/alert-priority"
+)
+
+// Annotations of CronJobs the controller keeps its own state in.
+const (
+	// AnnotationHeartbeat is the name of the heartbeat last synced, so it can
+	// be deleted once the CronJob is no longer annotated or is renamed.
+	AnnotationHeartbeat = "heartbeatctl.giantswarm.io/heartbeat"
+	// AnnotationLastPing is the completion time of the last successful Job
+	// the heartbeat was pinged for.
+	AnnotationLastPing = "heartbeatctl.giantswarm.io/last-ping"
+)
+
+// Alert tags heartbeats of CronJobs are tagged with, so selectors can match
+// them by the namespace and name of their CronJobs.
+const (
+	NamespaceLabel = "namespace"
+	CronJobLabel   = "cronjob"
+)
+
+// DefaultName returns the name of the heartbeat of the CronJob with given
+// namespace and name unless overridden with AnnotationName. Namespace names
+// can't contain dots, so CronJobs never share it.
+func DefaultName(namespace, name string) string {
+	return namespace + "." + name
+}
+
+// CronJobReconciler keeps a heartbeat for every annotated CronJob, with its
+// interval derived from the schedule of the CronJob, pinged whenever one of
+// its Jobs succeeds.
+type CronJobReconciler struct {
+	// Client reads and annotates CronJobs and reads their Jobs.
+	Client client.Client
+	// Ctl changes heartbeats in OpsGenie.
+	Ctl ctl.Port
+	// OpsGenie looks up and pings single heartbeats.
+	OpsGenie hbclient.Port
+	// Owner is the ID of the set heartbeats are tagged as belonging to, see
+	// manifest.OwnerTags. Only heartbeats of the set are ever deleted or
+	// pinged.
+	Owner string
+	// Namespace is the namespace CronJobs are watched in, all namespaces if
+	// empty. Only heartbeats tagged with it are pruned.
+	Namespace string
+	// GracePeriod is the time added to the longest gap between runs of a
+	// CronJob, unless overridden with AnnotationGracePeriod.
+	GracePeriod time.Duration
+	// ResyncPeriod is how often heartbeats are synced again to correct
+	// changes made in OpsGenie, never if zero.
+	ResyncPeriod time.Duration
+	// Logger logs every change made to heartbeats.
+	Logger logrus.FieldLogger
+	// Now returns the current time.
+	Now func() time.Time
+}
+
+// SetupWithManager registers the reconciler with given manager, along with a
+// task deleting heartbeats of CronJobs deleted while it wasn't running.
+func (r *CronJobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		if err := r.Prune(ctx); err != nil {
+			r.Logger.WithError(err).Error("failed to prune heartbeats of deleted CronJobs")
+		}
+		return nil
+	})); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("cronjob").
+		For(&batchv1.CronJob{}, builder.WithPredicates(managedPredicate())).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
+
+// managedPredicate only lets through events of CronJobs that are annotated
+// or have a heartbeat, including updates removing the annotation.
+func managedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return managed(e.Object) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return managed(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return managed(e.ObjectOld) || managed(e.ObjectNew) },
+		GenericFunc: func(e event.GenericEvent) bool { return managed(e.Object) },
+	}
+}
+
+func managed(obj client.Object) bool {
+	return enabled(obj) || obj.GetAnnotations()[AnnotationHeartbeat] != ""
+}
+
+func enabled(obj client.Object) bool {
+	return obj.GetAnnotations()[AnnotationEnabled] == "true"
+}
+
+// Reconcile creates or updates the heartbeat of the requested CronJob and
+// pings it if a Job succeeded since it was last pinged, or deletes it if the
+// CronJob is gone or no longer annotated. CronJobs with schedules or
+// annotations heartbeats can't be derived from are logged and not retried
+// until they change.
+func (r *CronJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := r.Logger.WithField("cronjob", req.NamespacedName.String())
+
+	var cj batchv1.CronJob
+	if err := r.Client.Get(ctx, req.NamespacedName, &cj); err != nil {
+		if client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.deleteHeartbeats(r.cronJobSelector(req.Namespace, req.Name), logger)
+	}
+
+	original := cj.DeepCopy()
+	if !enabled(&cj) {
+		if name := cj.Annotations[AnnotationHeartbeat]; name != "" {
+			if err := r.deleteHeartbeats(r.nameSelector(name), logger); err != nil {
+				return ctrl.Result{}, err
+			}
+			delete(cj.Annotations, AnnotationHeartbeat)
+			delete(cj.Annotations, AnnotationLastPing)
+			return ctrl.Result{}, r.Client.Patch(ctx, &cj, client.MergeFrom(original))
+		}
+		return ctrl.Result{}, nil
+	}
+
+	m, interval, err := r.manifest(&cj)
+	if err != nil {
+		logger.WithError(err).Error("failed to derive heartbeat of CronJob")
+		return ctrl.Result{}, nil
+	}
+
+	actions, err := r.Ctl.Apply([]manifest.Heartbeat{m})
+	for _, a := range actions {
+		if !a.Unchanged {
+			logger.WithFields(logrus.Fields{"heartbeat": a.Heartbeat, "action": a.Action}).Info(logMessages[a.Action])
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to apply heartbeat: %w", err)
+	}
+	if previous := cj.Annotations[AnnotationHeartbeat]; previous != "" && previous != m.Metadata.Name {
+		if err := r.deleteHeartbeats(r.nameSelector(previous), logger); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	cj.Annotations[AnnotationHeartbeat] = m.Metadata.Name
+
+	if err := r.ping(ctx, &cj, m.Metadata.Name, interval, logger); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.Client.Patch(ctx, &cj, client.MergeFrom(original)); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.ResyncPeriod}, nil
+}
+
+// manifest returns a manifest of the heartbeat of given CronJob, along with
+// how long it may go without pings.
+func (r *CronJobReconciler) manifest(cj *batchv1.CronJob) (manifest.Heartbeat, time.Duration, error) {
+	grace := r.GracePeriod
+	if v, ok := cj.Annotations[AnnotationGracePeriod]; ok {
+		var err error
+		if grace, err = time.ParseDuration(v); err != nil {
+			return manifest.Heartbeat{}, 0, fmt.Errorf("invalid %s annotation: %w", AnnotationGracePeriod, err)
+		}
+	}
+
+	expr := cj.Spec.Schedule
+	if cj.Spec.TimeZone != nil && *cj.Spec.TimeZone != "" {
+		expr = fmt.Sprintf("CRON_TZ=%s %s", *cj.Spec.TimeZone, expr)
+	}
+	interval, err := schedule.Derive(expr, grace, r.Now())
+	if err != nil {
+		return manifest.Heartbeat{}, 0, err
+	}
+
+	name := cj.Annotations[AnnotationName]
+	if name == "" {
+		name = DefaultName(cj.Namespace, cj.Name)
+	}
+	enabled := cj.Spec.Suspend == nil || !*cj.Spec.Suspend
+	m := manifest.Heartbeat{
+		APIVersion: manifest.APIVersion,
+		Kind:       manifest.KindHeartbeat,
+		Metadata:   manifest.Metadata{Name: name},
+		Spec: manifest.Spec{
+			Description:   fmt.Sprintf("Pinged when a Job of CronJob %s/%s succeeds.", cj.Namespace, cj.Name),
+			Interval:      interval.Interval,
+			IntervalUnit:  interval.IntervalUnit,
+			Enabled:       &enabled,
+			OwnerTeam:     cj.Annotations[AnnotationOwnerTeam],
+			AlertMessage:  fmt.Sprintf("CronJob %s/%s has not succeeded in time", cj.Namespace, cj.Name),
+			AlertTags:     []string{NamespaceLabel + ": " + cj.Namespace, CronJobLabel + ": " + cj.Name},
+			AlertPriority: cj.Annotations[AnnotationAlertPriority],
+		},
+	}
+	if err := validate(m); err != nil {
+		return manifest.Heartbeat{}, 0, err
+	}
+	m.SetOwner(r.Owner)
+
+	return m, interval.MaxGap + grace, nil
+}
+
+// ping pings the heartbeat with given name if a Job of given CronJob
+// succeeded since it was last pinged, unless the heartbeat would have expired
+// since, recording the completion time of the Job in an annotation.
+func (r *CronJobReconciler) ping(ctx context.Context, cj *batchv1.CronJob, name string, interval time.Duration, logger logrus.FieldLogger) error {
+	completed, err := r.lastSuccess(ctx, cj)
+	if err != nil || completed.IsZero() {
+		return err
+	}
+	if last, err := time.Parse(time.RFC3339, cj.Annotations[AnnotationLastPing]); err == nil && !completed.After(last) {
+		return nil
+	}
+
+	if r.Now().Sub(completed) < interval {
+		h, err := getOwned(ctx, r.OpsGenie, name, r.Owner)
+		if err != nil {
+			return err
+		}
+		if h == nil {
+			return fmt.Errorf("heartbeat \"%s\" to ping doesn't exist or doesn't belong to the set", name)
+		}
+		if _, err := r.OpsGenie.Ping(ctx, name); err != nil {
+			return fmt.Errorf("failed to ping heartbeat: %w", err)
+		}
+		logger.WithFields(logrus.Fields{"heartbeat": name, "action": ctl.ActionPing}).Info("pinged heartbeat")
+	}
+	cj.Annotations[AnnotationLastPing] = completed.UTC().Format(time.RFC3339)
+	return nil
+}
+
+// lastSuccess returns when the last successful Job of given CronJob
+// completed, zero if none did.
+func (r *CronJobReconciler) lastSuccess(ctx context.Context, cj *batchv1.CronJob) (time.Time, error) {
+	var jobs batchv1.JobList
+	if err := r.Client.List(ctx, &jobs, client.InNamespace(cj.Namespace)); err != nil {
+		return time.Time{}, err
+	}
+
+	var last time.Time
+	for _, job := range jobs.Items {
+		owner := metav1.GetControllerOf(&job)
+		if owner == nil || owner.Kind != "CronJob" || owner.Name != cj.Name {
+			continue
+		}
+		if !succeeded(&job) || job.Status.CompletionTime == nil {
+			continue
+		}
+		if t := job.Status.CompletionTime.Time; t.After(last) {
+			last = t
+		}
+	}
+	return last, nil
+}
+
+func succeeded(job *batchv1.Job) bool {
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobComplete && c.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// Prune deletes heartbeats of the set of the reconciler whose CronJobs were
+// deleted or are no longer annotated. With a namespace set, heartbeats of
+// CronJobs in other namespaces are kept, as their CronJobs aren't watched.
+func (r *CronJobReconciler) Prune(ctx context.Context) error {
+	var cronJobs batchv1.CronJobList
+	if err := r.Client.List(ctx, &cronJobs, client.InNamespace(r.Namespace)); err != nil {
+		return err
+	}
+
+	var keep []string
+	if r.Namespace != "" {
+		others, err := r.Ctl.Get(&ctl.SelectorConfig{
+			LabelSelector: manifest.OwnerSelector(r.Owner) + "," + NamespaceLabel + "!=" + r.Namespace,
+		})
+		if err != nil {
+			return err
+		}
+		for _, h := range others {
+			keep = append(keep, h.Name)
+		}
+	}
+	for i := range cronJobs.Items {
+		cj := &cronJobs.Items[i]
+		if !enabled(cj) {
+			continue
+		}
+		if name := cj.Annotations[AnnotationName]; name != "" {
+			keep = append(keep, name)
+		} else {
+			keep = append(keep, DefaultName(cj.Namespace, cj.Name))
+		}
+	}
+
+	pruned, err := ctl.Prune(r.Ctl, r.Owner, keep, false)
+	for _, a := range pruned {
+		r.Logger.WithFields(logrus.Fields{"heartbeat": a.Heartbeat, "action": a.Action}).Info(logMessages[a.Action])
+	}
+	return err
+}
+
+// nameSelector selects the heartbeat with given name if it belongs to the
+// set of the reconciler.
+func (r *CronJobReconciler) nameSelector(name string) *ctl.SelectorConfig {
+	return &ctl.SelectorConfig{
+		NameExpressions: []string{regexp.QuoteMeta(name)},
+		LabelSelector:   manifest.OwnerSelector(r.Owner),
+	}
+}
+
+// cronJobSelector selects heartbeats of the set of the reconciler tagged as
+// belonging to the CronJob with given namespace and name.
+func (r *CronJobReconciler) cronJobSelector(namespace, name string) *ctl.SelectorConfig {
+	return &ctl.SelectorConfig{
+		LabelSelector: labels.Set{
+			"managed-by":        "heartbeatctl",
+			manifest.OwnerLabel: r.Owner,
+			NamespaceLabel:      namespace,
+			CronJobLabel:        name,
+		}.String(),
+	}
+}
+
+// deleteHeartbeats deletes selected heartbeats, having none to delete not
+// being an error.
+func (r *CronJobReconciler) deleteHeartbeats(selector *ctl.SelectorConfig, logger logrus.FieldLogger) error {
+	deleted, err := r.Ctl.Delete(selector)
+	if err != nil && !errors.Is(err, ctl.ErrNoMatch) {
+		return fmt.Errorf("failed to delete heartbeat: %w", err)
+	}
+	for _, name := range deleted {
+		logger.WithFields(logrus.Fields{"heartbeat": name, "action": ctl.ActionDelete}).Info(logMessages[ctl.ActionDelete])
+	}
+	return nil
+}
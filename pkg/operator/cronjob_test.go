@@ -0,0 +1,303 @@
+package operator_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	k8sfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/conv"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+	"github.com/giantswarm/heartbeatctl/pkg/operator"
+)
+
+var _ = Describe("CronJobReconciler", func() {
+	var (
+		ctx        context.Context
+		repo       *fake.Client
+		kube       client.Client
+		logs       *logtest.Hook
+		now        time.Time
+		reconciler *operator.CronJobReconciler
+		key        types.NamespacedName
+	)
+
+	newCronJob := func(schedule string, annotations map[string]string) *batchv1.CronJob {
+		return &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Annotations: annotations},
+			Spec:       batchv1.CronJobSpec{Schedule: schedule},
+		}
+	}
+
+	newJob := func(name string, completed time.Time, condition batchv1.JobConditionType) *batchv1.Job {
+		controller := true
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: key.Namespace,
+				OwnerReferences: []metav1.OwnerReference{
+					{APIVersion: "batch/v1", Kind: "CronJob", Name: key.Name, UID: "uid", Controller: &controller},
+				},
+			},
+			Status: batchv1.JobStatus{
+				CompletionTime: &metav1.Time{Time: completed},
+				Conditions:     []batchv1.JobCondition{{Type: condition, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+
+	setup := func(objects ...client.Object) {
+		kube = k8sfake.NewClientBuilder().
+			WithScheme(clientgoscheme.Scheme).
+			WithObjects(objects...).
+			Build()
+
+		logger, hook := logtest.NewNullLogger()
+		logs = hook
+		reconciler = &operator.CronJobReconciler{
+			Client:       kube,
+			Ctl:          ctl.NewCtl(repo),
+			OpsGenie:     repo,
+			Owner:        "cronjobs",
+			GracePeriod:  30 * time.Minute,
+			ResyncPeriod: 10 * time.Minute,
+			Logger:       logger,
+			Now:          func() time.Time { return now },
+		}
+	}
+
+	reconcile := func() (ctrl.Result, error) {
+		return reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	}
+
+	fetch := func() *batchv1.CronJob {
+		var cj batchv1.CronJob
+		Expect(kube.Get(ctx, key, &cj)).To(Succeed())
+		return &cj
+	}
+
+	messages := func() []string {
+		var ret []string
+		for _, e := range logs.AllEntries() {
+			ret = append(ret, e.Message)
+		}
+		return ret
+	}
+
+	enabled := map[string]string{operator.AnnotationEnabled: "true"}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+		key = types.NamespacedName{Namespace: "backups", Name: "etcd"}
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "manual", Interval: 1, IntervalUnit: "days", AlertTags: []string{"namespace: backups", "cronjob: etcd"}},
+		)
+	})
+
+	It("creates heartbeats with intervals derived from schedules", func() {
+		setup(newCronJob("0 3 * * 1-5", map[string]string{
+			operator.AnnotationEnabled:       "true",
+			operator.AnnotationOwnerTeam:     "rocket",
+			operator.AnnotationAlertPriority: "P2",
+		}))
+
+		result, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(10 * time.Minute))
+
+		h, err := repo.Get(ctx, "backups.etcd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(h.Heartbeat).To(Equal(heartbeat.Heartbeat{
+			Name:          "backups.etcd",
+			Description:   "Pinged when a Job of CronJob backups/etcd succeeds.",
+			Interval:      4350,
+			IntervalUnit:  "minutes",
+			Enabled:       true,
+			OwnerTeam:     og.OwnerTeam{Name: "rocket"},
+			AlertMessage:  "CronJob backups/etcd has not succeeded in time",
+			AlertTags:     append([]string{"namespace: backups", "cronjob: etcd"}, manifest.OwnerTags("cronjobs")...),
+			AlertPriority: "P2",
+		}))
+		Expect(labels.SelectorFromSet(labels.Set{"namespace": "backups", "cronjob": "etcd"}).Matches(conv.HeartbeatAsLabels(h.Heartbeat))).To(BeTrue())
+
+		Expect(fetch().Annotations).To(HaveKeyWithValue(operator.AnnotationHeartbeat, "backups.etcd"))
+		Expect(messages()).To(Equal([]string{"created heartbeat"}))
+	})
+
+	It("names heartbeats of different CronJobs differently", func() {
+		Expect(operator.DefaultName("a-b", "c")).NotTo(Equal(operator.DefaultName("a", "b-c")))
+		Expect(operator.DefaultName("a", "b.c")).To(Equal("a.b.c"))
+	})
+
+	It("uses annotations and the time zone of CronJobs", func() {
+		cj := newCronJob("0 3 * * *", map[string]string{
+			operator.AnnotationEnabled:     "true",
+			operator.AnnotationName:        "etcd-backup",
+			operator.AnnotationGracePeriod: "1h",
+		})
+		zone := "Europe/Berlin"
+		cj.Spec.TimeZone = &zone
+		suspended := true
+		cj.Spec.Suspend = &suspended
+		setup(cj)
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		h, err := repo.Get(ctx, "etcd-backup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(h.Heartbeat.Interval).To(Equal(26))
+		Expect(h.Heartbeat.IntervalUnit).To(Equal("hours"))
+		Expect(h.Heartbeat.Enabled).To(BeFalse())
+	})
+
+	It("pings heartbeats once for every Job that succeeds", func() {
+		setup(
+			newCronJob("0 3 * * *", enabled),
+			newJob("etcd-1", now.Add(-33*time.Hour), batchv1.JobComplete),
+			newJob("etcd-2", now.Add(-9*time.Hour), batchv1.JobComplete),
+			newJob("etcd-3", now.Add(-time.Hour), batchv1.JobFailed),
+		)
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages()).To(Equal([]string{"created heartbeat", "pinged heartbeat"}))
+		Expect(logs.LastEntry().Data).To(HaveKeyWithValue("heartbeat", "backups.etcd"))
+		Expect(fetch().Annotations).To(HaveKeyWithValue(operator.AnnotationLastPing, "2022-10-05T03:00:00Z"))
+
+		logs.Reset()
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages()).To(BeEmpty())
+	})
+
+	It("doesn't ping heartbeats for Jobs that succeeded too long ago", func() {
+		setup(
+			newCronJob("0 3 * * *", enabled),
+			newJob("etcd-1", now.Add(-25*time.Hour), batchv1.JobComplete),
+		)
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages()).To(Equal([]string{"created heartbeat"}))
+		Expect(fetch().Annotations).To(HaveKey(operator.AnnotationLastPing))
+	})
+
+	It("deletes heartbeats of deleted CronJobs", func() {
+		setup(newCronJob("@hourly", enabled))
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(kube.Delete(ctx, fetch())).To(Succeed())
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(ConsistOf(HaveField("Name", "manual")))
+		Expect(logs.LastEntry().Message).To(Equal("deleted heartbeat"))
+	})
+
+	It("deletes heartbeats of CronJobs no longer annotated", func() {
+		setup(newCronJob("@hourly", enabled))
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		cj := fetch()
+		cj.Annotations[operator.AnnotationEnabled] = "false"
+		Expect(kube.Update(ctx, cj)).To(Succeed())
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(HaveLen(1))
+		Expect(fetch().Annotations).NotTo(HaveKey(operator.AnnotationHeartbeat))
+	})
+
+	It("deletes the heartbeat synced before when it's renamed", func() {
+		setup(newCronJob("@hourly", enabled))
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		cj := fetch()
+		cj.Annotations[operator.AnnotationName] = "etcd-backup"
+		Expect(kube.Update(ctx, cj)).To(Succeed())
+		_, err = reconcile()
+		Expect(err).NotTo(HaveOccurred())
+
+		names := []string{}
+		for _, h := range repo.Heartbeats() {
+			names = append(names, h.Name)
+		}
+		Expect(names).To(Equal([]string{"etcd-backup", "manual"}))
+	})
+
+	It("leaves CronJobs with invalid schedules or annotations alone", func() {
+		setup(newCronJob("0 3 * *", map[string]string{
+			operator.AnnotationEnabled:       "true",
+			operator.AnnotationAlertPriority: "P0",
+		}))
+
+		result, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(repo.Heartbeats()).To(HaveLen(1))
+		Expect(logs.LastEntry().Message).To(Equal("failed to derive heartbeat of CronJob"))
+	})
+
+	It("ignores CronJobs that are not annotated", func() {
+		setup(newCronJob("@hourly", nil))
+		repo.Fail("List", "", context.Canceled)
+
+		_, err := reconcile()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Describe("Prune", func() {
+		It("deletes heartbeats of the set whose CronJobs are gone", func() {
+			setup(newCronJob("@hourly", enabled))
+			_, err := reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = repo.Add(ctx, &heartbeat.AddRequest{Name: "gone", AlertTag: manifest.OwnerTags("cronjobs")})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reconciler.Prune(ctx)).To(Succeed())
+			names := []string{}
+			for _, h := range repo.Heartbeats() {
+				names = append(names, h.Name)
+			}
+			Expect(names).To(Equal([]string{"backups.etcd", "manual"}))
+		})
+
+		It("only deletes heartbeats tagged with its namespace if it has one", func() {
+			setup(newCronJob("@hourly", enabled))
+			_, err := reconcile()
+			Expect(err).NotTo(HaveOccurred())
+			reconciler.Namespace = key.Namespace
+			for _, h := range []heartbeat.AddRequest{
+				{Name: "gone", AlertTag: append(manifest.OwnerTags("cronjobs"), operator.NamespaceLabel+": "+key.Namespace)},
+				{Name: "elsewhere", AlertTag: append(manifest.OwnerTags("cronjobs"), operator.NamespaceLabel+": other")},
+			} {
+				_, err := repo.Add(ctx, &h)
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			Expect(reconciler.Prune(ctx)).To(Succeed())
+			names := []string{}
+			for _, h := range repo.Heartbeats() {
+				names = append(names, h.Name)
+			}
+			Expect(names).To(Equal([]string{"backups.etcd", "elsewhere", "manual"}))
+		})
+	})
+})
@@ -0,0 +1,4 @@
+// operator package keeps heartbeats in OpsGenie in sync with Heartbeat
+// custom resources in a Kubernetes cluster, deleting heartbeats along with
+// their resources and reporting the outcome in their status.
+package operator
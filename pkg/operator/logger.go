@@ -0,0 +1,74 @@
+package operator
+
+import (
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+)
+
+// NewLogger returns a logr.Logger, as used by controller-runtime, writing to
+// given logrus logger. Messages of verbosity levels above 0 are logged at
+// debug level.
+func NewLogger(logger *logrus.Logger) logr.Logger {
+	return logr.New(&logrusSink{entry: logrus.NewEntry(logger)})
+}
+
+// logrusSink is a logr.LogSink writing to a logrus logger.
+type logrusSink struct {
+	entry *logrus.Entry
+	name  string
+}
+
+func (s *logrusSink) Init(logr.RuntimeInfo) {}
+
+func (s *logrusSink) Enabled(level int) bool {
+	if level > 0 {
+		return s.entry.Logger.IsLevelEnabled(logrus.DebugLevel)
+	}
+	return s.entry.Logger.IsLevelEnabled(logrus.InfoLevel)
+}
+
+func (s *logrusSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	entry := s.withValues(keysAndValues)
+	if level > 0 {
+		entry.Debug(msg)
+	} else {
+		entry.Info(msg)
+	}
+}
+
+func (s *logrusSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.withValues(keysAndValues).WithError(err).Error(msg)
+}
+
+func (s *logrusSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &logrusSink{entry: s.entry.WithFields(fields(keysAndValues)), name: s.name}
+}
+
+func (s *logrusSink) WithName(name string) logr.LogSink {
+	if s.name != "" {
+		name = s.name + "." + name
+	}
+	return &logrusSink{entry: s.entry, name: name}
+}
+
+// withValues returns an entry with given key/value pairs as fields, along
+// with the name of the logger if it has one.
+func (s *logrusSink) withValues(keysAndValues []interface{}) *logrus.Entry {
+	entry := s.entry.WithFields(fields(keysAndValues))
+	if s.name != "" {
+		entry = entry.WithField("logger", s.name)
+	}
+	return entry
+}
+
+// fields returns given key/value pairs as logrus fields, skipping keys that
+// are not strings.
+func fields(keysAndValues []interface{}) logrus.Fields {
+	ret := logrus.Fields{}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if key, ok := keysAndValues[i].(string); ok {
+			ret[key] = keysAndValues[i+1]
+		}
+	}
+	return ret
+}
@@ -0,0 +1,35 @@
+package operator_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/giantswarm/heartbeatctl/pkg/operator"
+)
+
+var _ = Describe("NewLogger", func() {
+	It("writes messages with their values and names to logrus", func() {
+		logger, hook := logtest.NewNullLogger()
+		log := operator.NewLogger(logger).WithName("controller").WithValues("kind", "Heartbeat")
+
+		log.Info("starting", "workers", 1)
+		log.V(1).Info("not logged")
+		log.Error(errors.New("boom"), "failed")
+
+		entries := hook.AllEntries()
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].Level).To(Equal(logrus.InfoLevel))
+		Expect(entries[0].Message).To(Equal("starting"))
+		Expect(entries[0].Data).To(Equal(logrus.Fields{"logger": "controller", "kind": "Heartbeat", "workers": 1}))
+		Expect(entries[1].Level).To(Equal(logrus.ErrorLevel))
+		Expect(entries[1].Data).To(HaveKeyWithValue(logrus.ErrorKey, MatchError("boom")))
+
+		logger.SetLevel(logrus.DebugLevel)
+		log.V(1).Info("logged")
+		Expect(hook.LastEntry().Level).To(Equal(logrus.DebugLevel))
+	})
+})
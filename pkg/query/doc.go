@@ -0,0 +1,4 @@
+// query package implements a small expression language for selecting
+// heartbeats, combining comparisons on heartbeat fields and labels with 'and',
+// 'or', 'not' and parentheses, and compiles expressions into predicates.
+package query
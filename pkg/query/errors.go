@@ -0,0 +1,14 @@
+package query
+
+import "fmt"
+
+// SyntaxError is returned when a query expression cannot be parsed.
+type SyntaxError struct {
+	// Pos is the 1-based position in the query the error was found at.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d: %s", e.Pos, e.Msg)
+}
@@ -0,0 +1,282 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Keywords of the query language.
+const (
+	keywordAnd   = "and"
+	keywordOr    = "or"
+	keywordNot   = "not"
+	keywordIn    = "in"
+	keywordNotIn = "notin"
+)
+
+// comparisonOperators lists operators comparing a key with a single value,
+// longer ones first so they take precedence over their prefixes.
+var comparisonOperators = []string{"==", "!=", "=~", "!~", ">=", "<=", "=", ">", "<"}
+
+// parser is a recursive descent parser of query expressions:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | primary
+//	primary    = "(" expr ")" | key [ comparison ]
+//	comparison = operator value | ( "in" | "notin" ) "(" value { "," value } ")"
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); !p.eof() {
+		return nil, p.errorf("unexpected %q", p.peekWord())
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword(keywordOr) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword(keywordAnd) {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.acceptKeyword(keywordNot) {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	p.skipSpace()
+	if p.eof() {
+		return nil, p.errorf("unexpected end of query, expected a key or \"(\"")
+	}
+
+	if p.accept("(") {
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpace(); !p.accept(")") {
+			return nil, p.errorf("expected \")\"")
+		}
+		return n, nil
+	}
+
+	key := p.scanKey()
+	if key == "" {
+		return nil, p.errorf("unexpected %q, expected a key or \"(\"", p.peekWord())
+	}
+	if isKeyword(key) {
+		p.pos -= len(key)
+		return nil, p.errorf("unexpected keyword %q, expected a key", key)
+	}
+
+	p.skipSpace()
+	for _, op := range comparisonOperators {
+		if p.accept(op) {
+			return p.parseComparison(key, op)
+		}
+	}
+	for _, op := range []string{keywordNotIn, keywordIn} {
+		if p.acceptKeyword(op) {
+			return p.parseSet(key, op == keywordNotIn)
+		}
+	}
+
+	return existsNode{key: key}, nil
+}
+
+func (p *parser) parseComparison(key, op string) (node, error) {
+	p.skipSpace()
+	start := p.pos
+	value, err := p.scanValue()
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "=", "==":
+		return equalsNode{key: key, value: value}, nil
+	case "!=":
+		return notNode{equalsNode{key: key, value: value}}, nil
+	case "=~", "!~":
+		re, err := regexp.Compile(fmt.Sprintf("^(%s)$", value))
+		if err != nil {
+			return nil, &SyntaxError{Pos: start + 1, Msg: fmt.Sprintf("invalid regular expression: %v", err)}
+		}
+		if op == "!~" {
+			return notNode{matchesNode{key: key, re: re}}, nil
+		}
+		return matchesNode{key: key, re: re}, nil
+	default:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, &SyntaxError{Pos: start + 1, Msg: fmt.Sprintf("operator %s requires a number, got %q", op, value)}
+		}
+		return compareNode{key: key, op: op, value: number}, nil
+	}
+}
+
+func (p *parser) parseSet(key string, negate bool) (node, error) {
+	if p.skipSpace(); !p.accept("(") {
+		return nil, p.errorf("expected \"(\" starting a list of values")
+	}
+
+	n := inNode{key: key, values: map[string]bool{}}
+	for {
+		p.skipSpace()
+		value, err := p.scanValue()
+		if err != nil {
+			return nil, err
+		}
+		n.values[value] = true
+
+		if p.skipSpace(); p.accept(")") {
+			break
+		}
+		if !p.accept(",") {
+			return nil, p.errorf("expected \",\" or \")\" in a list of values")
+		}
+	}
+
+	if negate {
+		return notNode{n}, nil
+	}
+	return n, nil
+}
+
+// scanKey scans a label or field name.
+func (p *parser) scanKey() string {
+	start := p.pos
+	for !p.eof() && isKeyChar(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// scanValue scans a value, either quoted with single or double quotes, or
+// unquoted until whitespace, a comma or a parenthesis.
+func (p *parser) scanValue() (string, error) {
+	if p.eof() {
+		return "", p.errorf("unexpected end of query, expected a value")
+	}
+
+	if quote := p.input[p.pos]; quote == '"' || quote == '\'' {
+		start := p.pos
+		end := strings.IndexByte(p.input[p.pos+1:], quote)
+		if end < 0 {
+			return "", p.errorf("unterminated quoted value")
+		}
+		p.pos += end + 2
+		return p.input[start+1 : p.pos-1], nil
+	}
+
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune("(), \t\n", rune(p.input[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("unexpected %q, expected a value", p.peekWord())
+	}
+	return p.input[start:p.pos], nil
+}
+
+// accept consumes given token if it follows.
+func (p *parser) accept(token string) bool {
+	if strings.HasPrefix(p.input[p.pos:], token) {
+		p.pos += len(token)
+		return true
+	}
+	return false
+}
+
+// acceptKeyword consumes given keyword if it follows as a whole word.
+func (p *parser) acceptKeyword(keyword string) bool {
+	p.skipSpace()
+	rest := p.input[p.pos:]
+	if !strings.HasPrefix(rest, keyword) {
+		return false
+	}
+	if len(rest) > len(keyword) && isKeyChar(rune(rest[len(keyword)])) {
+		return false
+	}
+	p.pos += len(keyword)
+	return true
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+// peekWord returns the input following the current position up to the next
+// whitespace, for use in error messages.
+func (p *parser) peekWord() string {
+	rest := p.input[p.pos:]
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i > 0 {
+		return rest[:i]
+	}
+	return rest
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Pos: p.pos + 1, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isKeyChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("/._-", c)
+}
+
+func isKeyword(s string) bool {
+	switch s {
+	case keywordAnd, keywordOr, keywordNot, keywordIn, keywordNotIn:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,150 @@
+package query
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/giantswarm/heartbeatctl/pkg/conv"
+)
+
+// Query is a compiled query expression that can be matched against
+// heartbeats.
+//
+// Queries combine expressions with 'and', 'or' and 'not' (in order of
+// increasing precedence) and parentheses. Expressions compare a key with
+// values, using operators '=', '==', '!=', '=~' and '!~' matching a regular
+// expression against the entire value, '>', '>=', '<', '<=' comparing numbers,
+// and 'in' and 'notin' followed by a parenthesized list of values. A key on its
+// own is true when the heartbeat has such label.
+//
+// Keys are heartbeat fields as given by `conv.HeartbeatAsFields`, or labels
+// as given by `conv.HeartbeatAsLabels` for keys that are not fields, e.g.
+// labels derived from alert tags. Values containing whitespace, commas or
+// parentheses must be quoted with single or double quotes.
+type Query struct {
+	source string
+	root   node
+}
+
+// Parse compiles given query expression, returning a SyntaxError if it is
+// malformed.
+func Parse(s string) (*Query, error) {
+	p := &parser{input: s}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{source: s, root: root}, nil
+}
+
+// Matches returns true if given heartbeat satisfies the query.
+func (q *Query) Matches(h heartbeat.Heartbeat) bool {
+	return q.root.eval(&env{
+		fields: conv.HeartbeatAsFields(h),
+		labels: conv.HeartbeatAsLabels(h),
+	})
+}
+
+// String returns the source of the query.
+func (q *Query) String() string {
+	return q.source
+}
+
+// env holds keys a heartbeat can be queried by.
+type env struct {
+	fields fields.Set
+	labels labels.Labels
+}
+
+// lookup returns the value of a field or, if there's no such field, of a
+// label, and whether either exists.
+func (e *env) lookup(key string) (string, bool) {
+	if value, ok := e.fields[key]; ok {
+		return value, true
+	}
+	if e.labels.Has(key) {
+		return e.labels.Get(key), true
+	}
+	return "", false
+}
+
+type node interface {
+	eval(e *env) bool
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(e *env) bool { return n.left.eval(e) || n.right.eval(e) }
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(e *env) bool { return n.left.eval(e) && n.right.eval(e) }
+
+type notNode struct{ n node }
+
+func (n notNode) eval(e *env) bool { return !n.n.eval(e) }
+
+type existsNode struct{ key string }
+
+func (n existsNode) eval(e *env) bool { return e.labels.Has(n.key) }
+
+type equalsNode struct{ key, value string }
+
+func (n equalsNode) eval(e *env) bool {
+	value, ok := e.lookup(n.key)
+	return ok && value == n.value
+}
+
+type inNode struct {
+	key    string
+	values map[string]bool
+}
+
+func (n inNode) eval(e *env) bool {
+	value, ok := e.lookup(n.key)
+	return ok && n.values[value]
+}
+
+type matchesNode struct {
+	key string
+	re  *regexp.Regexp
+}
+
+func (n matchesNode) eval(e *env) bool {
+	value, ok := e.lookup(n.key)
+	return ok && n.re.MatchString(value)
+}
+
+type compareNode struct {
+	key   string
+	op    string
+	value float64
+}
+
+func (n compareNode) eval(e *env) bool {
+	value, ok := e.lookup(n.key)
+	if !ok {
+		return false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return false
+	}
+
+	switch n.op {
+	case ">":
+		return number > n.value
+	case ">=":
+		return number >= n.value
+	case "<":
+		return number < n.value
+	case "<=":
+		return number <= n.value
+	default:
+		return false
+	}
+}
@@ -0,0 +1,84 @@
+package query_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/og"
+
+	"github.com/giantswarm/heartbeatctl/pkg/query"
+)
+
+var heartbeats = []heartbeat.Heartbeat{
+	{Name: "foo", Enabled: true, Interval: 5, AlertPriority: "P1", OwnerTeam: og.OwnerTeam{Name: "team-rocket"}},
+	{Name: "bar", Enabled: false, Interval: 10, AlertPriority: "P1", AlertTags: []string{"managed-by: foobricator"}},
+	{Name: "baz", Enabled: false, Interval: 30, AlertPriority: "P3", Description: "baz (staging)"},
+	{Name: "qux", Enabled: true, Expired: true, Interval: 60, AlertPriority: "P4", AlertTags: []string{"tagged"}},
+}
+
+var _ = Describe("Query", func() {
+	DescribeTable("matches heartbeats",
+		func(expr string, names ...string) {
+			q, err := query.Parse(expr)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(q.String()).To(Equal(expr))
+
+			matched := []string{}
+			for _, h := range heartbeats {
+				if q.Matches(h) {
+					matched = append(matched, h.Name)
+				}
+			}
+			Expect(matched).To(ConsistOf(names))
+		},
+		Entry("equality", "alertPriority=P1", "foo", "bar"),
+		Entry("double equality", "name == foo", "foo"),
+		Entry("inequality", "alertPriority != P1", "baz", "qux"),
+		Entry("boolean field", "enabled=false", "bar", "baz"),
+		Entry("label existence", "expired", "qux"),
+		Entry("label derived from tags", "managed-by=foobricator", "bar"),
+		Entry("tag without value", "tagged", "qux"),
+		Entry("missing label", "managed-by!=foobricator", "foo", "baz", "qux"),
+		Entry("set", "alertPriority in (P3, P4)", "baz", "qux"),
+		Entry("negated set", "alertPriority notin (P3,P4)", "foo", "bar"),
+		Entry("regular expression", "name=~ba.", "bar", "baz"),
+		Entry("negated regular expression", "name !~ 'ba.'", "foo", "qux"),
+		Entry("quoted value", `description="baz (staging)"`, "baz"),
+		Entry("numeric comparison", "interval>10", "baz", "qux"),
+		Entry("numeric comparison with equality", "interval <= 10", "foo", "bar"),
+		Entry("numeric comparison with non-numeric value", "alertPriority>1"),
+		Entry("and", "alertPriority=P1 and enabled", "foo"),
+		Entry("or", "name=foo or name=qux", "foo", "qux"),
+		Entry("not", "not enabled", "bar", "baz"),
+		Entry("double negation", "not not enabled", "foo", "qux"),
+		Entry("and before or", "name=foo or name=bar and enabled", "foo"),
+		Entry("parentheses", "(name=foo or name=bar) and not enabled", "bar"),
+		Entry("disabled P1s or owned by team", "(not enabled and alertPriority=P1) or ownerTeam/name=team-rocket", "foo", "bar"),
+		Entry("keywords as key prefixes", "notes != x and order != y", "foo", "bar", "baz", "qux"),
+	)
+
+	DescribeTable("fails with a syntax error for malformed queries",
+		func(expr string, message string) {
+			_, err := query.Parse(expr)
+
+			var syntaxErr *query.SyntaxError
+			Expect(errors.As(err, &syntaxErr)).To(BeTrue())
+			Expect(err).To(MatchError(message))
+		},
+		Entry("empty query", "", "syntax error at position 1: unexpected end of query, expected a key or \"(\""),
+		Entry("missing value", "name=", "syntax error at position 6: unexpected end of query, expected a value"),
+		Entry("missing operand", "name=foo and", "syntax error at position 13: unexpected end of query, expected a key or \"(\""),
+		Entry("keyword as key", "and name=foo", "syntax error at position 1: unexpected keyword \"and\", expected a key"),
+		Entry("unclosed parenthesis", "(name=foo", "syntax error at position 10: expected \")\""),
+		Entry("unopened parenthesis", "name=foo)", "syntax error at position 9: unexpected \")\""),
+		Entry("trailing expression", "name=foo name=bar", "syntax error at position 10: unexpected \"name=bar\""),
+		Entry("set without parentheses", "name in foo", "syntax error at position 9: expected \"(\" starting a list of values"),
+		Entry("unclosed set", "name in (foo bar)", "syntax error at position 14: expected \",\" or \")\" in a list of values"),
+		Entry("unterminated quote", "name='foo", "syntax error at position 6: unterminated quoted value"),
+		Entry("invalid regular expression", "name=~a**", "syntax error at position 7: invalid regular expression: error parsing regexp: invalid nested repetition operator: `**`"),
+		Entry("non-numeric comparison", "interval > often", "syntax error at position 12: operator > requires a number, got \"often\""),
+		Entry("unknown operator", "name ~ foo", "syntax error at position 6: unexpected \"~\""),
+	)
+})
@@ -0,0 +1,4 @@
+// reconcile package keeps heartbeats in OpsGenie in sync with manifests kept
+// in a directory, e.g. one mounted from a ConfigMap in a cluster, correcting
+// any drift on every tick and reporting its progress as metrics.
+package reconcile
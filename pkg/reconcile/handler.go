@@ -0,0 +1,71 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/giantswarm/heartbeatctl/pkg/metrics"
+)
+
+// Handler returns an HTTP handler serving metrics in the Prometheus text
+// format at '/metrics', and readiness at '/readyz', which succeeds once
+// heartbeats were reconciled successfully within given time, e.g. a few
+// reconciliation intervals.
+func (r *Reconciler) Handler(maxAge time.Duration) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", r.serveMetrics)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		r.serveReadiness(w, maxAge)
+	})
+	return mux
+}
+
+// readiness is the response of the readiness endpoint.
+type readiness struct {
+	Status
+	Ready bool `json:"ready"`
+}
+
+func (r *Reconciler) serveReadiness(w http.ResponseWriter, maxAge time.Duration) {
+	status := r.Status()
+	ready := !status.LastSuccess.IsZero() && r.now().Sub(status.LastSuccess) <= maxAge
+
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(readiness{Status: status, Ready: ready})
+}
+
+func (r *Reconciler) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := metrics.NewWriter(w)
+
+	m.Metric("heartbeatctl_reconcile_runs_total", metrics.Counter, "Reconciliations of heartbeats by result.")
+	for _, result := range []string{"success", "failure"} {
+		m.Sample("heartbeatctl_reconcile_runs_total", float64(r.runs[result]), "result", result)
+	}
+
+	m.Metric("heartbeatctl_reconcile_corrections_total", metrics.Counter, "Heartbeats changed to match manifests by action.")
+	actions := make([]string, 0, len(logMessages))
+	for action := range logMessages {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		m.Sample("heartbeatctl_reconcile_corrections_total", float64(r.corrections[action]), "action", action)
+	}
+
+	m.Metric("heartbeatctl_reconcile_last_success_timestamp_seconds", metrics.Gauge, "When the last successful reconciliation started.")
+	m.Sample("heartbeatctl_reconcile_last_success_timestamp_seconds", metrics.Timestamp(r.status.LastSuccess))
+
+	m.Metric("heartbeatctl_reconcile_duration_seconds", metrics.Gauge, "How long the last reconciliation took.")
+	m.Sample("heartbeatctl_reconcile_duration_seconds", r.duration.Seconds())
+
+	m.Metric("heartbeatctl_reconcile_heartbeats", metrics.Gauge, "Heartbeats described by manifests in the last successful reconciliation.")
+	m.Sample("heartbeatctl_reconcile_heartbeats", float64(r.status.Heartbeats))
+}
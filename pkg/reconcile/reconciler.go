@@ -0,0 +1,243 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+)
+
+// ErrNoManifests is returned when reconciling a directory with no manifests.
+var ErrNoManifests = errors.New("no heartbeat manifests found")
+
+// Config configures what a Reconciler keeps in sync.
+type Config struct {
+	// Path is the directory manifests are read from, including its
+	// subdirectories. Files and directories with names starting with '.' are
+	// ignored, as are files without a '.yaml' or '.yml' extension.
+	Path string
+
+	// Owner is the ID of the set manifests are tagged as belonging to, see
+	// manifest.OwnerTags. If set, heartbeats of the set with no manifest are
+	// deleted, otherwise heartbeats are never deleted.
+	Owner string
+}
+
+// Status describes the outcome of reconciliations so far.
+type Status struct {
+	// LastAttempt is when the last reconciliation started.
+	LastAttempt time.Time `json:"lastAttempt"`
+	// LastSuccess is when the last successful reconciliation started, zero
+	// if none succeeded yet.
+	LastSuccess time.Time `json:"lastSuccessfulSync"`
+	// LastError is the error the last reconciliation failed with, empty if
+	// it succeeded.
+	LastError string `json:"lastError,omitempty"`
+	// Heartbeats is how many heartbeats were described by manifests in the
+	// last successful reconciliation.
+	Heartbeats int `json:"heartbeats"`
+}
+
+// Reconciler makes heartbeats match manifests in a directory.
+type Reconciler struct {
+	ctl    ctl.Port
+	config Config
+	logger logrus.FieldLogger
+	now    func() time.Time
+
+	mu          sync.Mutex
+	status      Status
+	runs        map[string]int
+	corrections map[string]int
+	duration    time.Duration
+}
+
+// New returns a Reconciler changing heartbeats through given Port and logging
+// every correction it makes with given logger.
+func New(c ctl.Port, config Config, logger logrus.FieldLogger, now func() time.Time) *Reconciler {
+	return &Reconciler{
+		ctl:         c,
+		config:      config,
+		logger:      logger,
+		now:         now,
+		runs:        map[string]int{},
+		corrections: map[string]int{},
+	}
+}
+
+// Run reconciles heartbeats right away and then every interval, until given
+// context is done. Failed reconciliations are logged and retried on the next
+// tick.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Reconcile(); err != nil {
+			r.logger.WithError(err).Error("failed to reconcile heartbeats")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile reads manifests and creates, updates and, if an owner is
+// configured, deletes heartbeats so they match them. It returns actions
+// taken, including heartbeats that were left unchanged.
+func (r *Reconciler) Reconcile() ([]ctl.PlannedAction, error) {
+	start := r.now()
+	r.mu.Lock()
+	r.status.LastAttempt = start
+	r.mu.Unlock()
+
+	actions, manifests, err := r.reconcile()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.duration = r.now().Sub(start)
+	for _, a := range actions {
+		if !a.Unchanged {
+			r.corrections[a.Action]++
+		}
+	}
+	if err != nil {
+		r.runs["failure"]++
+		r.status.LastError = err.Error()
+		return actions, err
+	}
+	r.runs["success"]++
+	r.status.LastSuccess = start
+	r.status.LastError = ""
+	r.status.Heartbeats = manifests
+	return actions, nil
+}
+
+func (r *Reconciler) reconcile() ([]ctl.PlannedAction, int, error) {
+	manifests, err := LoadDir(r.config.Path)
+	if err != nil {
+		return nil, 0, err
+	}
+	// An empty directory is more likely a broken mount than a request to
+	// prune everything.
+	if len(manifests) == 0 {
+		return nil, 0, fmt.Errorf("%w in %s", ErrNoManifests, r.config.Path)
+	}
+	if r.config.Owner != "" {
+		for i := range manifests {
+			manifests[i].SetOwner(r.config.Owner)
+		}
+	}
+
+	actions, err := r.ctl.Apply(manifests)
+	r.logActions(actions)
+	if err != nil {
+		return actions, 0, err
+	}
+
+	if r.config.Owner != "" {
+		keep := make([]string, 0, len(manifests))
+		for _, m := range manifests {
+			keep = append(keep, m.Metadata.Name)
+		}
+		pruned, err := ctl.Prune(r.ctl, r.config.Owner, keep, false)
+		r.logActions(pruned)
+		actions = append(actions, pruned...)
+		if err != nil {
+			return actions, 0, err
+		}
+	}
+
+	return actions, len(manifests), nil
+}
+
+// logMessages maps actions to messages logged when they are taken.
+var logMessages = map[string]string{
+	ctl.ActionCreate: "created missing heartbeat",
+	ctl.ActionUpdate: "corrected drifted heartbeat",
+	ctl.ActionDelete: "deleted heartbeat with no manifest",
+}
+
+func (r *Reconciler) logActions(actions []ctl.PlannedAction) {
+	for _, a := range actions {
+		if a.Unchanged {
+			continue
+		}
+		r.logger.WithFields(logrus.Fields{
+			"heartbeat": a.Heartbeat,
+			"action":    a.Action,
+		}).Info(logMessages[a.Action])
+	}
+}
+
+// Status returns the outcome of reconciliations so far.
+func (r *Reconciler) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// LoadDir reads manifests from YAML files in given directory and its
+// subdirectories, skipping those with names starting with '.', like the
+// '..data' directory of mounted ConfigMaps. Manifests are checked with
+// manifest.Linter and a manifest.ProblemsError is returned if any problems
+// are found.
+func LoadDir(path string) ([]manifest.Heartbeat, error) {
+	var files []string
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != path && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := filepath.Ext(p); !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifests from %s: %w", path, err)
+	}
+
+	linter := manifest.NewLinter()
+	var manifests []manifest.Heartbeat
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifests from %s: %w", file, err)
+		}
+
+		linter.Lint(file, bytes.NewReader(data))
+		if len(linter.Problems()) > 0 {
+			continue
+		}
+		ms, err := manifest.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read manifests from %s: %w", file, err)
+		}
+		manifests = append(manifests, ms...)
+	}
+
+	if problems := linter.Problems(); len(problems) > 0 {
+		return nil, &manifest.ProblemsError{Problems: problems}
+	}
+	return manifests, nil
+}
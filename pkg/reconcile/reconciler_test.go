@@ -0,0 +1,220 @@
+package reconcile_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/manifest"
+	"github.com/giantswarm/heartbeatctl/pkg/reconcile"
+)
+
+// manifestYAML returns a manifest of a heartbeat with given name and
+// interval.
+func manifestYAML(name string, interval int) string {
+	return fmt.Sprintf(
+		"apiVersion: heartbeatctl.giantswarm.io/v1alpha1\nkind: Heartbeat\nmetadata:\n  name: %s\nspec:\n  interval: %d\n  intervalUnit: minutes\n",
+		name, interval,
+	)
+}
+
+var _ = Describe("Reconciler", func() {
+	var (
+		dir    string
+		repo   *fake.Client
+		logs   *logtest.Hook
+		now    time.Time
+		config reconcile.Config
+	)
+
+	writeFile := func(name, content string) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	newReconciler := func() *reconcile.Reconciler {
+		logger, hook := logtest.NewNullLogger()
+		logs = hook
+		return reconcile.New(ctl.NewCtl(repo), config, logger, func() time.Time { return now })
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+		config = reconcile.Config{Path: dir}
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "drifted", Interval: 9, IntervalUnit: "minutes"},
+			heartbeat.Heartbeat{Name: "manual", Interval: 1, IntervalUnit: "hours"},
+		)
+
+		writeFile("a.yaml", manifestYAML("drifted", 5)+"---\n"+manifestYAML("missing", 3))
+		writeFile("nested/b.yml", manifestYAML("unchanged", 1))
+		writeFile("..data/ignored.yaml", manifestYAML("ignored", 1))
+		writeFile("README.md", "not a manifest")
+		Expect(repo.Add(context.TODO(), manifest.Heartbeat{
+			Metadata: manifest.Metadata{Name: "unchanged"},
+			Spec:     manifest.Spec{Interval: 1, IntervalUnit: "minutes"},
+		}.AddRequest())).Error().NotTo(HaveOccurred())
+	})
+
+	It("creates missing and corrects drifted heartbeats, logging each correction", func() {
+		r := newReconciler()
+
+		actions, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(Equal([]ctl.PlannedAction{
+			{Action: ctl.ActionUpdate, Heartbeat: "drifted"},
+			{Action: ctl.ActionCreate, Heartbeat: "missing"},
+			{Action: ctl.ActionUpdate, Heartbeat: "unchanged", Unchanged: true},
+		}))
+
+		Expect(logs.AllEntries()).To(HaveLen(2))
+		Expect(logs.AllEntries()[0].Message).To(Equal("corrected drifted heartbeat"))
+		Expect(logs.AllEntries()[0].Data).To(Equal(logrus.Fields{"heartbeat": "drifted", "action": ctl.ActionUpdate}))
+		Expect(logs.AllEntries()[1].Message).To(Equal("created missing heartbeat"))
+
+		Expect(repo.Heartbeats()).To(ContainElement(heartbeat.Heartbeat{Name: "drifted", Interval: 5, IntervalUnit: "minutes"}))
+		Expect(repo.Heartbeats()).To(ContainElement(HaveField("Name", "manual")))
+
+		actions, err = r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		for _, a := range actions {
+			Expect(a.Unchanged).To(BeTrue(), a.Heartbeat)
+		}
+	})
+
+	It("converges on heartbeats with fields the manifests leave empty", func() {
+		repo = fake.NewClient(heartbeat.Heartbeat{
+			Name: "drifted", Interval: 9, IntervalUnit: "minutes",
+			Description: "edited in the UI", AlertTags: []string{"ui"}, AlertMessage: "drifted is late", AlertPriority: "P1",
+		})
+		r := newReconciler()
+
+		_, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.Heartbeats()).To(ContainElement(HaveField("AlertMessage", fake.DefaultAlertMessage("missing"))))
+
+		logs.Reset()
+		actions, err := r.Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		for _, a := range actions {
+			Expect(a.Unchanged).To(BeTrue(), a.Heartbeat)
+		}
+		Expect(logs.AllEntries()).To(BeEmpty())
+	})
+
+	It("deletes heartbeats of the owned set with no manifest", func() {
+		config.Owner = "gitops"
+		repo = fake.NewClient(heartbeat.Heartbeat{
+			Name: "removed", Interval: 1, IntervalUnit: "hours",
+			AlertTags: manifest.OwnerTags("gitops"),
+		}, heartbeat.Heartbeat{Name: "manual", Interval: 1, IntervalUnit: "hours"})
+
+		actions, err := newReconciler().Reconcile()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actions).To(ContainElement(ctl.PlannedAction{Action: ctl.ActionDelete, Heartbeat: "removed"}))
+
+		names := []string{}
+		for _, h := range repo.Heartbeats() {
+			names = append(names, h.Name)
+			if h.Name != "manual" {
+				Expect(h.AlertTags).To(Equal(manifest.OwnerTags("gitops")))
+			}
+		}
+		Expect(names).To(Equal([]string{"drifted", "manual", "missing", "unchanged"}))
+	})
+
+	It("fails without changing anything if manifests have problems", func() {
+		writeFile("c.yaml", manifestYAML("broken", 0))
+
+		_, err := newReconciler().Reconcile()
+		Expect(err).To(MatchError(ContainSubstring("c.yaml:6:13: spec.interval must be at least 1, got 0")))
+		Expect(repo.Heartbeats()).To(HaveLen(3))
+	})
+
+	It("refuses to reconcile a directory with no manifests", func() {
+		config.Path = GinkgoT().TempDir()
+		config.Owner = "gitops"
+
+		_, err := newReconciler().Reconcile()
+		Expect(err).To(MatchError(reconcile.ErrNoManifests))
+	})
+
+	It("runs until the context is done", func() {
+		r := newReconciler()
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx, time.Millisecond)
+			close(done)
+		}()
+
+		Eventually(func() time.Time { return r.Status().LastSuccess }).Should(Equal(now))
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+
+	Describe("Handler", func() {
+		get := func(r *reconcile.Reconciler, path string) *httptest.ResponseRecorder {
+			w := httptest.NewRecorder()
+			r.Handler(15*time.Minute).ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+			return w
+		}
+
+		It("reports readiness once reconciled recently", func() {
+			r := newReconciler()
+			Expect(get(r, "/readyz").Code).To(Equal(http.StatusServiceUnavailable))
+
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			w := get(r, "/readyz")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var body map[string]interface{}
+			Expect(json.Unmarshal(w.Body.Bytes(), &body)).To(Succeed())
+			Expect(body).To(HaveKeyWithValue("ready", true))
+			Expect(body).To(HaveKeyWithValue("lastSuccessfulSync", "2022-10-05T12:00:00Z"))
+			Expect(body).To(HaveKeyWithValue("heartbeats", BeNumerically("==", 3)))
+
+			writeFile("c.yaml", "kind: [")
+			now = now.Add(10 * time.Minute)
+			_, err = r.Reconcile()
+			Expect(err).To(HaveOccurred())
+			Expect(get(r, "/readyz").Code).To(Equal(http.StatusOK))
+
+			now = now.Add(10 * time.Minute)
+			w = get(r, "/readyz")
+			Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+			Expect(w.Body.String()).To(ContainSubstring(`"lastError":"found 1 problem in manifests`))
+		})
+
+		It("serves metrics", func() {
+			r := newReconciler()
+			_, err := r.Reconcile()
+			Expect(err).NotTo(HaveOccurred())
+
+			w := get(r, "/metrics")
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(w.Body.String()).To(ContainSubstring(`heartbeatctl_reconcile_runs_total{result="success"} 1`))
+			Expect(w.Body.String()).To(ContainSubstring(`heartbeatctl_reconcile_runs_total{result="failure"} 0`))
+			Expect(w.Body.String()).To(ContainSubstring(`heartbeatctl_reconcile_corrections_total{action="create"} 1`))
+			Expect(w.Body.String()).To(ContainSubstring(`heartbeatctl_reconcile_corrections_total{action="update"} 1`))
+			Expect(w.Body.String()).To(ContainSubstring("heartbeatctl_reconcile_last_success_timestamp_seconds 1664971200\n"))
+			Expect(w.Body.String()).To(ContainSubstring("heartbeatctl_reconcile_heartbeats 3\n"))
+		})
+	})
+})
@@ -0,0 +1,4 @@
+// relay package forwards pings from workloads that can't reach OpsGenie,
+// e.g. in networks without internet egress, to heartbeats selected by an
+// allow-list, using the relay's own API key.
+package relay
@@ -0,0 +1,103 @@
+package relay
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/giantswarm/heartbeatctl/pkg/metrics"
+)
+
+// response is the JSON body of responses to pings.
+type response struct {
+	Heartbeat string `json:"heartbeat"`
+	Result    string `json:"result"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Handler returns an HTTP handler accepting pings at 'POST /ping/{name}',
+// and serving metrics in the Prometheus text format at '/metrics', liveness
+// at '/healthz' and readiness at '/readyz', which succeeds once the
+// allow-list was loaded. If a shared secret is configured, pings must carry
+// it in an 'Authorization: Bearer <secret>' header.
+func (r *Relay) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ping/{name}", r.servePing)
+	mux.HandleFunc("GET /metrics", r.serveMetrics)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !r.Ready() {
+			http.Error(w, ErrNotReady.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+func (r *Relay) servePing(w http.ResponseWriter, req *http.Request) {
+	name := req.PathValue("name")
+
+	if !r.authorized(req) {
+		_ = r.result(ResultUnauthorized, nil)
+		r.logger.WithField("heartbeat", name).Warn("rejected ping without the shared secret")
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeResponse(w, http.StatusUnauthorized, response{Heartbeat: name, Result: ResultUnauthorized})
+		return
+	}
+
+	result, err := r.Ping(req.Context(), name)
+	resp := response{Heartbeat: name, Result: result}
+	status := http.StatusAccepted
+	switch {
+	case errors.Is(err, ErrDenied):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrNotReady):
+		status = http.StatusServiceUnavailable
+	case err != nil:
+		status = http.StatusBadGateway
+	}
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	writeResponse(w, status, resp)
+}
+
+// authorized returns true if no shared secret is configured or given request
+// carries it.
+func (r *Relay) authorized(req *http.Request) bool {
+	if r.config.Secret == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(r.config.Secret)) == 1
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (r *Relay) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := metrics.NewWriter(w)
+
+	m.Metric("heartbeatctl_relay_pings_total", metrics.Counter, "Pings received by the result of relaying them.")
+	for _, result := range results {
+		m.Sample("heartbeatctl_relay_pings_total", float64(r.results[result]), "result", result)
+	}
+
+	m.Metric("heartbeatctl_relay_allowed_heartbeats", metrics.Gauge, "Heartbeats in the allow-list.")
+	m.Sample("heartbeatctl_relay_allowed_heartbeats", float64(len(r.allowed)))
+
+	m.Metric("heartbeatctl_relay_allow_list_timestamp_seconds", metrics.Gauge, "When the allow-list was last loaded.")
+	m.Sample("heartbeatctl_relay_allow_list_timestamp_seconds", metrics.Timestamp(r.loaded))
+}
@@ -0,0 +1,184 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+)
+
+// Results of handling pings, counted in metrics.
+const (
+	// ResultForwarded means the ping was forwarded to OpsGenie.
+	ResultForwarded = "forwarded"
+	// ResultCoalesced means the heartbeat was pinged recently enough that the
+	// ping was not forwarded.
+	ResultCoalesced = "coalesced"
+	// ResultDenied means the heartbeat is not in the allow-list.
+	ResultDenied = "denied"
+	// ResultUnauthorized means the request didn't carry the shared secret.
+	ResultUnauthorized = "unauthorized"
+	// ResultFailed means forwarding the ping failed.
+	ResultFailed = "failed"
+)
+
+// results lists results in the order they are reported in metrics.
+var results = []string{ResultForwarded, ResultCoalesced, ResultDenied, ResultUnauthorized, ResultFailed}
+
+var (
+	// ErrDenied is returned for pings of heartbeats not in the allow-list.
+	ErrDenied = errors.New("heartbeat is not in the allow-list")
+	// ErrNotReady is returned for pings received before the allow-list was
+	// loaded.
+	ErrNotReady = errors.New("allow-list not loaded yet")
+)
+
+// Config configures which pings a Relay forwards.
+type Config struct {
+	// Allowed selects heartbeats pings are forwarded to, like selectors of
+	// other commands, and must not be empty.
+	Allowed *ctl.SelectorConfig
+
+	// Secret is the shared secret requests must carry as a bearer token, not
+	// checked if empty.
+	Secret string
+
+	// CoalesceWindow is how long after forwarding a ping further pings of the
+	// same heartbeat are not forwarded.
+	CoalesceWindow time.Duration
+}
+
+// Relay forwards pings of allowed heartbeats to OpsGenie.
+type Relay struct {
+	client client.Port
+	ctl    ctl.Port
+	config Config
+	logger logrus.FieldLogger
+	now    func() time.Time
+
+	mu        sync.Mutex
+	allowed   map[string]bool
+	loaded    time.Time
+	forwarded map[string]time.Time
+	results   map[string]int
+}
+
+// New returns a Relay forwarding pings through given client, with heartbeats
+// in the allow-list looked up through given Port. It returns
+// ctl.ErrNoSelector if the allow-list selects everything implicitly, or an
+// InvalidSelectorError if it's malformed.
+func New(c client.Port, p ctl.Port, config Config, logger logrus.FieldLogger, now func() time.Time) (*Relay, error) {
+	if allowed := config.Allowed; allowed == nil || (len(allowed.NameExpressions) == 0 &&
+		allowed.LabelSelector == "" && allowed.FieldSelector == "" && allowed.Query == "") {
+		return nil, ctl.ErrNoSelector
+	}
+	if err := ctl.ValidateSelector(config.Allowed); err != nil {
+		return nil, err
+	}
+
+	return &Relay{
+		client:    c,
+		ctl:       p,
+		config:    config,
+		logger:    logger,
+		now:       now,
+		forwarded: map[string]time.Time{},
+		results:   map[string]int{},
+	}, nil
+}
+
+// Refresh looks up heartbeats selected by the allow-list again, keeping the
+// previous list if that fails.
+func (r *Relay) Refresh() error {
+	selected, err := r.ctl.Get(r.config.Allowed)
+	if err != nil {
+		return err
+	}
+
+	allowed := make(map[string]bool, len(selected))
+	for _, h := range selected {
+		allowed[h.Name] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.allowed = allowed
+	r.loaded = r.now()
+	return nil
+}
+
+// Run refreshes the allow-list every interval until given context is done.
+// Failed refreshes are logged and retried on the next tick.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := r.Refresh(); err != nil {
+			r.logger.WithError(err).Error("failed to refresh allow-list")
+		}
+	}
+}
+
+// Ping forwards a ping of the heartbeat with given name unless it's not in
+// the allow-list, returning ErrDenied, or it was forwarded within the
+// coalescing window, and returns the result.
+func (r *Relay) Ping(ctx context.Context, name string) (string, error) {
+	logger := r.logger.WithField("heartbeat", name)
+
+	r.mu.Lock()
+	switch {
+	case r.allowed == nil:
+		r.mu.Unlock()
+		return ResultFailed, r.result(ResultFailed, ErrNotReady)
+	case !r.allowed[name]:
+		r.mu.Unlock()
+		logger.Warn("denied ping of heartbeat not in the allow-list")
+		return ResultDenied, r.result(ResultDenied, ErrDenied)
+	}
+	now := r.now()
+	if last, ok := r.forwarded[name]; ok && now.Sub(last) < r.config.CoalesceWindow {
+		r.mu.Unlock()
+		logger.Debug("coalesced ping of heartbeat pinged recently")
+		return ResultCoalesced, r.result(ResultCoalesced, nil)
+	}
+	// Concurrent pings are coalesced while this one is forwarded.
+	r.forwarded[name] = now
+	r.mu.Unlock()
+
+	if _, err := r.client.Ping(ctx, name); err != nil {
+		r.mu.Lock()
+		delete(r.forwarded, name)
+		r.mu.Unlock()
+		logger.WithError(err).Error("failed to forward ping")
+		return ResultFailed, r.result(ResultFailed, err)
+	}
+	logger.Info("forwarded ping")
+	return ResultForwarded, r.result(ResultForwarded, nil)
+}
+
+// result counts given result and returns given error.
+func (r *Relay) result(result string, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results[result]++
+	return err
+}
+
+// Ready returns true once the allow-list was loaded.
+func (r *Relay) Ready() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.allowed != nil
+}
@@ -0,0 +1,221 @@
+package relay_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/ctl"
+	"github.com/giantswarm/heartbeatctl/pkg/relay"
+)
+
+var _ = Describe("Relay", func() {
+	var (
+		repo    *fake.Client
+		logs    *logtest.Hook
+		now     time.Time
+		config  relay.Config
+		r       *relay.Relay
+		handler http.Handler
+	)
+
+	ping := func(name, secret string) (*httptest.ResponseRecorder, map[string]string) {
+		req := httptest.NewRequest(http.MethodPost, "/ping/"+name, nil)
+		if secret != "" {
+			req.Header.Set("Authorization", "Bearer "+secret)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		body := map[string]string{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &body)).To(Succeed())
+		return rec, body
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// pinged returns names of heartbeats pinged since they were expired.
+	pinged := func() []string {
+		names := []string{}
+		for _, h := range repo.Heartbeats() {
+			if !h.Expired {
+				names = append(names, h.Name)
+			}
+		}
+		return names
+	}
+
+	metrics := func() string {
+		rec := get("/metrics")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		body, err := io.ReadAll(rec.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	BeforeEach(func() {
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "backup-etcd", Expired: true, AlertTags: []string{"team: rocket"}},
+			heartbeat.Heartbeat{Name: "backup-vault", Expired: true, AlertTags: []string{"team: rocket"}},
+			heartbeat.Heartbeat{Name: "gorilla-watchdog", Expired: true},
+		)
+		config = relay.Config{
+			Allowed:        &ctl.SelectorConfig{LabelSelector: "team=rocket"},
+			CoalesceWindow: time.Minute,
+		}
+	})
+
+	JustBeforeEach(func() {
+		logger, hook := logtest.NewNullLogger()
+		logs = hook
+		var err error
+		r, err = relay.New(repo, ctl.NewCtl(repo), config, logger, func() time.Time { return now })
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r.Refresh()).To(Succeed())
+		handler = r.Handler()
+	})
+
+	It("forwards pings of heartbeats in the allow-list", func() {
+		rec, body := ping("backup-etcd", "")
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(body).To(Equal(map[string]string{"heartbeat": "backup-etcd", "result": relay.ResultForwarded}))
+		Expect(pinged()).To(Equal([]string{"backup-etcd"}))
+		Expect(logs.LastEntry().Message).To(Equal("forwarded ping"))
+	})
+
+	It("denies pings of heartbeats not in the allow-list", func() {
+		rec, body := ping("gorilla-watchdog", "")
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(body).To(HaveKeyWithValue("error", relay.ErrDenied.Error()))
+		Expect(pinged()).To(BeEmpty())
+	})
+
+	It("coalesces pings within the window", func() {
+		rec, _ := ping("backup-etcd", "")
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+
+		now = now.Add(30 * time.Second)
+		rec, body := ping("backup-etcd", "")
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(body).To(HaveKeyWithValue("result", relay.ResultCoalesced))
+
+		now = now.Add(30 * time.Second)
+		_, body = ping("backup-etcd", "")
+		Expect(body).To(HaveKeyWithValue("result", relay.ResultForwarded))
+
+		Expect(metrics()).To(Equal(strings.Join([]string{
+			"# HELP heartbeatctl_relay_pings_total Pings received by the result of relaying them.",
+			"# TYPE heartbeatctl_relay_pings_total counter",
+			`heartbeatctl_relay_pings_total{result="forwarded"} 2`,
+			`heartbeatctl_relay_pings_total{result="coalesced"} 1`,
+			`heartbeatctl_relay_pings_total{result="denied"} 0`,
+			`heartbeatctl_relay_pings_total{result="unauthorized"} 0`,
+			`heartbeatctl_relay_pings_total{result="failed"} 0`,
+			"# HELP heartbeatctl_relay_allowed_heartbeats Heartbeats in the allow-list.",
+			"# TYPE heartbeatctl_relay_allowed_heartbeats gauge",
+			"heartbeatctl_relay_allowed_heartbeats 2",
+			"# HELP heartbeatctl_relay_allow_list_timestamp_seconds When the allow-list was last loaded.",
+			"# TYPE heartbeatctl_relay_allow_list_timestamp_seconds gauge",
+			"heartbeatctl_relay_allow_list_timestamp_seconds 1664971200",
+			"",
+		}, "\n")))
+	})
+
+	It("doesn't coalesce pings after failed ones", func() {
+		repo.Fail("Ping", "backup-etcd", errors.New("boom"))
+		rec, body := ping("backup-etcd", "")
+		Expect(rec.Code).To(Equal(http.StatusBadGateway))
+		Expect(body).To(HaveKeyWithValue("error", "boom"))
+
+		repo.Fail("Ping", "backup-etcd", nil)
+		rec, _ = ping("backup-etcd", "")
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		Expect(pinged()).To(Equal([]string{"backup-etcd"}))
+	})
+
+	It("picks up heartbeats added to the allow-list on refresh", func() {
+		_, err := repo.Add(context.Background(), &heartbeat.AddRequest{Name: "backup-consul", AlertTag: []string{"team: rocket"}})
+		Expect(err).NotTo(HaveOccurred())
+		rec, _ := ping("backup-consul", "")
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+
+		Expect(r.Refresh()).To(Succeed())
+		rec, _ = ping("backup-consul", "")
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+	})
+
+	It("keeps the allow-list if refreshing it fails", func() {
+		repo.Fail("List", "", errors.New("boom"))
+		Expect(r.Refresh()).To(MatchError("boom"))
+
+		rec, _ := ping("backup-etcd", "")
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+	})
+
+	It("is not ready until the allow-list was loaded", func() {
+		logger, _ := logtest.NewNullLogger()
+		r, err := relay.New(repo, ctl.NewCtl(repo), config, logger, time.Now)
+		Expect(err).NotTo(HaveOccurred())
+		handler = r.Handler()
+
+		Expect(get("/healthz").Code).To(Equal(http.StatusOK))
+		Expect(get("/readyz").Code).To(Equal(http.StatusServiceUnavailable))
+		rec, body := ping("backup-etcd", "")
+		Expect(rec.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(body).To(HaveKeyWithValue("error", relay.ErrNotReady.Error()))
+	})
+
+	It("rejects other methods and paths", func() {
+		Expect(get("/ping/backup-etcd").Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(get("/nope").Code).To(Equal(http.StatusNotFound))
+		Expect(get("/readyz").Code).To(Equal(http.StatusOK))
+	})
+
+	It("requires a valid allow-list", func() {
+		_, err := relay.New(repo, ctl.NewCtl(repo), relay.Config{Allowed: &ctl.SelectorConfig{}}, nil, time.Now)
+		Expect(err).To(MatchError(ctl.ErrNoSelector))
+
+		_, err = relay.New(repo, ctl.NewCtl(repo), relay.Config{Allowed: &ctl.SelectorConfig{LabelSelector: "team in"}}, nil, time.Now)
+		Expect(err).To(BeAssignableToTypeOf(&ctl.InvalidSelectorError{}))
+	})
+
+	Context("with a shared secret", func() {
+		BeforeEach(func() {
+			config.Secret = "s3cr3t"
+		})
+
+		It("forwards pings carrying the secret", func() {
+			rec, _ := ping("backup-etcd", "s3cr3t")
+			Expect(rec.Code).To(Equal(http.StatusAccepted))
+			Expect(pinged()).To(Equal([]string{"backup-etcd"}))
+		})
+
+		It("rejects pings without the secret", func() {
+			rec, body := ping("backup-etcd", "")
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(rec.Header().Get("WWW-Authenticate")).To(Equal("Bearer"))
+			Expect(body).To(HaveKeyWithValue("result", relay.ResultUnauthorized))
+
+			rec, _ = ping("backup-etcd", "wrong")
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+			Expect(pinged()).To(BeEmpty())
+			Expect(metrics()).To(ContainSubstring(`heartbeatctl_relay_pings_total{result="unauthorized"} 2`))
+		})
+	})
+})
@@ -0,0 +1,4 @@
+// schedule package derives heartbeat intervals from cron schedules, so a
+// heartbeat pinged by a job expects pings as often as the job runs at its
+// least frequent.
+package schedule
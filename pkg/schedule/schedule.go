@@ -0,0 +1,128 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// horizon is how far ahead runs of a schedule are looked at for the longest
+// gap between them, long enough to cover every day of the week, month and
+// year. Standard schedules run at most once a minute, so that's at most about
+// half a million runs.
+const horizon = 366 * 24 * time.Hour
+
+// ErrNeverRuns is returned for schedules with no runs, like "0 0 30 2 *".
+var ErrNeverRuns = errors.New("schedule never runs")
+
+// Interval is a heartbeat interval derived from a schedule.
+type Interval struct {
+	// Schedule is the cron expression the interval was derived from.
+	Schedule string
+	// MaxGap is the longest time between consecutive runs of the schedule.
+	MaxGap time.Duration
+	// Grace is the time added to the longest gap, allowing jobs to take a
+	// while.
+	Grace time.Duration
+	// Interval and IntervalUnit are the longest gap plus the grace time,
+	// rounded up to whole minutes and expressed in the largest unit OpsGenie
+	// supports that divides it evenly.
+	Interval     int
+	IntervalUnit string
+}
+
+// Derive returns the heartbeat interval of given cron schedule, in the
+// standard five field format or a descriptor like "@daily", optionally
+// prefixed with "CRON_TZ=<zone> " to run in another time zone than UTC. Runs
+// within a year after given time are considered.
+func Derive(schedule string, grace time.Duration, from time.Time) (*Interval, error) {
+	if grace < 0 {
+		return nil, fmt.Errorf("grace time must not be negative, got %s", grace)
+	}
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+	gap, err := MaxGap(parsed, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", schedule, err)
+	}
+
+	interval, unit := Convert(gap + grace)
+	return &Interval{
+		Schedule:     schedule,
+		MaxGap:       gap,
+		Grace:        grace,
+		Interval:     interval,
+		IntervalUnit: unit,
+	}, nil
+}
+
+// MaxGap returns the longest time between consecutive runs of given schedule
+// within a year after given time. Schedules running at a fixed delay, like
+// "@every 90s", always have that gap.
+func MaxGap(s cron.Schedule, from time.Time) (time.Duration, error) {
+	if c, ok := s.(cron.ConstantDelaySchedule); ok {
+		return c.Delay, nil
+	}
+
+	from = from.UTC()
+	end := from.Add(horizon)
+
+	prev := s.Next(from)
+	if prev.IsZero() {
+		return 0, ErrNeverRuns
+	}
+	var gap time.Duration
+	for {
+		next := s.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		if d := next.Sub(prev); d > gap {
+			gap = d
+		}
+		if next.After(end) {
+			break
+		}
+		prev = next
+	}
+	if gap == 0 {
+		return 0, ErrNeverRuns
+	}
+	return gap, nil
+}
+
+// Convert returns given duration as a heartbeat interval and interval unit,
+// rounded up to whole minutes and in the largest unit dividing it evenly.
+func Convert(d time.Duration) (int, string) {
+	minutes := int((d + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	switch {
+	case minutes%(24*60) == 0:
+		return minutes / (24 * 60), "days"
+	case minutes%60 == 0:
+		return minutes / 60, "hours"
+	default:
+		return minutes, "minutes"
+	}
+}
+
+// Duration returns the duration of given heartbeat interval and interval
+// unit, the inverse of Convert.
+func Duration(interval int, unit string) (time.Duration, error) {
+	switch unit {
+	case "minutes":
+		return time.Duration(interval) * time.Minute, nil
+	case "hours":
+		return time.Duration(interval) * time.Hour, nil
+	case "days":
+		return time.Duration(interval) * 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unsupported interval unit %q", unit)
+	}
+}
@@ -0,0 +1,71 @@
+package schedule_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/giantswarm/heartbeatctl/pkg/schedule"
+)
+
+var _ = Describe("Derive", func() {
+	// from is a Wednesday.
+	from := time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+
+	DescribeTable("derives intervals from the longest gap between runs",
+		func(expr string, grace time.Duration, gap time.Duration, interval int, unit string) {
+			i, err := schedule.Derive(expr, grace, from)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(i.MaxGap).To(Equal(gap))
+			Expect(i.Interval).To(Equal(interval))
+			Expect(i.IntervalUnit).To(Equal(unit))
+		},
+		Entry("every 5 minutes", "*/5 * * * *", time.Duration(0), 5*time.Minute, 5, "minutes"),
+		Entry("hourly with grace", "@hourly", 30*time.Minute, time.Hour, 90, "minutes"),
+		Entry("daily", "0 3 * * *", time.Duration(0), 24*time.Hour, 1, "days"),
+		Entry("workdays, over the weekend", "0 3 * * 1-5", 30*time.Minute, 72*time.Hour, 4350, "minutes"),
+		Entry("workdays with an hour of grace", "0 3 * * 1-5", time.Hour, 72*time.Hour, 73, "hours"),
+		Entry("monthly, over the longest month", "0 0 1 * *", time.Duration(0), 31*24*time.Hour, 31, "days"),
+		Entry("in another time zone, over DST changes", "CRON_TZ=Europe/Berlin 0 3 * * *", time.Duration(0), 25*time.Hour, 25, "hours"),
+		Entry("rounding up to whole minutes", "@every 90s", time.Duration(0), 90*time.Second, 2, "minutes"),
+	)
+
+	It("finds gaps late in the year of schedules running every minute", func() {
+		i, err := schedule.Derive("* * * 1-11 *", 0, time.Date(2022, 1, 5, 12, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(i.MaxGap).To(Equal(31*24*time.Hour + time.Minute))
+	})
+
+	It("rejects malformed schedules", func() {
+		_, err := schedule.Derive("0 3 * *", 0, from)
+		Expect(err).To(MatchError(HavePrefix(`invalid schedule "0 3 * *": expected exactly 5 fields`)))
+	})
+
+	It("rejects schedules that never run", func() {
+		_, err := schedule.Derive("0 0 30 2 *", 0, from)
+		Expect(err).To(MatchError(schedule.ErrNeverRuns))
+	})
+
+	It("rejects negative grace time", func() {
+		_, err := schedule.Derive("@daily", -time.Minute, from)
+		Expect(err).To(MatchError("grace time must not be negative, got -1m0s"))
+	})
+})
+
+var _ = Describe("Duration", func() {
+	DescribeTable("is the inverse of Convert",
+		func(d time.Duration) {
+			interval, unit := schedule.Convert(d)
+			Expect(schedule.Duration(interval, unit)).To(Equal(d))
+		},
+		Entry("minutes", 90*time.Minute),
+		Entry("hours", 73*time.Hour),
+		Entry("days", 31*24*time.Hour),
+	)
+
+	It("rejects unsupported units", func() {
+		_, err := schedule.Duration(1, "weeks")
+		Expect(err).To(MatchError(`unsupported interval unit "weeks"`))
+	})
+})
@@ -0,0 +1,4 @@
+// spool package implements a local store of pings that failed because
+// OpsGenie couldn't be reached, replaying them with backoff once it can be
+// reached again, unless they've become older than the heartbeat interval.
+package spool
@@ -0,0 +1,27 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// lock takes an exclusive lock on a file next to the spool, so writers
+// changing the spool don't lose entries of each other, and returns a function
+// releasing it. It blocks until the lock is available.
+func (s *Spool) lock() (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() error {
+		// Closing the file releases the lock.
+		return file.Close()
+	}, nil
+}
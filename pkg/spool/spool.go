@@ -0,0 +1,370 @@
+package spool
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client"
+	"github.com/giantswarm/heartbeatctl/pkg/schedule"
+)
+
+const (
+	// minBackoff is how long replaying a ping is put off after its first
+	// failed attempt, doubled after every further one.
+	minBackoff = 30 * time.Second
+	// maxBackoff limits how long replaying a ping is put off.
+	maxBackoff = 10 * time.Minute
+)
+
+// Outcomes of replaying spooled pings.
+const (
+	// OutcomeDelivered means the ping was delivered and removed from the
+	// spool.
+	OutcomeDelivered = "delivered"
+	// OutcomeExpired means the ping became older than the heartbeat interval
+	// and was discarded.
+	OutcomeExpired = "expired"
+	// OutcomeDiscarded means the ping can't be delivered, e.g. because the
+	// heartbeat was deleted, and was discarded.
+	OutcomeDiscarded = "discarded"
+	// OutcomeFailed means replaying the ping failed again and it was kept to
+	// be retried after a backoff.
+	OutcomeFailed = "failed"
+	// OutcomePending means the ping was kept without replaying it, because
+	// its backoff didn't pass yet.
+	OutcomePending = "pending"
+)
+
+// Entry is a ping that couldn't be delivered.
+type Entry struct {
+	// Heartbeat is the name of the pinged heartbeat.
+	Heartbeat string `json:"heartbeat"`
+	// Time is when the heartbeat was pinged.
+	Time time.Time `json:"time"`
+	// Expires is when the ping becomes older than the heartbeat interval, zero
+	// until the interval is known.
+	Expires time.Time `json:"expires,omitzero"`
+	// Attempts is how many times replaying the ping failed.
+	Attempts int `json:"attempts"`
+	// NextAttempt is when the ping should be replayed next.
+	NextAttempt time.Time `json:"nextAttempt,omitzero"`
+	// Error is the error the last attempt failed with.
+	Error string `json:"error"`
+}
+
+// Result is the outcome of replaying a spooled ping.
+type Result struct {
+	Entry
+	// Outcome is one of the Outcome constants.
+	Outcome string
+	// Err is the error replaying the ping failed with, if any.
+	Err error
+}
+
+// Spool is a file holding entries as JSON lines.
+type Spool struct {
+	path string
+}
+
+// Open returns the spool stored in given file. The file is created when the
+// first entry is added, and removed once the spool is empty. Changes are
+// serialized with a lock on a file with '.lock' appended to the path, which
+// is kept.
+func Open(path string) *Spool {
+	return &Spool{path: path}
+}
+
+// Path returns the path of the file the spool is stored in.
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Retryable returns true if given error means OpsGenie couldn't be reached or
+// was temporarily unable to handle the request, so the request may succeed
+// later.
+func Retryable(err error) bool {
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return true
+	}
+
+	var apiErr *ogclient.ApiError
+	return errors.As(err, &apiErr) &&
+		(apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= http.StatusInternalServerError)
+}
+
+// Add appends entries for pings of heartbeats that failed at given time,
+// mapped to errors they failed with. Entries supersede older entries of the
+// same heartbeat.
+func (s *Spool) Add(failed map[string]error, at time.Time) error {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(failed))
+	for name := range failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var data []byte
+	for _, name := range names {
+		line, err := json.Marshal(Entry{Heartbeat: name, Time: at, Error: failed[name].Error()})
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	// Written in a single call so readers not taking the lock never see
+	// partially written entries.
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Entries returns the newest entry of every heartbeat, sorted by heartbeat
+// name. A missing spool file is treated as having no entries.
+func (s *Spool) Entries() ([]Entry, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	newest := map[string]Entry{}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse spool %s line %d: %w", s.path, line, err)
+		}
+		if prev, ok := newest[e.Heartbeat]; !ok || !e.Time.Before(prev.Time) {
+			newest[e.Heartbeat] = e
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(newest))
+	for _, e := range newest {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Heartbeat < entries[j].Heartbeat
+	})
+	return entries, nil
+}
+
+// Remove removes entries of given heartbeats, or all entries if none are
+// given, and returns the removed entries.
+func (s *Spool) Remove(names ...string) ([]Entry, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	remove := map[string]bool{}
+	for _, name := range names {
+		remove[name] = true
+	}
+
+	var kept, removed []Entry
+	for _, e := range entries {
+		if len(names) == 0 || remove[e.Heartbeat] {
+			removed = append(removed, e)
+		} else {
+			kept = append(kept, e)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, s.write(kept)
+}
+
+// Replay pings heartbeats of spooled entries through given client, removing
+// entries that were delivered, and ones that are older than the heartbeat
+// interval or can't be delivered. Entries that failed again are kept and
+// put off with exponential backoff. Entries whose backoff didn't pass yet are
+// not replayed unless force is set. Entries of given heartbeats only are
+// replayed if any are given. The spool isn't locked while pings are replayed,
+// only while the results are merged with entries added in the meantime.
+func (s *Spool) Replay(ctx context.Context, c client.Port, now time.Time, force bool, names ...string) ([]Result, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	replay := map[string]bool{}
+	for _, name := range names {
+		replay[name] = true
+	}
+
+	results := make([]Result, 0, len(entries))
+	for _, e := range entries {
+		if len(names) > 0 && !replay[e.Heartbeat] {
+			continue
+		}
+		results = append(results, replayEntry(ctx, c, e, now, force))
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return results, err
+	}
+	defer unlock()
+
+	// Entries added while replaying supersede replayed ones, like they do in
+	// Entries.
+	current, err := s.Entries()
+	if err != nil {
+		return results, err
+	}
+	replayed := map[string]Result{}
+	for _, r := range results {
+		replayed[r.Heartbeat] = r
+	}
+	var kept []Entry
+	for _, e := range current {
+		r, ok := replayed[e.Heartbeat]
+		switch {
+		case !ok || e.Time.After(r.Time):
+			kept = append(kept, e)
+		case r.Outcome == OutcomeFailed || r.Outcome == OutcomePending:
+			kept = append(kept, r.Entry)
+		}
+	}
+	return results, s.write(kept)
+}
+
+// replayEntry replays given entry and returns the result, with the entry
+// updated to be kept in the spool if it wasn't delivered or discarded.
+func replayEntry(ctx context.Context, c client.Port, e Entry, now time.Time, force bool) Result {
+	if !e.Expires.IsZero() && !now.Before(e.Expires) {
+		return Result{Entry: e, Outcome: OutcomeExpired}
+	}
+	if !force && now.Before(e.NextAttempt) {
+		return Result{Entry: e, Outcome: OutcomePending}
+	}
+
+	if e.Expires.IsZero() {
+		expires, err := expiry(ctx, c, e)
+		if err != nil {
+			return failed(e, now, err)
+		}
+		e.Expires = expires
+		if !now.Before(e.Expires) {
+			return Result{Entry: e, Outcome: OutcomeExpired}
+		}
+	}
+
+	if _, err := c.Ping(ctx, e.Heartbeat); err != nil {
+		return failed(e, now, err)
+	}
+	return Result{Entry: e, Outcome: OutcomeDelivered}
+}
+
+// expiry returns when given entry becomes older than the interval of its
+// heartbeat.
+func expiry(ctx context.Context, c client.Port, e Entry) (time.Time, error) {
+	result, err := c.Get(ctx, e.Heartbeat)
+	if err != nil {
+		return time.Time{}, err
+	}
+	interval, err := schedule.Duration(result.Heartbeat.Interval, result.Heartbeat.IntervalUnit)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return e.Time.Add(interval), nil
+}
+
+// failed returns the result of given entry failing to replay with given
+// error, discarding it unless the error is Retryable.
+func failed(e Entry, now time.Time, err error) Result {
+	if !Retryable(err) {
+		return Result{Entry: e, Outcome: OutcomeDiscarded, Err: err}
+	}
+
+	e.Attempts++
+	e.Error = err.Error()
+	backoff := minBackoff << min(e.Attempts-1, 10)
+	e.NextAttempt = now.Add(min(backoff, maxBackoff))
+	return Result{Entry: e, Outcome: OutcomeFailed, Err: err}
+}
+
+// write replaces all entries of the spool with given ones, removing the
+// spool file if there are none. It must be called with the lock held.
+func (s *Spool) write(entries []Entry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	var data []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	// Written to a temporary file renamed over the spool, so readers never
+	// see a partially written spool.
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
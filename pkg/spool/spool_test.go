@@ -0,0 +1,210 @@
+package spool_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ogclient "github.com/opsgenie/opsgenie-go-sdk-v2/client"
+	"github.com/opsgenie/opsgenie-go-sdk-v2/heartbeat"
+
+	"github.com/giantswarm/heartbeatctl/pkg/client/fake"
+	"github.com/giantswarm/heartbeatctl/pkg/spool"
+)
+
+// unreachable is the error pinging fails with without connectivity.
+var unreachable = &url.Error{Op: "Post", URL: "https://api.opsgenie.com/v2/heartbeats/backup/ping", Err: errors.New("connection refused")}
+
+var _ = Describe("Spool", func() {
+	var (
+		path string
+		s    *spool.Spool
+		repo *fake.Client
+		now  time.Time
+	)
+
+	// outcomes returns outcomes of given results by heartbeat name.
+	outcomes := func(results []spool.Result) map[string]string {
+		ret := map[string]string{}
+		for _, r := range results {
+			ret[r.Heartbeat] = r.Outcome
+		}
+		return ret
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "state", "spool.jsonl")
+		s = spool.Open(path)
+		now = time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC)
+		repo = fake.NewClient(
+			heartbeat.Heartbeat{Name: "backup", Interval: 1, IntervalUnit: "hours", Expired: true},
+			heartbeat.Heartbeat{Name: "cleanup", Interval: 10, IntervalUnit: "minutes", Expired: true},
+		)
+	})
+
+	It("has no entries when the file doesn't exist", func() {
+		entries, err := s.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("keeps the newest entry of every heartbeat", func() {
+		Expect(s.Add(map[string]error{"cleanup": unreachable, "backup": unreachable}, now)).To(Succeed())
+		Expect(s.Add(map[string]error{"backup": errors.New("timeout")}, now.Add(time.Minute))).To(Succeed())
+
+		entries, err := s.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]spool.Entry{
+			{Heartbeat: "backup", Time: now.Add(time.Minute), Error: "timeout"},
+			{Heartbeat: "cleanup", Time: now, Error: unreachable.Error()},
+		}))
+	})
+
+	It("delivers spooled pings and removes them", func() {
+		Expect(s.Add(map[string]error{"backup": unreachable, "cleanup": unreachable}, now)).To(Succeed())
+
+		results, err := s.Replay(context.Background(), repo, now.Add(5*time.Minute), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes(results)).To(Equal(map[string]string{"backup": spool.OutcomeDelivered, "cleanup": spool.OutcomeDelivered}))
+		Expect(results[0].Expires).To(Equal(now.Add(time.Hour)))
+
+		for _, h := range repo.Heartbeats() {
+			Expect(h.Expired).To(BeFalse())
+		}
+		_, err = os.Stat(path)
+		Expect(err).To(MatchError(os.ErrNotExist))
+	})
+
+	It("discards pings older than the heartbeat interval", func() {
+		Expect(s.Add(map[string]error{"backup": unreachable, "cleanup": unreachable}, now)).To(Succeed())
+
+		results, err := s.Replay(context.Background(), repo, now.Add(30*time.Minute), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes(results)).To(Equal(map[string]string{"backup": spool.OutcomeDelivered, "cleanup": spool.OutcomeExpired}))
+		Expect(repo.Heartbeats()[1].Expired).To(BeTrue())
+	})
+
+	It("discards pings of deleted heartbeats", func() {
+		Expect(s.Add(map[string]error{"nope": unreachable}, now)).To(Succeed())
+
+		results, err := s.Replay(context.Background(), repo, now, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Outcome).To(Equal(spool.OutcomeDiscarded))
+		Expect(s.Entries()).To(BeEmpty())
+	})
+
+	It("backs off replaying pings that fail again", func() {
+		repo.Fail("Ping", "backup", unreachable)
+		Expect(s.Add(map[string]error{"backup": unreachable}, now)).To(Succeed())
+
+		results, err := s.Replay(context.Background(), repo, now, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes(results)).To(Equal(map[string]string{"backup": spool.OutcomeFailed}))
+		Expect(s.Entries()).To(Equal([]spool.Entry{{
+			Heartbeat:   "backup",
+			Time:        now,
+			Expires:     now.Add(time.Hour),
+			Attempts:    1,
+			NextAttempt: now.Add(30 * time.Second),
+			Error:       unreachable.Error(),
+		}}))
+
+		results, err = s.Replay(context.Background(), repo, now.Add(10*time.Second), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes(results)).To(Equal(map[string]string{"backup": spool.OutcomePending}))
+
+		results, err = s.Replay(context.Background(), repo, now.Add(30*time.Second), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes(results)).To(Equal(map[string]string{"backup": spool.OutcomeFailed}))
+		entries, err := s.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries[0].NextAttempt).To(Equal(now.Add(90 * time.Second)))
+
+		repo.Fail("Ping", "backup", nil)
+		results, err = s.Replay(context.Background(), repo, now.Add(time.Minute), true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes(results)).To(Equal(map[string]string{"backup": spool.OutcomeDelivered}))
+		Expect(s.Entries()).To(BeEmpty())
+	})
+
+	It("keeps entries added concurrently while replaying", func() {
+		repo.Fail("Ping", "backup", unreachable)
+		Expect(s.Add(map[string]error{"backup": unreachable}, now)).To(Succeed())
+
+		var wg sync.WaitGroup
+		for i := range 20 {
+			wg.Add(2)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Expect(s.Add(map[string]error{fmt.Sprintf("added-%02d", i): unreachable}, now)).To(Succeed())
+			}()
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				_, err := s.Replay(context.Background(), repo, now, true, "backup")
+				Expect(err).NotTo(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+
+		entries, err := s.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(21))
+	})
+
+	It("replays entries of given heartbeats only", func() {
+		Expect(s.Add(map[string]error{"backup": unreachable, "cleanup": unreachable}, now)).To(Succeed())
+
+		results, err := s.Replay(context.Background(), repo, now, false, "cleanup")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outcomes(results)).To(Equal(map[string]string{"cleanup": spool.OutcomeDelivered}))
+
+		entries, err := s.Entries()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+		Expect(entries[0].Heartbeat).To(Equal("backup"))
+	})
+
+	It("removes entries", func() {
+		Expect(s.Add(map[string]error{"backup": unreachable, "cleanup": unreachable}, now)).To(Succeed())
+
+		removed, err := s.Remove("cleanup", "nope")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(HaveLen(1))
+		Expect(s.Entries()).To(HaveLen(1))
+
+		removed, err = s.Remove()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(removed).To(HaveLen(1))
+		Expect(s.Entries()).To(BeEmpty())
+	})
+
+	It("fails on malformed entries", func() {
+		Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(Succeed())
+		Expect(os.WriteFile(path, []byte("{\n"), 0600)).To(Succeed())
+
+		_, err := s.Entries()
+		Expect(err).To(MatchError(HavePrefix("failed to parse spool " + path + " line 1:")))
+	})
+
+	DescribeTable("tells errors worth retrying",
+		func(err error, retryable bool) {
+			Expect(spool.Retryable(err)).To(Equal(retryable))
+		},
+		Entry("unreachable", unreachable, true),
+		Entry("wrapped", errors.Join(errors.New("giving up"), unreachable), true),
+		Entry("rate limited", &ogclient.ApiError{StatusCode: 429}, true),
+		Entry("unavailable", &ogclient.ApiError{StatusCode: 503}, true),
+		Entry("not found", &ogclient.ApiError{StatusCode: 404}, false),
+		Entry("other", errors.New("API call failed"), false),
+	)
+})